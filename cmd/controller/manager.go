@@ -2,7 +2,9 @@
 package controller
 
 import (
+	"context"
 	"crypto/tls"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
@@ -16,21 +18,35 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/uuid"
 	utilfeature "k8s.io/apiserver/pkg/util/feature"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
 	cliflag "k8s.io/component-base/cli/flag"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
-	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
 	// +kubebuilder:scaffold:imports
 	"go.datum.net/datum/internal/config"
+	iamcontroller "go.datum.net/datum/internal/controller/iam"
 	resourcemanagercontroller "go.datum.net/datum/internal/controller/resourcemanager"
+	"go.datum.net/datum/internal/doctor"
+	"go.datum.net/datum/internal/dryrun"
+	"go.datum.net/datum/internal/health"
+	"go.datum.net/datum/internal/sharding"
+	"go.datum.net/datum/internal/tracing"
+	resourcemanagerwebhook "go.datum.net/datum/internal/webhook/resourcemanager"
 	"go.datum.net/datum/pkg/features"
 	iamv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
 	resourcemanagerv1alpha1 "go.miloapis.com/milo/pkg/apis/resourcemanager/v1alpha1"
@@ -50,6 +66,10 @@ func init() {
 	utilruntime.Must(iamv1alpha1.AddToScheme(scheme))
 	utilruntime.Must(resourcemanagerv1alpha1.AddToScheme(scheme))
 
+	// Register the server config so that decoding it applies its defaults.
+	utilruntime.Must(config.AddToScheme(scheme))
+	utilruntime.Must(config.RegisterDefaults(scheme))
+
 	// +kubebuilder:scaffold:scheme
 }
 
@@ -68,6 +88,8 @@ func NewControllerManagerCommand() *cobra.Command {
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var skipPreflight bool
+	var dryRun bool
 	var serverConfigFile string
 
 	cmd := &cobra.Command{
@@ -90,6 +112,8 @@ func NewControllerManagerCommand() *cobra.Command {
 				probeAddr,
 				secureMetrics,
 				enableHTTP2,
+				skipPreflight,
+				dryRun,
 			)
 		},
 	}
@@ -131,14 +155,15 @@ func NewControllerManagerCommand() *cobra.Command {
 	cmd.Flags().BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
 	cmd.Flags().StringVar(&serverConfigFile, "config", "", "path to the controller manager config file")
-
-	// Add zap logging flags
-	opts := zap.Options{
-		Development: true,
-	}
-	// Convert cobra pflag to standard flag for zap compatibility
+	cmd.Flags().BoolVar(&skipPreflight, "skip-preflight", false,
+		"If set, the manager starts without checking that the required APIs are served and its permissions are granted.")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false,
+		"If set, writes are sent as server-side dry-run requests and logged as planned mutations, so the manager can "+
+			"run in shadow of the active one. Leader election uses a separate lease, named after --leader-election-id with a -dry-run suffix.")
+
+	// Add the flags registered on the standard flag set, such as --kubeconfig.
+	// Logging is configured in the config file.
 	cmd.Flags().AddGoFlagSet(flag.CommandLine)
-	opts.BindFlags(flag.CommandLine)
 
 	namedFlagSets := cliflag.NamedFlagSets{}
 	utilfeature.DefaultMutableFeatureGate.AddFlag(namedFlagSets.FlagSet("feature gates"))
@@ -165,16 +190,34 @@ func runControllerManager(
 	probeAddr string,
 	secureMetrics bool,
 	enableHTTP2 bool,
+	skipPreflight bool,
+	dryRun bool,
 ) error {
 	var tlsOpts []func(*tls.Config)
 
-	// Initialize zap logger
-	opts := zap.Options{
-		Development: true,
+	var serverConfig config.DatumControllerManager
+	var configData []byte
+	if len(serverConfigFile) > 0 {
+		var err error
+		configData, err = os.ReadFile(serverConfigFile)
+		if err != nil {
+			return fmt.Errorf("unable to read server config from %q: %w", serverConfigFile, err)
+		}
+	}
+
+	if err := runtime.DecodeInto(codecs.UniversalDecoder(), configData, &serverConfig); err != nil {
+		return fmt.Errorf("unable to decode server config: %w", err)
+	}
+
+	logger, err := serverConfig.Logging.NewLogger(os.Stderr)
+	if err != nil {
+		return fmt.Errorf("unable to configure logging: %w", err)
+	}
+	ctrl.SetLogger(logger)
+
+	if err := serverConfig.Validate(); err != nil {
+		return fmt.Errorf("invalid server config: %w", err)
 	}
-	// Parse the command line flags to get the zap options
-	flag.Parse()
-	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
 	// due to its vulnerabilities. More specifically, disabling http/2 will
@@ -191,19 +234,60 @@ func runControllerManager(
 		tlsOpts = append(tlsOpts, disableHTTP2)
 	}
 
-	var serverConfig config.DatumControllerManager
-	var configData []byte
-	if len(serverConfigFile) > 0 {
-		var err error
-		configData, err = os.ReadFile(serverConfigFile)
+	restConfig := serverConfig.Client.Apply(ctrl.GetConfigOrDie())
+	if serverConfig.Tracing.Enabled {
+		tracerProvider, err := serverConfig.Tracing.NewTracerProvider(context.Background())
 		if err != nil {
-			setupLog.Error(fmt.Errorf("unable to read server config from %q", serverConfigFile), "")
-			os.Exit(1)
+			setupLog.Error(err, "unable to set up tracing")
+			return err
 		}
+		defer func() {
+			if err := tracerProvider.Shutdown(context.Background()); err != nil {
+				setupLog.Error(err, "problem shutting down tracing")
+			}
+		}()
+		otel.SetTracerProvider(tracerProvider)
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+
+		// Clients created from copies of the config, such as the impersonated
+		// clients used to create projects, keep the wrapped transport.
+		restConfig.Wrap(tracing.WrapTransport)
 	}
 
-	if err := runtime.DecodeInto(codecs.UniversalDecoder(), configData, &serverConfig); err != nil {
-		return fmt.Errorf("unable to decode server config: %w", err)
+	// Fail fast when the control plane cannot serve the controllers, rather
+	// than failing every reconcile. The same checks are run by datum doctor.
+	if !skipPreflight {
+		clientset, err := kubernetes.NewForConfig(restConfig)
+		if err != nil {
+			setupLog.Error(err, "unable to create client for preflight checks")
+			return err
+		}
+		report := doctor.Preflight(context.Background(), clientset)
+		for _, result := range report {
+			if result.Status == doctor.StatusPass {
+				setupLog.Info("Preflight check passed", "check", result.Check, "message", result.Message)
+			} else {
+				setupLog.Error(nil, "Preflight check failed", "check", result.Check, "message", result.Message, "remediation", result.Remediation)
+			}
+		}
+		if report.Failed() {
+			return errors.New("preflight checks failed")
+		}
+	}
+
+	// Leader election must keep writing its lease in dry-run mode, so it uses a
+	// copy of the config made before writes are intercepted. Shadow replicas
+	// elect a leader among themselves rather than contending with the active
+	// manager. Its clients get their own rate limiter, so lease renewals are
+	// not throttled by the controllers.
+	leaderElectionConfig := rest.CopyConfig(restConfig)
+	leaderElectionConfig.RateLimiter = nil
+	restConfig.Wrap(dryrun.WrapTransport(dryRun))
+	shardLeaseName := "datum-user-shard"
+	if dryRun {
+		leaderElectionID += "-dry-run"
+		shardLeaseName += "-dry-run"
+		setupLog.Info("Running in dry-run mode, writes are not persisted", "leader-election-id", leaderElectionID)
 	}
 
 	// Create watchers for metrics and webhooks certificates
@@ -280,7 +364,7 @@ func runControllerManager(
 		})
 	}
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
 		Scheme:                        scheme,
 		Metrics:                       metricsServerOptions,
 		WebhookServer:                 webhookServer,
@@ -292,6 +376,7 @@ func runControllerManager(
 		RenewDeadline:                 &leaderElectionRenewDeadline,
 		RetryPeriod:                   &leaderElectionRetryPeriod,
 		LeaderElectionReleaseOnCancel: leaderElectionReleaseOnCancel,
+		LeaderElectionConfig:          leaderElectionConfig,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
@@ -316,27 +401,223 @@ func runControllerManager(
 		}
 	}
 
-	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
-		setupLog.Error(err, "unable to set up health check")
-		return err
+	healthChecks := map[string]healthz.Checker{
+		"healthz":         healthz.Ping,
+		"reconcile-stall": health.ReconcileStallCheck(metrics.Registry, serverConfig.HealthProbes.ReconcileStallTimeout.Duration),
 	}
-	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
-		setupLog.Error(err, "unable to set up ready check")
-		return err
+	readyChecks := map[string]healthz.Checker{
+		"informers": health.CacheSyncCheck(mgr.GetCache()),
+	}
+	if !utilfeature.DefaultFeatureGate.Enabled(features.UnifiedOrganizations) {
+		readyChecks["owner-role"] = health.ObjectExistsCheck(mgr.GetClient(), &iamv1alpha1.Role{}, client.ObjectKey{
+			Name:      serverConfig.PersonalOrganizationController.RoleName,
+			Namespace: serverConfig.PersonalOrganizationController.RoleNamespace,
+		})
+	}
+	if utilfeature.DefaultFeatureGate.Enabled(features.LastOwnerProtection) {
+		// The webhook server only accepts connections once its serving
+		// certificate has been loaded.
+		readyChecks["webhook"] = mgr.GetWebhookServer().StartedChecker()
+	}
+	for name, check := range healthChecks {
+		if err := mgr.AddHealthzCheck(name, check); err != nil {
+			setupLog.Error(err, "unable to set up health check", "check", name)
+			return err
+		}
+	}
+	for name, check := range readyChecks {
+		if err := mgr.AddReadyzCheck(name, check); err != nil {
+			setupLog.Error(err, "unable to set up ready check", "check", name)
+			return err
+		}
+	}
+
+	// Impersonated clients keep the transport of the manager, and share a rate
+	// limiter of their own.
+	impersonationConfig := serverConfig.ImpersonatedClient.Apply(mgr.GetConfig())
+
+	// With sharding, the User controllers run on every replica for the Users
+	// of the shards it claims.
+	var shards *sharding.Coordinator
+	if serverConfig.Sharding.Enabled {
+		// Shard Leases, like the leader election lease, must be written in
+		// dry-run mode, and are not cached.
+		leaseClient, err := client.New(leaderElectionConfig, client.Options{Scheme: mgr.GetScheme()})
+		if err != nil {
+			setupLog.Error(err, "unable to create client for shard leases")
+			return err
+		}
+		hostname, err := os.Hostname()
+		if err != nil {
+			setupLog.Error(err, "unable to get hostname for shard leases")
+			return err
+		}
+		identity := hostname + "-" + string(uuid.NewUUID())
+		shards = serverConfig.Sharding.NewCoordinator(leaseClient, mgr.GetClient(), shardLeaseName, identity)
+		if err := mgr.Add(shards); err != nil {
+			setupLog.Error(err, "unable to add shard coordinator to manager")
+			return err
+		}
 	}
 
 	if !utilfeature.DefaultFeatureGate.Enabled(features.UnifiedOrganizations) {
+		// Users with an incomplete personal workspace found by the auditor are
+		// repaired by the personal organization controller.
+		var repairs chan event.GenericEvent
+		if serverConfig.PersonalWorkspaceAuditor.Repair {
+			repairs = make(chan event.GenericEvent)
+		}
+
 		if err = (&resourcemanagercontroller.PersonalOrganizationController{
 			Client:     mgr.GetClient(),
 			Config:     serverConfig.PersonalOrganizationController,
 			Scheme:     mgr.GetScheme(),
-			RestConfig: mgr.GetConfig(),
+			RestConfig: impersonationConfig,
+			Repairs:    repairs,
+			Shards:     shards,
+			Options:    serverConfig.Controllers.Options("personal-organization"),
 		}).SetupWithManager(mgr); err != nil {
 			setupLog.Error(err, "unable to create controller", "controller", "PersonalOrganization")
 			return err
 		}
+
+		if serverConfig.PersonalWorkspaceAuditor.Enabled {
+			if err = (&resourcemanagercontroller.PersonalWorkspaceAuditor{
+				Client:  mgr.GetClient(),
+				Config:  serverConfig.PersonalWorkspaceAuditor,
+				Repairs: repairs,
+				Shards:  shards,
+				Options: serverConfig.Controllers.Options("personal-workspace-auditor"),
+			}).SetupWithManager(mgr); err != nil {
+				setupLog.Error(err, "unable to create controller", "controller", "PersonalWorkspaceAuditor")
+				return err
+			}
+		}
 	} else {
-		setupLog.Info("PersonalOrganization controller disabled by UnifiedOrganizations feature gate")
+		setupLog.Info("PersonalOrganization controller and PersonalWorkspaceAuditor disabled by UnifiedOrganizations feature gate")
+	}
+
+	if utilfeature.DefaultFeatureGate.Enabled(features.OrganizationQuotaUsage) {
+		if err = (&resourcemanagercontroller.OrganizationQuotaUsageController{
+			Client:   mgr.GetClient(),
+			Config:   serverConfig.OrganizationQuotaUsageController,
+			Recorder: mgr.GetEventRecorderFor("organization-quota-usage"),
+			Options:  serverConfig.Controllers.Options("organization-quota-usage"),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "OrganizationQuotaUsage")
+			return err
+		}
+	}
+
+	if utilfeature.DefaultFeatureGate.Enabled(features.OrganizationBootstrap) {
+		if err = (&resourcemanagercontroller.OrganizationBootstrapController{
+			Client:                    mgr.GetClient(),
+			Config:                    serverConfig.OrganizationBootstrapController,
+			SkipPersonalOrganizations: !utilfeature.DefaultFeatureGate.Enabled(features.UnifiedOrganizations),
+			Scheme:                    mgr.GetScheme(),
+			RestConfig:                impersonationConfig,
+			Options:                   serverConfig.Controllers.Options("organization-bootstrap"),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "OrganizationBootstrap")
+			return err
+		}
+	}
+
+	if utilfeature.DefaultFeatureGate.Enabled(features.OrganizationCustomRoles) {
+		if err = (&resourcemanagercontroller.OrganizationCustomRoleController{
+			Client:   mgr.GetClient(),
+			Config:   serverConfig.OrganizationCustomRoleController,
+			Scheme:   mgr.GetScheme(),
+			Recorder: mgr.GetEventRecorderFor("organization-custom-role"),
+			Options:  serverConfig.Controllers.Options("organization-custom-role"),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "OrganizationCustomRole")
+			return err
+		}
+	}
+
+	if utilfeature.DefaultFeatureGate.Enabled(features.OrganizationInvitations) {
+		if err = (&resourcemanagercontroller.OrganizationInvitationController{
+			Client:   mgr.GetClient(),
+			Config:   serverConfig.OrganizationInvitationController,
+			Recorder: mgr.GetEventRecorderFor("organization-invitation"),
+			Options:  serverConfig.Controllers.Options("organization-invitation"),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "OrganizationInvitation")
+			return err
+		}
+	}
+
+	if utilfeature.DefaultFeatureGate.Enabled(features.UserOffboarding) {
+		if err = (&resourcemanagercontroller.UserOffboardingController{
+			Client:   mgr.GetClient(),
+			Config:   serverConfig.UserOffboardingController,
+			Recorder: mgr.GetEventRecorderFor("user-offboarding"),
+			Shards:   shards,
+			Options:  serverConfig.Controllers.Options("user-offboarding"),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "UserOffboarding")
+			return err
+		}
+	}
+
+	if utilfeature.DefaultFeatureGate.Enabled(features.OrganizationTransfer) {
+		if err = (&resourcemanagercontroller.OrganizationTransferController{
+			Client:  mgr.GetClient(),
+			Config:  serverConfig.OrganizationTransferController,
+			Options: serverConfig.Controllers.Options("organization-transfer"),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "OrganizationTransfer")
+			return err
+		}
+	}
+
+	if utilfeature.DefaultFeatureGate.Enabled(features.UserOnboarding) {
+		if err = (&resourcemanagercontroller.UserOnboardingController{
+			Client:   mgr.GetClient(),
+			Config:   serverConfig.UserOnboardingController,
+			Recorder: mgr.GetEventRecorderFor("user-onboarding"),
+			Shards:   shards,
+			Options:  serverConfig.Controllers.Options("user-onboarding"),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "UserOnboarding")
+			return err
+		}
+	}
+
+	if utilfeature.DefaultFeatureGate.Enabled(features.UserWaitlist) {
+		if err = (&resourcemanagercontroller.UserWaitlistController{
+			Client:                mgr.GetClient(),
+			Config:                serverConfig.UserWaitlistController,
+			SkipProvisioningCheck: utilfeature.DefaultFeatureGate.Enabled(features.UnifiedOrganizations),
+			Onboarding:            utilfeature.DefaultFeatureGate.Enabled(features.UserOnboarding),
+			Options:               serverConfig.Controllers.Options("user-waitlist"),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "UserWaitlist")
+			return err
+		}
+	}
+
+	if utilfeature.DefaultFeatureGate.Enabled(features.RoleCatalog) {
+		if err = (&iamcontroller.RoleCatalogController{
+			Client:   mgr.GetClient(),
+			Config:   serverConfig.RoleCatalogController,
+			Recorder: mgr.GetEventRecorderFor("role-catalog"),
+			Options:  serverConfig.Controllers.Options("role-catalog"),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "RoleCatalog")
+			return err
+		}
+	}
+
+	if utilfeature.DefaultFeatureGate.Enabled(features.LastOwnerProtection) {
+		if err = (&resourcemanagerwebhook.OrganizationMembershipValidator{
+			Client: mgr.GetClient(),
+			Config: serverConfig.LastOwnerProtectionWebhook,
+		}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "OrganizationMembership")
+			return err
+		}
 	}
 
 	setupLog.Info("starting manager")
@@ -0,0 +1,147 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+package doctor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
+	"k8s.io/client-go/kubernetes"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cliflag "k8s.io/component-base/cli/flag"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	iamv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
+	resourcemanagerv1alpha1 "go.miloapis.com/milo/pkg/apis/resourcemanager/v1alpha1"
+
+	"go.datum.net/datum/internal/doctor"
+	"go.datum.net/datum/pkg/features"
+)
+
+// NewDoctorCommand creates the doctor command.
+func NewDoctorCommand() *cobra.Command {
+	var (
+		kubeconfig, kubeContext, impersonate string
+		metricsCertSecret, webhookCertSecret string
+		opts                                 = doctor.Options{
+			ConfigDir:                "config",
+			StuckUserThreshold:       time.Hour,
+			CertificateExpiryWarning: 30 * 24 * time.Hour,
+		}
+	)
+
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check that a control plane can run the Datum controller manager",
+		Long: `Doctor connects to the control plane of the current kubeconfig context and runs
+the preflight checks of the controller manager: the Milo APIs used by the
+controllers must be served, and the controller manager must hold every
+permission listed in the RBAC markers of the controllers, including the right
+to impersonate users.
+
+It then diagnoses the running environment:
+
+  - the feature gates given with --feature-gates must match the feature gates
+    of the same name reported by the Milo API server metrics,
+  - the assignable organization roles and the roles they inherit must exist,
+  - the grant, claim and registration policies of each service in
+    config/services must be installed,
+  - users must not be stuck without a personal organization or project,
+  - the metrics and webhook serving certificates must not be about to expire.
+
+Permissions are checked for the identity of the kubeconfig. Use --as to check
+the permissions of the controller manager's service account instead.`,
+		Example: `  datum doctor
+  datum doctor --feature-gates UnifiedOrganizations=true
+  datum doctor --as system:serviceaccount:datum-system:datum-controller-manager`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			if opts.MetricsCertificateSecret, err = parseSecret(metricsCertSecret); err != nil {
+				return fmt.Errorf("invalid --metrics-cert-secret: %w", err)
+			}
+			if opts.WebhookCertificateSecret, err = parseSecret(webhookCertSecret); err != nil {
+				return fmt.Errorf("invalid --webhook-cert-secret: %w", err)
+			}
+			opts.FeatureGates = features.Gates()
+
+			loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+			loadingRules.ExplicitPath = kubeconfig
+			restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+				loadingRules, &clientcmd.ConfigOverrides{
+					CurrentContext: kubeContext,
+					AuthInfo:       clientcmdapi.AuthInfo{Impersonate: impersonate},
+				},
+			).ClientConfig()
+			if err != nil {
+				return fmt.Errorf("unable to load kubeconfig: %w", err)
+			}
+			clientset, err := kubernetes.NewForConfig(restConfig)
+			if err != nil {
+				return fmt.Errorf("unable to create client: %w", err)
+			}
+
+			scheme := runtime.NewScheme()
+			if err := clientgoscheme.AddToScheme(scheme); err != nil {
+				return err
+			}
+			if err := iamv1alpha1.AddToScheme(scheme); err != nil {
+				return err
+			}
+			if err := resourcemanagerv1alpha1.AddToScheme(scheme); err != nil {
+				return err
+			}
+			c, err := client.New(restConfig, client.Options{Scheme: scheme})
+			if err != nil {
+				return fmt.Errorf("unable to create client: %w", err)
+			}
+
+			report := doctor.Preflight(cmd.Context(), clientset)
+			report = append(report, doctor.Diagnose(cmd.Context(), clientset.Discovery().RESTClient(), c, opts)...)
+			if err := report.Print(cmd.OutOrStdout()); err != nil {
+				return err
+			}
+			if report.Failed() {
+				return errors.New("some checks failed")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Path to the kubeconfig file of the control plane.")
+	cmd.Flags().StringVar(&kubeContext, "context", "", "The kubeconfig context of the control plane.")
+	cmd.Flags().StringVar(&impersonate, "as", "", "The user to impersonate when checking permissions.")
+	cmd.Flags().StringVar(&opts.ConfigDir, "config-dir", opts.ConfigDir, "The directory containing the services and overlays kustomizations.")
+	cmd.Flags().DurationVar(&opts.StuckUserThreshold, "stuck-user-threshold", opts.StuckUserThreshold, "How long after signing up a user without a personal organization or project is reported.")
+	cmd.Flags().StringVar(&metricsCertSecret, "metrics-cert-secret", "datum-system/metrics-server-cert", "The namespace/name of the secret holding the metrics serving certificate. Empty to skip the check.")
+	cmd.Flags().StringVar(&webhookCertSecret, "webhook-cert-secret", "datum-system/webhook-server-cert", "The namespace/name of the secret holding the webhook serving certificate. Empty to skip the check.")
+	cmd.Flags().DurationVar(&opts.CertificateExpiryWarning, "cert-expiry-warning", opts.CertificateExpiryWarning, "How long before expiry certificates are reported.")
+
+	namedFlagSets := cliflag.NamedFlagSets{}
+	utilfeature.DefaultMutableFeatureGate.AddFlag(namedFlagSets.FlagSet("feature gates"))
+	for _, fs := range namedFlagSets.FlagSets {
+		cmd.Flags().AddFlagSet(fs)
+	}
+
+	return cmd
+}
+
+// parseSecret parses a namespace/name secret reference. An empty reference
+// returns an empty name.
+func parseSecret(ref string) (types.NamespacedName, error) {
+	if ref == "" {
+		return types.NamespacedName{}, nil
+	}
+	namespace, name, ok := strings.Cut(ref, "/")
+	if !ok || namespace == "" || name == "" {
+		return types.NamespacedName{}, fmt.Errorf("expected namespace/name, got %q", ref)
+	}
+	return types.NamespacedName{Namespace: namespace, Name: name}, nil
+}
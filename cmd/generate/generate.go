@@ -0,0 +1,18 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+package generate
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// NewGenerateCommand creates the generate command and its subcommands.
+func NewGenerateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate manifests from their Go definitions",
+	}
+
+	cmd.AddCommand(newRolesCommand())
+
+	return cmd
+}
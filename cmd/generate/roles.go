@@ -0,0 +1,42 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+package generate
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"go.datum.net/datum/internal/catalog"
+)
+
+func newRolesCommand() *cobra.Command {
+	var outputDir string
+
+	cmd := &cobra.Command{
+		Use:   "roles",
+		Short: "Generate the assignable organization roles from the service catalog",
+		Long: `Generate writes the assignable organization role manifests from the service
+catalog in internal/catalog. The output is deterministic, so running it on an
+unchanged catalog leaves the manifests untouched.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			changed, err := catalog.WriteRoles(outputDir)
+			if err != nil {
+				return err
+			}
+			for _, path := range changed {
+				fmt.Fprintf(cmd.OutOrStdout(), "wrote %s\n", path)
+			}
+			if len(changed) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "roles are up to date")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&outputDir, "output-dir", "config/assignable-organization-roles/roles",
+		"Directory to write the role manifests to.")
+
+	return cmd
+}
@@ -8,6 +8,11 @@ import (
 	"github.com/spf13/cobra"
 
 	"go.datum.net/datum/cmd/controller"
+	"go.datum.net/datum/cmd/doctor"
+	"go.datum.net/datum/cmd/generate"
+	"go.datum.net/datum/cmd/policy"
+	"go.datum.net/datum/cmd/quota"
+	"go.datum.net/datum/cmd/roles"
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -33,6 +38,11 @@ func Execute() {
 func init() {
 	// Add subcommands
 	rootCmd.AddCommand(controller.NewControllerManagerCommand())
+	rootCmd.AddCommand(doctor.NewDoctorCommand())
+	rootCmd.AddCommand(generate.NewGenerateCommand())
+	rootCmd.AddCommand(policy.NewPolicyCommand())
+	rootCmd.AddCommand(quota.NewQuotaCommand())
+	rootCmd.AddCommand(roles.NewRolesCommand())
 }
 
 func main() {
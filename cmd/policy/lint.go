@@ -0,0 +1,55 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+package policy
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"go.datum.net/datum/internal/manifest"
+	"go.datum.net/datum/internal/policy"
+)
+
+func newLintCommand() *cobra.Command {
+	var warningsAsErrors bool
+
+	cmd := &cobra.Command{
+		Use:   "lint [path...]",
+		Short: "Lint quota, search and admission policy manifests",
+		Long: `Lint parses GrantCreationPolicy, ClaimCreationPolicy, ResourceRegistration,
+ResourceIndexPolicy, ContactGroupEnrollmentPolicy and ValidatingAdmissionPolicy
+manifests, compiles their CEL expressions and template placeholders, and checks
+that every resource type claimed by a ClaimCreationPolicy is registered and
+granted.
+
+Paths may be files or directories, which are searched recursively. Defaults to
+config/services.`,
+		Example: `  datum policy lint
+  datum policy lint config/services config/overlays/unified-organizations`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				args = []string{"config/services"}
+			}
+
+			objects, err := manifest.Load(args...)
+			if err != nil {
+				return fmt.Errorf("unable to load manifests: %w", err)
+			}
+
+			findings := policy.Lint(objects)
+			for _, f := range findings {
+				fmt.Fprintln(cmd.OutOrStdout(), f.String())
+			}
+
+			if policy.HasErrors(findings) || (warningsAsErrors && len(findings) > 0) {
+				return fmt.Errorf("found %d problem(s) in %d manifest(s)", len(findings), len(objects))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&warningsAsErrors, "warnings-as-errors", false, "Exit with an error if any warnings are found.")
+
+	return cmd
+}
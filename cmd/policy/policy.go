@@ -0,0 +1,22 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+package policy
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// NewPolicyCommand creates the policy command and its subcommands.
+func NewPolicyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "policy",
+		Short: "Validate and test the policy manifests shipped with Datum",
+		Long: `The policy commands check the quota, search, notification and admission
+policy manifests in this repository before they are applied to a control plane,
+and evaluate admission policies against fixtures without a cluster.`,
+	}
+
+	cmd.AddCommand(newLintCommand())
+	cmd.AddCommand(newTestCommand())
+
+	return cmd
+}
@@ -0,0 +1,77 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+package policy
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"go.datum.net/datum/internal/manifest"
+	"go.datum.net/datum/internal/policy"
+)
+
+func newTestCommand() *cobra.Command {
+	var fixturePaths []string
+
+	cmd := &cobra.Command{
+		Use:   "test [path...]",
+		Short: "Evaluate ValidatingAdmissionPolicies against admission request fixtures",
+		Long: `Test compiles the ValidatingAdmissionPolicies found in the given paths with the
+upstream CEL admission library and evaluates them against the admission request
+fixtures found in testdata directories, without a cluster.
+
+Each fixture names a policy and lists admission requests with the expected
+decision and, optionally, the expected denial message.
+
+Paths may be files or directories, which are searched recursively. Defaults to
+config/services.`,
+		Example: `  datum policy test
+  datum policy test config/services --fixtures my-fixtures.yaml`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				args = []string{"config/services"}
+			}
+			if len(fixturePaths) == 0 {
+				fixturePaths = args
+			}
+
+			objects, err := manifest.Load(args...)
+			if err != nil {
+				return fmt.Errorf("unable to load manifests: %w", err)
+			}
+			policies, err := policy.LoadAdmissionPolicies(objects)
+			if err != nil {
+				return fmt.Errorf("unable to compile admission policies: %w", err)
+			}
+			suites, err := policy.LoadAdmissionTestSuites(fixturePaths...)
+			if err != nil {
+				return fmt.Errorf("unable to load fixtures: %w", err)
+			}
+
+			var total, failed int
+			for _, suite := range suites {
+				for _, result := range policy.RunAdmissionTestSuite(cmd.Context(), policies, suite) {
+					total++
+					if result.Err != nil {
+						failed++
+						fmt.Fprintf(cmd.OutOrStdout(), "FAIL %s/%s: %v\n", suite.Policy, result.Case.Name, result.Err)
+						continue
+					}
+					fmt.Fprintf(cmd.OutOrStdout(), "PASS %s/%s\n", suite.Policy, result.Case.Name)
+				}
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("%d of %d admission test case(s) failed", failed, total)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "ok %d admission test case(s) passed\n", total)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&fixturePaths, "fixtures", nil,
+		"Fixture files or directories to load. Defaults to testdata directories below the policy paths.")
+
+	return cmd
+}
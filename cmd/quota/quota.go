@@ -0,0 +1,21 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+package quota
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// NewQuotaCommand creates the quota command and its subcommands.
+func NewQuotaCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "quota",
+		Short: "Inspect the quota policies shipped with Datum",
+		Long: `The quota commands evaluate the GrantCreationPolicies and ClaimCreationPolicies
+in this repository without a cluster, so the effect of a policy or overlay
+change can be reviewed before it is rolled out.`,
+	}
+
+	cmd.AddCommand(newSimulateCommand())
+
+	return cmd
+}
@@ -0,0 +1,138 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+package quota
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
+	cliflag "k8s.io/component-base/cli/flag"
+	"sigs.k8s.io/yaml"
+
+	"go.datum.net/datum/internal/quota"
+	"go.datum.net/datum/pkg/features"
+)
+
+func newSimulateCommand() *cobra.Command {
+	var (
+		configDir string
+		output    string
+		scenario  = quota.Scenario{
+			OrganizationName: "example",
+			ProjectName:      "example-project",
+			Username:         "user@example.com",
+		}
+	)
+
+	cmd := &cobra.Command{
+		Use:   "simulate",
+		Short: "Render the ResourceGrants and ResourceClaims created for a hypothetical organization",
+		Long: `Simulate renders the service configuration with kustomize, the same way it is
+deployed, and evaluates every GrantCreationPolicy and ClaimCreationPolicy
+against a synthetic Organization and a Project owned by it. The ResourceGrants
+and ResourceClaims the policies would create are printed along with the total
+allowance granted per consumer and resource type.
+
+Feature gates select the overlays that are applied: with UnifiedOrganizations
+enabled, config/overlays/unified-organizations is included.`,
+		Example: `  datum quota simulate --org-type Personal
+  datum quota simulate --org-type Personal --feature-gates UnifiedOrganizations=true
+  datum quota simulate --org-type Standard -o yaml`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if scenario.OrganizationType != "Personal" && scenario.OrganizationType != "Standard" {
+				return fmt.Errorf("--org-type must be Personal or Standard, got %q", scenario.OrganizationType)
+			}
+			if output != "table" && output != "yaml" {
+				return fmt.Errorf("--output must be table or yaml, got %q", output)
+			}
+
+			components := []string{filepath.Join(configDir, "services")}
+			if utilfeature.DefaultFeatureGate.Enabled(features.UnifiedOrganizations) {
+				components = append(components, filepath.Join(configDir, "overlays", "unified-organizations"))
+			}
+
+			objects, err := quota.Render(components...)
+			if err != nil {
+				return err
+			}
+			result, err := quota.Simulate(objects, scenario)
+			if err != nil {
+				return fmt.Errorf("unable to simulate quota policies: %w", err)
+			}
+
+			if output == "yaml" {
+				return printYAML(cmd.OutOrStdout(), result)
+			}
+			return printTable(cmd.OutOrStdout(), result)
+		},
+	}
+
+	cmd.Flags().StringVar(&scenario.OrganizationType, "org-type", "Personal", "The type of the simulated organization, Personal or Standard.")
+	cmd.Flags().StringVar(&scenario.OrganizationName, "org-name", scenario.OrganizationName, "The name of the simulated organization.")
+	cmd.Flags().StringVar(&scenario.ProjectName, "project-name", scenario.ProjectName, "The name of the simulated project.")
+	cmd.Flags().StringVar(&configDir, "config-dir", "config", "The directory containing the services and overlays kustomizations.")
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format. table prints a summary including total allowances, yaml prints the rendered ResourceGrants and ResourceClaims.")
+
+	namedFlagSets := cliflag.NamedFlagSets{}
+	utilfeature.DefaultMutableFeatureGate.AddFlag(namedFlagSets.FlagSet("feature gates"))
+	for _, fs := range namedFlagSets.FlagSets {
+		cmd.Flags().AddFlagSet(fs)
+	}
+
+	return cmd
+}
+
+func printTable(out io.Writer, result *quota.Result) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(w, "GRANTS")
+	fmt.Fprintln(w, "POLICY\tTRIGGER\tNAMESPACE\tNAME")
+	for _, grant := range result.Grants {
+		fmt.Fprintf(w, "%s\t%s/%s\t%s\t%s\n", grant.Policy, grant.Trigger.GetKind(), grant.Trigger.GetName(),
+			grant.Object.GetNamespace(), grant.Object.GetName())
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "CLAIMS")
+	fmt.Fprintln(w, "POLICY\tTRIGGER\tNAMESPACE\tNAME")
+	for _, claim := range result.Claims {
+		fmt.Fprintf(w, "%s\t%s/%s\t%s\t%s\n", claim.Policy, claim.Trigger.GetKind(), claim.Trigger.GetName(),
+			claim.Object.GetNamespace(), claim.Object.GetName())
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "ALLOWANCES")
+	fmt.Fprintln(w, "CONSUMER\tRESOURCE TYPE\tAMOUNT")
+	for _, a := range result.Allowances {
+		fmt.Fprintf(w, "%s/%s\t%s\t%d\n", a.ConsumerKind, a.ConsumerName, a.ResourceType, a.Amount)
+	}
+
+	return w.Flush()
+}
+
+func printYAML(out io.Writer, result *quota.Result) error {
+	var objects []any
+	for _, grant := range result.Grants {
+		objects = append(objects, grant.Object.Object)
+	}
+	for _, claim := range result.Claims {
+		objects = append(objects, claim.Object.Object)
+	}
+	for i, obj := range objects {
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			fmt.Fprintln(out, "---")
+		}
+		if _, err := out.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+package roles
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	iamv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
+
+	"go.datum.net/datum/internal/manifest"
+	"go.datum.net/datum/internal/roles"
+)
+
+func newExplainCommand() *cobra.Command {
+	var (
+		filenames   []string
+		kubeconfig  string
+		kubeContext string
+		namespace   string
+		diff        string
+		output      string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "explain ROLE",
+		Short: "Print the effective permissions of a role",
+		Long: `Explain expands the inheritedRoles of a Role recursively and prints every
+permission it grants, along with the role each permission is inherited from.
+
+Roles are read from the manifests given with --filename, or from the control
+plane of the current kubeconfig context when no manifests are given. Roles are
+referenced as NAMESPACE/NAME, or as NAME within --namespace.
+
+With --diff, the permissions of the two roles are compared instead: added
+permissions are granted by the --diff role only, removed permissions by ROLE
+only.`,
+		Example: `  datum roles explain editor
+  datum roles explain editor -f config/assignable-organization-roles/roles -o markdown
+  datum roles explain editor --diff owner -o json`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if output != "table" && output != "json" && output != "markdown" {
+				return fmt.Errorf("--output must be table, json or markdown, got %q", output)
+			}
+
+			var (
+				allRoles []iamv1alpha1.Role
+				err      error
+			)
+			if len(filenames) > 0 {
+				allRoles, err = loadManifestRoles(filenames, namespace)
+			} else {
+				allRoles, err = loadClusterRoles(cmd, kubeconfig, kubeContext)
+			}
+			if err != nil {
+				return err
+			}
+
+			graph := roles.NewGraph(allRoles)
+			explanation, err := graph.Explain(roleKey(args[0], namespace))
+			if err != nil {
+				return err
+			}
+			warnUnresolved(cmd.ErrOrStderr(), explanation)
+
+			if diff == "" {
+				return printExplanation(cmd.OutOrStdout(), explanation, output)
+			}
+
+			other, err := graph.Explain(roleKey(diff, namespace))
+			if err != nil {
+				return err
+			}
+			warnUnresolved(cmd.ErrOrStderr(), other)
+			return printDiff(cmd.OutOrStdout(), roles.DiffExplanations(explanation, other), output)
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&filenames, "filename", "f", nil,
+		"Role manifests or directories to read roles from. Defaults to reading roles from the control plane.")
+	cmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Path to the kubeconfig file used to read roles from the control plane.")
+	cmd.Flags().StringVar(&kubeContext, "context", "", "The kubeconfig context used to read roles from the control plane.")
+	cmd.Flags().StringVarP(&namespace, "namespace", "n", "datum-cloud",
+		"The namespace of roles referenced without one, and of datum role manifests without a namespace.")
+	cmd.Flags().StringVar(&diff, "diff", "", "A role to compare the permissions of ROLE with.")
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format, one of table, json or markdown.")
+
+	return cmd
+}
+
+func roleKey(ref, namespace string) roles.Key {
+	if ns, name, ok := strings.Cut(ref, "/"); ok {
+		return roles.Key{Namespace: ns, Name: name}
+	}
+	return roles.Key{Namespace: namespace, Name: ref}
+}
+
+func loadManifestRoles(paths []string, namespace string) ([]iamv1alpha1.Role, error) {
+	objects, err := manifest.Load(paths...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load manifests: %w", err)
+	}
+	return roles.FromManifests(objects, namespace)
+}
+
+func loadClusterRoles(cmd *cobra.Command, kubeconfig, kubeContext string) ([]iamv1alpha1.Role, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = kubeconfig
+	restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		loadingRules, &clientcmd.ConfigOverrides{CurrentContext: kubeContext},
+	).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("unable to load kubeconfig: %w", err)
+	}
+
+	scheme := runtime.NewScheme()
+	if err := iamv1alpha1.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	c, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, fmt.Errorf("unable to create client: %w", err)
+	}
+
+	list := &iamv1alpha1.RoleList{}
+	if err := c.List(cmd.Context(), list); err != nil {
+		return nil, fmt.Errorf("unable to list roles: %w", err)
+	}
+	return list.Items, nil
+}
+
+func warnUnresolved(out io.Writer, e *roles.Explanation) {
+	if len(e.Unresolved) > 0 {
+		fmt.Fprintf(out, "warning: %d role(s) inherited by %s were not found and their permissions are not included: %s\n",
+			len(e.Unresolved), e.Role, strings.Join(e.Unresolved, ", "))
+	}
+	for _, cycle := range e.Cycles {
+		fmt.Fprintf(out, "warning: inheritance cycle %s\n", cycle)
+	}
+}
+
+func printExplanation(out io.Writer, e *roles.Explanation, output string) error {
+	switch output {
+	case "json":
+		return printJSON(out, e)
+	case "markdown":
+		fmt.Fprintf(out, "## %s\n\n", e.Role)
+		fmt.Fprintf(out, "Inherits %d role(s) and grants %d permission(s).\n\n", len(e.InheritedRoles), len(e.Permissions))
+		fmt.Fprintln(out, "| Permission | Granted by |")
+		fmt.Fprintln(out, "| --- | --- |")
+		for _, grant := range e.Permissions {
+			fmt.Fprintf(out, "| `%s` | `%s` |\n", grant.Permission, grant.GrantedBy)
+		}
+		return nil
+	default:
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(w, "ROLE\t%s\n", e.Role)
+		fmt.Fprintf(w, "INHERITED ROLES\t%d\n", len(e.InheritedRoles))
+		fmt.Fprintf(w, "PERMISSIONS\t%d\n", len(e.Permissions))
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "PERMISSION\tGRANTED BY")
+		for _, grant := range e.Permissions {
+			fmt.Fprintf(w, "%s\t%s\n", grant.Permission, grant.GrantedBy)
+		}
+		return w.Flush()
+	}
+}
+
+func printDiff(out io.Writer, d *roles.Diff, output string) error {
+	switch output {
+	case "json":
+		return printJSON(out, d)
+	case "markdown":
+		fmt.Fprintf(out, "## %s compared to %s\n\n", d.To, d.From)
+		fmt.Fprintf(out, "%d permission(s) added, %d removed, %d in common.\n", len(d.Added), len(d.Removed), d.Common)
+		for _, section := range []struct {
+			title  string
+			grants []roles.Grant
+		}{{"Added", d.Added}, {"Removed", d.Removed}} {
+			if len(section.grants) == 0 {
+				continue
+			}
+			fmt.Fprintf(out, "\n### %s\n\n", section.title)
+			fmt.Fprintln(out, "| Permission | Granted by |")
+			fmt.Fprintln(out, "| --- | --- |")
+			for _, grant := range section.grants {
+				fmt.Fprintf(out, "| `%s` | `%s` |\n", grant.Permission, grant.GrantedBy)
+			}
+		}
+		return nil
+	default:
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(w, "FROM\t%s\n", d.From)
+		fmt.Fprintf(w, "TO\t%s\n", d.To)
+		fmt.Fprintf(w, "COMMON\t%d\n", d.Common)
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "\tPERMISSION\tGRANTED BY")
+		for _, grant := range d.Added {
+			fmt.Fprintf(w, "+\t%s\t%s\n", grant.Permission, grant.GrantedBy)
+		}
+		for _, grant := range d.Removed {
+			fmt.Fprintf(w, "-\t%s\t%s\n", grant.Permission, grant.GrantedBy)
+		}
+		return w.Flush()
+	}
+}
+
+func printJSON(out io.Writer, v any) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,21 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+package roles
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// NewRolesCommand creates the roles command and its subcommands.
+func NewRolesCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "roles",
+		Short: "Inspect IAM roles and their inherited permissions",
+		Long: `The roles commands resolve the inheritance of IAM Roles, such as the assignable
+organization roles in config/assignable-organization-roles, from manifests or
+from a control plane.`,
+	}
+
+	cmd.AddCommand(newExplainCommand())
+
+	return cmd
+}
@@ -0,0 +1,223 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package catalog defines the services that make up Datum Cloud and the Milo
+// roles that grant access to them. The assignable organization roles in
+// config/assignable-organization-roles/roles are generated from it with
+// `datum generate roles`.
+package catalog
+
+// LaunchStage is the maturity of a service.
+type LaunchStage string
+
+const (
+	// LaunchStageAlpha services are still being developed. Their roles are not
+	// included in the assignable organization roles and must be granted
+	// directly.
+	LaunchStageAlpha LaunchStage = "Alpha"
+	LaunchStageBeta  LaunchStage = "Beta"
+	LaunchStageGA    LaunchStage = "GA"
+)
+
+// Service is a Datum Cloud service and the Milo roles, in the milo-system
+// namespace, that grant access to it at each access level.
+type Service struct {
+	// Name identifies the service, usually by its API group.
+	Name string
+
+	LaunchStage LaunchStage
+
+	// ViewerRoles grant read access to the service.
+	ViewerRoles []string
+
+	// EditorRoles grant access to manage the resources of the service.
+	EditorRoles []string
+
+	// OwnerRoles grant full access to the service, including managing access
+	// to it.
+	OwnerRoles []string
+}
+
+// Level is the access level of an assignable role.
+type Level string
+
+const (
+	LevelViewer Level = "viewer"
+	LevelEditor Level = "editor"
+	LevelOwner  Level = "owner"
+)
+
+// AssignableRole is an organization role users can be assigned in the portal.
+type AssignableRole struct {
+	Level Level
+
+	DisplayName string
+	Description string
+
+	// SortOrder is the position of the role in the portal.
+	SortOrder int
+
+	// Product is the product the role is grouped under in the portal.
+	Product string
+
+	LaunchStage LaunchStage
+
+	// Inherits are the levels of other assignable roles included in this
+	// role.
+	Inherits []Level
+}
+
+// Services is the catalog of Datum Cloud services.
+var Services = []Service{
+	{
+		Name:        "core.miloapis.com",
+		LaunchStage: LaunchStageBeta,
+		EditorRoles: []string{"core-admin"},
+		OwnerRoles:  []string{"core-admin"},
+	},
+	{
+		Name:        "iam.miloapis.com/organizations",
+		LaunchStage: LaunchStageBeta,
+		OwnerRoles:  []string{"iam-organization-admin"},
+	},
+	{
+		Name:        "networking.datumapis.com",
+		LaunchStage: LaunchStageBeta,
+		ViewerRoles: []string{"networking.datumapis.com-viewer", "networking.datumapis.com-location-viewer"},
+		EditorRoles: []string{"networking.datumapis.com-admin"},
+		OwnerRoles:  []string{"networking.datumapis.com-admin", "networking.datumapis.com-locationbinding-viewer"},
+	},
+	{
+		Name:        "telemetry.miloapis.com",
+		LaunchStage: LaunchStageBeta,
+		ViewerRoles: []string{"telemetry.miloapis.com-viewer"},
+		EditorRoles: []string{"telemetry.miloapis.com-admin"},
+		OwnerRoles:  []string{"telemetry.miloapis.com-admin"},
+	},
+	{
+		Name:        "quota.miloapis.com",
+		LaunchStage: LaunchStageBeta,
+		ViewerRoles: []string{"quota.miloapis.com-organization-quota-manager"},
+	},
+	{
+		Name:        "resourcemanager.miloapis.com/projects",
+		LaunchStage: LaunchStageBeta,
+		ViewerRoles: []string{"resourcemanager.miloapis.com-project-viewer"},
+		EditorRoles: []string{"resourcemanager.miloapis.com-project-admin"},
+		OwnerRoles:  []string{"resourcemanager.miloapis.com-project-admin"},
+	},
+	{
+		Name:        "resourcemanager.miloapis.com/organizations",
+		LaunchStage: LaunchStageBeta,
+		ViewerRoles: []string{"resourcemanager.miloapis.com-organization-viewer"},
+		EditorRoles: []string{"resourcemanager.miloapis.com-organization-viewer"},
+		OwnerRoles:  []string{"resourcemanager.miloapis.com-organization-admin"},
+	},
+	{
+		Name:        "dns.networking.miloapis.com",
+		LaunchStage: LaunchStageBeta,
+		ViewerRoles: []string{"dns.networking.miloapis.com-dns-viewer"},
+		EditorRoles: []string{"dns.networking.miloapis.com-dns-admin"},
+		OwnerRoles:  []string{"dns.networking.miloapis.com-dns-admin"},
+	},
+	{
+		Name:        "iam.miloapis.com/userinvitations",
+		LaunchStage: LaunchStageBeta,
+		ViewerRoles: []string{"iam-user-invitations-reader"},
+		OwnerRoles:  []string{"iam-user-invitations-admin"},
+	},
+	{
+		Name:        "activity.miloapis.com",
+		LaunchStage: LaunchStageBeta,
+		ViewerRoles: []string{"activity.miloapis.com-viewer"},
+	},
+	{
+		Name:        "notes.miloapis.com",
+		LaunchStage: LaunchStageBeta,
+		ViewerRoles: []string{"notes-viewer"},
+		EditorRoles: []string{"notes-editor"},
+		OwnerRoles:  []string{"notes-admin"},
+	},
+	{
+		Name:        "identity.miloapis.com/serviceaccountkeys",
+		LaunchStage: LaunchStageBeta,
+		ViewerRoles: []string{"identity-service-account-keys-viewer"},
+		EditorRoles: []string{"identity-service-account-keys-editor"},
+		OwnerRoles:  []string{"identity-service-account-keys-admin"},
+	},
+	{
+		Name:        "iam.miloapis.com/serviceaccounts",
+		LaunchStage: LaunchStageBeta,
+		ViewerRoles: []string{"iam-service-accounts-viewer"},
+		EditorRoles: []string{"iam-service-accounts-editor"},
+		OwnerRoles:  []string{"iam-service-accounts-admin"},
+	},
+	{
+		Name:        "billing.miloapis.com",
+		LaunchStage: LaunchStageBeta,
+		ViewerRoles: []string{"billing.miloapis.com-viewer"},
+		EditorRoles: []string{"billing.miloapis.com-admin"},
+		OwnerRoles:  []string{"billing.miloapis.com-admin", "stripe.billing.miloapis.com-stripe-payment-method-viewer"},
+	},
+	{
+		Name:        "ipam.miloapis.com",
+		LaunchStage: LaunchStageBeta,
+		OwnerRoles:  []string{"ipam.miloapis.com-admin"},
+	},
+	{
+		Name:        "services.miloapis.com",
+		LaunchStage: LaunchStageBeta,
+		ViewerRoles: []string{"services.miloapis.com-entitlement-viewer"},
+		OwnerRoles:  []string{"services.miloapis.com-entitlement-admin"},
+	},
+	{
+		Name:        "compute.datumapis.com",
+		LaunchStage: LaunchStageBeta,
+		ViewerRoles: []string{"compute.datumapis.com-viewer"},
+		EditorRoles: []string{"compute.datumapis.com-admin"},
+		OwnerRoles:  []string{"compute.datumapis.com-admin"},
+	},
+}
+
+// AssignableRoles are the organization roles generated from the catalog.
+var AssignableRoles = []AssignableRole{
+	{
+		Level:       LevelOwner,
+		DisplayName: "Owner",
+		Description: "Full access to all Datum Cloud resources in the organization",
+		SortOrder:   10,
+		Product:     "Access Everything",
+		LaunchStage: LaunchStageBeta,
+		Inherits:    []Level{LevelViewer},
+	},
+	{
+		Level:       LevelEditor,
+		DisplayName: "Editor",
+		Description: "Edit access to all Datum Cloud resources in the organization, except managing team members",
+		SortOrder:   15,
+		Product:     "Access Everything",
+		LaunchStage: LaunchStageBeta,
+		Inherits:    []Level{LevelViewer},
+	},
+	{
+		Level:       LevelViewer,
+		DisplayName: "Viewer",
+		Description: "View access to all Datum Cloud resources in the organization",
+		SortOrder:   20,
+		Product:     "Access Everything",
+		LaunchStage: LaunchStageBeta,
+	},
+}
+
+// Roles returns the Milo roles granted by the service at the given level.
+func (s Service) Roles(level Level) []string {
+	switch level {
+	case LevelViewer:
+		return s.ViewerRoles
+	case LevelEditor:
+		return s.EditorRoles
+	case LevelOwner:
+		return s.OwnerRoles
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,124 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package catalog
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+)
+
+const (
+	// RoleNamespace is the namespace of the assignable organization roles.
+	RoleNamespace = "datum-cloud"
+
+	// ServiceRoleNamespace is the namespace of the Milo service roles.
+	ServiceRoleNamespace = "milo-system"
+)
+
+// generatedHeader marks the role manifests as generated.
+const generatedHeader = "# Code generated by `datum generate roles`. DO NOT EDIT.\n" +
+	"# Update the service catalog in internal/catalog instead.\n"
+
+// RoleReference is a role inherited by an assignable role.
+type RoleReference struct {
+	Name      string
+	Namespace string
+}
+
+// InheritedRoles returns the roles inherited by the assignable role: the other
+// assignable roles it includes, followed by the service roles for its level in
+// catalog order. Services in the Alpha launch stage are skipped, and each role
+// is listed once.
+func InheritedRoles(role AssignableRole, services []Service) []RoleReference {
+	var refs []RoleReference
+	for _, level := range role.Inherits {
+		refs = append(refs, RoleReference{Name: string(level), Namespace: RoleNamespace})
+	}
+	for _, service := range services {
+		if service.LaunchStage == LaunchStageAlpha {
+			continue
+		}
+		for _, name := range service.Roles(role.Level) {
+			ref := RoleReference{Name: name, Namespace: ServiceRoleNamespace}
+			if !slices.Contains(refs, ref) {
+				refs = append(refs, ref)
+			}
+		}
+	}
+	return refs
+}
+
+// Filename returns the name of the manifest file of the assignable role.
+func Filename(role AssignableRole) string {
+	return fmt.Sprintf("%s-%s.yaml", RoleNamespace, role.Level)
+}
+
+// RenderRole renders the manifest of the assignable role. The output only
+// depends on the catalog, so that regenerating an unchanged catalog produces
+// identical files.
+func RenderRole(role AssignableRole, services []Service) []byte {
+	var b bytes.Buffer
+	b.WriteString(generatedHeader)
+	b.WriteString("apiVersion: iam.miloapis.com/v1alpha1\n")
+	b.WriteString("kind: Role\n")
+	b.WriteString("metadata:\n")
+	fmt.Fprintf(&b, "  name: %s\n", role.Level)
+	b.WriteString("  annotations:\n")
+	fmt.Fprintf(&b, "    kubernetes.io/display-name: %s\n", strconv.Quote(role.DisplayName))
+	fmt.Fprintf(&b, "    kubernetes.io/description: %s\n", strconv.Quote(role.Description))
+	fmt.Fprintf(&b, "    taxonomy.miloapis.com/sort-order: \"%d\"\n", role.SortOrder)
+	fmt.Fprintf(&b, "    taxonomy.miloapis.com/product: %s\n", strconv.Quote(role.Product))
+	b.WriteString("spec:\n")
+	fmt.Fprintf(&b, "  launchStage: %s\n", role.LaunchStage)
+	b.WriteString("  inheritedRoles:\n")
+	for _, ref := range InheritedRoles(role, services) {
+		fmt.Fprintf(&b, "    - name: %s\n", ref.Name)
+		fmt.Fprintf(&b, "      namespace: %s\n", ref.Namespace)
+	}
+	return b.Bytes()
+}
+
+// Render renders the manifests of all assignable roles, keyed by file name.
+func Render() map[string][]byte {
+	files := make(map[string][]byte, len(AssignableRoles))
+	for _, role := range AssignableRoles {
+		files[Filename(role)] = RenderRole(role, Services)
+	}
+	return files
+}
+
+// WriteRoles writes the manifests of all assignable roles to dir and returns
+// the paths of the files that changed.
+func WriteRoles(dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	files := Render()
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var changed []string
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		current, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return changed, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if bytes.Equal(current, files[name]) {
+			continue
+		}
+		if err := os.WriteFile(path, files[name], 0o644); err != nil {
+			return changed, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		changed = append(changed, path)
+	}
+	return changed, nil
+}
@@ -0,0 +1,57 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package catalog
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const rolesDir = "../../config/assignable-organization-roles/roles"
+
+// TestGeneratedRolesUpToDate fails when the checked-in role manifests do not
+// match the catalog. Run `datum generate roles` to update them.
+func TestGeneratedRolesUpToDate(t *testing.T) {
+	for name, want := range Render() {
+		got, err := os.ReadFile(filepath.Join(rolesDir, name))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s is out of date with the service catalog, run `datum generate roles`", name)
+		}
+	}
+}
+
+func TestInheritedRoles(t *testing.T) {
+	services := []Service{
+		{Name: "a", LaunchStage: LaunchStageGA, ViewerRoles: []string{"a-viewer"}, OwnerRoles: []string{"a-admin"}},
+		{Name: "b", LaunchStage: LaunchStageAlpha, ViewerRoles: []string{"b-viewer"}},
+		{Name: "c", LaunchStage: LaunchStageBeta, ViewerRoles: []string{"a-viewer", "c-viewer"}},
+	}
+
+	got := InheritedRoles(AssignableRole{Level: LevelViewer}, services)
+	want := []RoleReference{
+		{Name: "a-viewer", Namespace: ServiceRoleNamespace},
+		{Name: "c-viewer", Namespace: ServiceRoleNamespace},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("expected %v at %d, got %v", want[i], i, got[i])
+		}
+	}
+
+	got = InheritedRoles(AssignableRole{Level: LevelOwner, Inherits: []Level{LevelViewer}}, services)
+	want = []RoleReference{
+		{Name: "viewer", Namespace: RoleNamespace},
+		{Name: "a-admin", Namespace: ServiceRoleNamespace},
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
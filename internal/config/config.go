@@ -4,19 +4,43 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
-
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/time/rate"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/client-go/util/workqueue"
 	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	iamv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
+	resourcemanagerv1alpha1 "go.miloapis.com/milo/pkg/apis/resourcemanager/v1alpha1"
 
+	"go.datum.net/datum/internal/catalog"
+	iamcontroller "go.datum.net/datum/internal/controller/iam"
 	resourcemanagercontroller "go.datum.net/datum/internal/controller/resourcemanager"
+	"go.datum.net/datum/internal/logging"
+	"go.datum.net/datum/internal/sharding"
+	resourcemanagerwebhook "go.datum.net/datum/internal/webhook/resourcemanager"
 )
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -28,9 +52,98 @@ type DatumControllerManager struct {
 	// MetricsServer is the configuration for the metrics server.
 	MetricsServer MetricsServerConfig `json:"metricsServer"`
 
+	// HealthProbes is the configuration for the liveness and readiness checks
+	// of the controller manager.
+	HealthProbes HealthProbesConfig `json:"healthProbes"`
+
+	// Logging is the configuration for the logs of the controller manager.
+	Logging LoggingConfig `json:"logging"`
+
+	// Tracing is the configuration for exporting OpenTelemetry traces of
+	// reconciles and API calls. Tracing is disabled by default.
+	Tracing TracingConfig `json:"tracing"`
+
+	// Sharding is the configuration for spreading the reconciliation of Users
+	// across replicas. Sharding is disabled by default.
+	Sharding ShardingConfig `json:"sharding"`
+
+	// Controllers is the configuration for the workers, backoff and rate
+	// limiting of the controllers.
+	Controllers ControllersConfig `json:"controllers"`
+
+	// Client is the configuration for the rate limiting of the client of the
+	// manager, shared by the controllers.
+	Client ClientConfig `json:"client"`
+
+	// ImpersonatedClient is the configuration for the rate limiting of the
+	// clients impersonating users, such as to create the projects of new
+	// organizations. An impersonated client is created for every reconcile,
+	// and the limits are shared by all of them.
+	ImpersonatedClient ClientConfig `json:"impersonatedClient"`
+
 	// PersonalOrganizationController is the configuration for the personal
 	// organization controller. Only active when UnifiedOrganizations is disabled.
 	PersonalOrganizationController resourcemanagercontroller.PersonalOrganizationControllerConfig `json:"personalOrganizationController"`
+
+	// OrganizationQuotaUsageController is the configuration for the controller
+	// that summarizes the quota usage of organizations. The controller only
+	// runs when the OrganizationQuotaUsage feature gate is enabled.
+	OrganizationQuotaUsageController resourcemanagercontroller.OrganizationQuotaUsageControllerConfig `json:"organizationQuotaUsageController"`
+
+	// OrganizationBootstrapController is the configuration for the controller
+	// that provisions the starter resources of new organizations. The
+	// controller only runs when the OrganizationBootstrap feature gate is
+	// enabled.
+	OrganizationBootstrapController resourcemanagercontroller.OrganizationBootstrapControllerConfig `json:"organizationBootstrapController"`
+
+	// OrganizationCustomRoleController is the configuration for the controller
+	// that materializes the custom roles of organizations. The controller only
+	// runs when the OrganizationCustomRoles feature gate is enabled.
+	OrganizationCustomRoleController resourcemanagercontroller.OrganizationCustomRoleControllerConfig `json:"organizationCustomRoleController"`
+
+	// OrganizationInvitationController is the configuration for the controller
+	// that accepts and expires organization invitations. The controller only
+	// runs when the OrganizationInvitations feature gate is enabled.
+	OrganizationInvitationController resourcemanagercontroller.OrganizationInvitationControllerConfig `json:"organizationInvitationController"`
+
+	// UserOffboardingController is the configuration for the controller that
+	// revokes the organization memberships of deactivated and rejected users.
+	// The controller only runs when the UserOffboarding feature gate is
+	// enabled.
+	UserOffboardingController resourcemanagercontroller.UserOffboardingControllerConfig `json:"userOffboardingController"`
+
+	// OrganizationTransferController is the configuration for the controller
+	// that transfers the projects of personal organizations to team
+	// organizations. The controller only runs when the OrganizationTransfer
+	// feature gate is enabled.
+	OrganizationTransferController resourcemanagercontroller.OrganizationTransferControllerConfig `json:"organizationTransferController"`
+
+	// UserOnboardingController is the configuration for the controller that
+	// approves new users and assigns them to organizations based on the domain
+	// of their email. The controller only runs when the UserOnboarding feature
+	// gate is enabled.
+	UserOnboardingController resourcemanagercontroller.UserOnboardingControllerConfig `json:"userOnboardingController"`
+
+	// UserWaitlistController is the configuration for the controller that
+	// approves waitlisted users in batches. The controller only runs when the
+	// UserWaitlist feature gate is enabled. When the UserOnboarding feature
+	// gate is enabled too, only users matching an onboarding rule are
+	// released.
+	UserWaitlistController resourcemanagercontroller.UserWaitlistControllerConfig `json:"userWaitlistController"`
+
+	// PersonalWorkspaceAuditor is the configuration for the auditor that finds
+	// approved users missing their personal organization, membership or
+	// project. The auditor is disabled by default.
+	PersonalWorkspaceAuditor resourcemanagercontroller.PersonalWorkspaceAuditorConfig `json:"personalWorkspaceAuditor"`
+
+	// LastOwnerProtectionWebhook is the configuration for the webhook that
+	// prevents the last owner of an organization from being removed.
+	LastOwnerProtectionWebhook resourcemanagerwebhook.LastOwnerProtectionWebhookConfig `json:"lastOwnerProtectionWebhook"`
+
+	// RoleCatalogController is the configuration for the controller that
+	// validates the assignable organization roles. The controller only runs
+	// when the RoleCatalog feature gate is enabled.
+	RoleCatalogController iamcontroller.RoleCatalogControllerConfig `json:"roleCatalogController"`
 }
 
 // +k8s:deepcopy-gen=true
@@ -62,6 +175,204 @@ func SetDefaults_MetricsServerConfig(obj *MetricsServerConfig) {
 	}
 }
 
+func SetDefaults_OrganizationQuotaUsageControllerConfig(obj *resourcemanagercontroller.OrganizationQuotaUsageControllerConfig) {
+	if obj.WarningThresholdPercent == 0 {
+		obj.WarningThresholdPercent = 80
+	}
+}
+
+func SetDefaults_OrganizationBootstrapControllerConfig(obj *resourcemanagercontroller.OrganizationBootstrapControllerConfig) {
+	if len(obj.OwnerRoles) == 0 {
+		obj.OwnerRoles = []resourcemanagerv1alpha1.RoleReference{
+			{
+				Name:      string(catalog.LevelOwner),
+				Namespace: catalog.RoleNamespace,
+			},
+		}
+	}
+
+	if len(obj.StarterGroups) == 0 {
+		obj.StarterGroups = []resourcemanagercontroller.StarterGroupConfig{
+			{
+				Name: "editors",
+				Role: resourcemanagerv1alpha1.RoleReference{
+					Name:      string(catalog.LevelEditor),
+					Namespace: catalog.RoleNamespace,
+				},
+			},
+			{
+				Name: "viewers",
+				Role: resourcemanagerv1alpha1.RoleReference{
+					Name:      string(catalog.LevelViewer),
+					Namespace: catalog.RoleNamespace,
+				},
+			},
+		}
+	}
+
+	if obj.MaxOrganizationAge.Duration == 0 {
+		obj.MaxOrganizationAge = metav1.Duration{Duration: time.Hour}
+	}
+}
+
+func SetDefaults_DefaultProjectConfig(obj *resourcemanagercontroller.DefaultProjectConfig) {
+	if obj.Enabled == nil {
+		obj.Enabled = ptr.To(true)
+	}
+
+	if obj.DisplayName == "" {
+		obj.DisplayName = "Default Project"
+	}
+}
+
+func SetDefaults_OrganizationCustomRoleControllerConfig(obj *resourcemanagercontroller.OrganizationCustomRoleControllerConfig) {
+	if len(obj.AllowedRoles) == 0 {
+		for _, level := range []catalog.Level{catalog.LevelViewer, catalog.LevelEditor} {
+			obj.AllowedRoles = append(obj.AllowedRoles, iamv1alpha1.ScopedRoleReference{Name: string(level), Namespace: catalog.RoleNamespace})
+		}
+		for _, service := range catalog.Services {
+			if service.LaunchStage == catalog.LaunchStageAlpha {
+				continue
+			}
+			for _, name := range service.ViewerRoles {
+				ref := iamv1alpha1.ScopedRoleReference{Name: name, Namespace: catalog.ServiceRoleNamespace}
+				if !slices.Contains(obj.AllowedRoles, ref) {
+					obj.AllowedRoles = append(obj.AllowedRoles, ref)
+				}
+			}
+		}
+	}
+
+	if obj.MaxRolesPerOrganization == 0 {
+		obj.MaxRolesPerOrganization = 20
+	}
+
+	if obj.LaunchStage == "" {
+		obj.LaunchStage = "Beta"
+	}
+}
+
+func SetDefaults_OrganizationInvitationControllerConfig(obj *resourcemanagercontroller.OrganizationInvitationControllerConfig) {
+	if obj.TTL.Duration == 0 {
+		obj.TTL = metav1.Duration{Duration: 7 * 24 * time.Hour}
+	}
+
+	if len(obj.DefaultRoles) == 0 {
+		obj.DefaultRoles = []resourcemanagerv1alpha1.RoleReference{
+			{
+				Name:      string(catalog.LevelViewer),
+				Namespace: catalog.RoleNamespace,
+			},
+		}
+	}
+
+	if len(obj.AllowedRoles) == 0 {
+		for _, role := range catalog.AssignableRoles {
+			obj.AllowedRoles = append(obj.AllowedRoles, resourcemanagerv1alpha1.RoleReference{
+				Name:      string(role.Level),
+				Namespace: catalog.RoleNamespace,
+			})
+		}
+	}
+}
+
+func SetDefaults_UserOffboardingControllerConfig(obj *resourcemanagercontroller.UserOffboardingControllerConfig) {
+	if obj.Action == "" {
+		obj.Action = resourcemanagercontroller.OffboardingActionSuspend
+	}
+
+	if len(obj.OwnerRoles) == 0 {
+		obj.OwnerRoles = []resourcemanagerv1alpha1.RoleReference{
+			{
+				Name:      string(catalog.LevelOwner),
+				Namespace: catalog.RoleNamespace,
+			},
+		}
+	}
+}
+
+func SetDefaults_OrganizationTransferControllerConfig(obj *resourcemanagercontroller.OrganizationTransferControllerConfig) {
+	if obj.SourceAction == "" {
+		obj.SourceAction = resourcemanagercontroller.TransferSourceActionArchive
+	}
+}
+
+func SetDefaults_OnboardingRule(obj *resourcemanagercontroller.OnboardingRule) {
+	if obj.Organization != "" && len(obj.Roles) == 0 {
+		obj.Roles = []resourcemanagerv1alpha1.RoleReference{
+			{
+				Name:      "viewer",
+				Namespace: "datum-cloud",
+			},
+		}
+	}
+}
+
+func SetDefaults_UserWaitlistControllerConfig(obj *resourcemanagercontroller.UserWaitlistControllerConfig) {
+	if obj.Order == "" {
+		obj.Order = resourcemanagercontroller.WaitlistOrderFIFO
+	}
+
+	if obj.BatchSize == 0 {
+		obj.BatchSize = 10
+	}
+
+	if obj.Interval.Duration == 0 {
+		obj.Interval = metav1.Duration{Duration: time.Minute}
+	}
+
+	if obj.ProvisioningTimeout.Duration == 0 {
+		obj.ProvisioningTimeout = metav1.Duration{Duration: 10 * time.Minute}
+	}
+
+	if obj.MaxProvisioningFailures == 0 {
+		obj.MaxProvisioningFailures = 5
+	}
+
+	if obj.MaxPositions == 0 {
+		obj.MaxPositions = 100
+	}
+}
+
+func SetDefaults_PersonalWorkspaceAuditorConfig(obj *resourcemanagercontroller.PersonalWorkspaceAuditorConfig) {
+	if obj.Interval.Duration == 0 {
+		obj.Interval = metav1.Duration{Duration: time.Hour}
+	}
+
+	if obj.GracePeriod.Duration == 0 {
+		obj.GracePeriod = metav1.Duration{Duration: 10 * time.Minute}
+	}
+}
+
+func SetDefaults_LastOwnerProtectionWebhookConfig(obj *resourcemanagerwebhook.LastOwnerProtectionWebhookConfig) {
+	if len(obj.OwnerRoles) == 0 {
+		obj.OwnerRoles = []resourcemanagerv1alpha1.RoleReference{
+			{
+				Name:      "owner",
+				Namespace: "datum-cloud",
+			},
+		}
+	}
+
+	if len(obj.ExemptUsers) == 0 {
+		obj.ExemptUsers = []string{"system:serviceaccount:datum-system:datum-controller-manager"}
+	}
+
+	if len(obj.ExemptGroups) == 0 {
+		obj.ExemptGroups = []string{"datum:break-glass"}
+	}
+}
+
+func SetDefaults_RoleCatalogControllerConfig(obj *iamcontroller.RoleCatalogControllerConfig) {
+	if obj.Namespace == "" {
+		obj.Namespace = "datum-cloud"
+	}
+
+	if len(obj.ReadOnlyRoles) == 0 {
+		obj.ReadOnlyRoles = []string{"viewer"}
+	}
+}
+
 func (c *MetricsServerConfig) Options(ctx context.Context, secretsClient client.Client) metricsserver.Options {
 	opts := metricsserver.Options{
 		SecureServing: *c.SecureServing,
@@ -88,6 +399,415 @@ func (c *MetricsServerConfig) Options(ctx context.Context, secretsClient client.
 
 // +k8s:deepcopy-gen=true
 
+type HealthProbesConfig struct {
+	// ReconcileStallTimeout is how long a single reconcile may run before the
+	// liveness check fails and the manager is restarted. Defaults to 15m.
+	ReconcileStallTimeout metav1.Duration `json:"reconcileStallTimeout"`
+}
+
+func SetDefaults_HealthProbesConfig(obj *HealthProbesConfig) {
+	if obj.ReconcileStallTimeout.Duration == 0 {
+		obj.ReconcileStallTimeout = metav1.Duration{Duration: 15 * time.Minute}
+	}
+}
+
+// +k8s:deepcopy-gen=true
+
+type LoggingConfig struct {
+	// Format is the encoding of log lines, json or console. Defaults to json.
+	Format logging.Format `json:"format"`
+
+	// Level is the minimum level of logged entries: debug, info, warn, error,
+	// or a verbosity such as 2. Defaults to info.
+	Level string `json:"level"`
+
+	// Levels overrides the level of named loggers, such as
+	// controller-runtime.webhook, and their descendants.
+	Levels map[string]string `json:"levels,omitempty"`
+
+	// StacktraceLevel is the minimum level of entries logged with a stack
+	// trace. Defaults to error.
+	StacktraceLevel string `json:"stacktraceLevel"`
+
+	// Sampling limits the number of repeated log entries.
+	Sampling LogSamplingConfig `json:"sampling"`
+
+	// Redaction hides sensitive user data in log entries.
+	Redaction LogRedactionConfig `json:"redaction"`
+}
+
+// +k8s:deepcopy-gen=true
+
+type LogSamplingConfig struct {
+	// Enabled enables sampling. Sampling is ignored for verbosities above 1.
+	// Defaults to true.
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// Initial is the number of entries with the same message logged each
+	// second. Defaults to 100.
+	Initial int `json:"initial"`
+
+	// Thereafter is the sampling rate of entries after the initial entries.
+	// Defaults to 100, logging every hundredth entry.
+	Thereafter int `json:"thereafter"`
+}
+
+// +k8s:deepcopy-gen=true
+
+type LogRedactionConfig struct {
+	// Enabled replaces the values of the redacted keys in log entries.
+	// Defaults to true.
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// Keys are the keys of redacted values. Defaults to email, givenName and
+	// familyName.
+	Keys []string `json:"keys,omitempty"`
+}
+
+func SetDefaults_LoggingConfig(obj *LoggingConfig) {
+	if obj.Format == "" {
+		obj.Format = logging.FormatJSON
+	}
+
+	if obj.Level == "" {
+		obj.Level = "info"
+	}
+
+	if obj.StacktraceLevel == "" {
+		obj.StacktraceLevel = "error"
+	}
+}
+
+func SetDefaults_LogSamplingConfig(obj *LogSamplingConfig) {
+	if obj.Enabled == nil {
+		obj.Enabled = ptr.To(true)
+	}
+
+	if obj.Initial == 0 {
+		obj.Initial = 100
+	}
+
+	if obj.Thereafter == 0 {
+		obj.Thereafter = 100
+	}
+}
+
+func SetDefaults_LogRedactionConfig(obj *LogRedactionConfig) {
+	if obj.Enabled == nil {
+		obj.Enabled = ptr.To(true)
+	}
+
+	if len(obj.Keys) == 0 {
+		obj.Keys = []string{"email", "givenName", "familyName"}
+	}
+}
+
+// NewLogger returns a logger writing to w.
+func (c *LoggingConfig) NewLogger(w io.Writer) (logr.Logger, error) {
+	if c.Format != logging.FormatJSON && c.Format != logging.FormatConsole {
+		return logr.Logger{}, fmt.Errorf("invalid log format %q", c.Format)
+	}
+	opts := logging.Options{Format: c.Format}
+
+	var err error
+	if opts.Level, err = parseLogLevel(c.Level); err != nil {
+		return logr.Logger{}, err
+	}
+	if opts.StacktraceLevel, err = parseLogLevel(c.StacktraceLevel); err != nil {
+		return logr.Logger{}, err
+	}
+	if len(c.Levels) > 0 {
+		opts.Levels = make(map[string]zapcore.Level, len(c.Levels))
+		for name, value := range c.Levels {
+			if opts.Levels[name], err = parseLogLevel(value); err != nil {
+				return logr.Logger{}, fmt.Errorf("logger %s: %w", name, err)
+			}
+		}
+	}
+
+	if ptr.Deref(c.Sampling.Enabled, false) {
+		opts.SamplingInitial = c.Sampling.Initial
+		opts.SamplingThereafter = c.Sampling.Thereafter
+	}
+	if ptr.Deref(c.Redaction.Enabled, false) {
+		opts.RedactedKeys = c.Redaction.Keys
+	}
+
+	return logging.New(w, opts), nil
+}
+
+// parseLogLevel parses a zap level name, or a logr verbosity.
+func parseLogLevel(value string) (zapcore.Level, error) {
+	if verbosity, err := strconv.Atoi(value); err == nil {
+		if verbosity < 0 {
+			return 0, fmt.Errorf("invalid log verbosity %d", verbosity)
+		}
+		return zapcore.Level(-verbosity), nil
+	}
+	level, err := zapcore.ParseLevel(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid log level %q", value)
+	}
+	return level, nil
+}
+
+// +k8s:deepcopy-gen=true
+
+type TracingConfig struct {
+	// Enabled exports traces to the OTLP endpoint.
+	Enabled bool `json:"enabled"`
+
+	// Endpoint is the host and port of the OTLP gRPC endpoint traces are
+	// exported to. Defaults to localhost:4317.
+	Endpoint string `json:"endpoint"`
+
+	// Insecure disables TLS when connecting to the endpoint.
+	Insecure bool `json:"insecure"`
+
+	// ServiceName is the service name traces are reported with. Defaults to
+	// datum-controller-manager.
+	ServiceName string `json:"serviceName"`
+
+	// SamplingPercent is the percentage of traces that are sampled, unless the
+	// trace was already sampled by its parent. Defaults to 100.
+	SamplingPercent int `json:"samplingPercent"`
+}
+
+func SetDefaults_TracingConfig(obj *TracingConfig) {
+	if obj.Endpoint == "" {
+		obj.Endpoint = "localhost:4317"
+	}
+
+	if obj.ServiceName == "" {
+		obj.ServiceName = "datum-controller-manager"
+	}
+
+	if obj.SamplingPercent == 0 {
+		obj.SamplingPercent = 100
+	}
+}
+
+// NewTracerProvider returns a tracer provider exporting traces to the OTLP
+// endpoint. The provider must be shut down to flush pending spans.
+func (c *TracingConfig) NewTracerProvider(ctx context.Context) (*sdktrace.TracerProvider, error) {
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(c.Endpoint)}
+	if c.Insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", c.ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(float64(c.SamplingPercent)/100))),
+	), nil
+}
+
+// +k8s:deepcopy-gen=true
+
+type ShardingConfig struct {
+	// Enabled spreads the reconciliation of Users across the replicas of the
+	// controller manager. Replicas claim shards of User UIDs through Leases,
+	// and the User controllers run on every replica for the Users of its
+	// shards. The other controllers still run on the leader only.
+	Enabled bool `json:"enabled"`
+
+	// Shards is the number of shards User UIDs are split into. It must be the
+	// same on every replica, and should be larger than the number of
+	// replicas. Defaults to 32.
+	Shards int `json:"shards"`
+
+	// LeaseNamespace is the namespace of the shard Leases. The leader election
+	// Role grants access to the Leases of the namespace of the controller
+	// manager. Defaults to datum-system.
+	LeaseNamespace string `json:"leaseNamespace"`
+
+	// LeaseDuration is how long a replica owns a shard without renewing its
+	// Lease. Defaults to 15s.
+	LeaseDuration metav1.Duration `json:"leaseDuration"`
+
+	// RenewInterval is the time between two renewals of the shard Leases,
+	// during which replicas also rebalance shards. Defaults to 5s.
+	RenewInterval metav1.Duration `json:"renewInterval"`
+}
+
+func SetDefaults_ShardingConfig(obj *ShardingConfig) {
+	if obj.Shards == 0 {
+		obj.Shards = 32
+	}
+
+	if obj.LeaseNamespace == "" {
+		obj.LeaseNamespace = "datum-system"
+	}
+
+	if obj.LeaseDuration.Duration == 0 {
+		obj.LeaseDuration = metav1.Duration{Duration: 15 * time.Second}
+	}
+
+	if obj.RenewInterval.Duration == 0 {
+		obj.RenewInterval = metav1.Duration{Duration: 5 * time.Second}
+	}
+}
+
+// NewCoordinator returns the coordinator claiming shards for the replica
+// with the given identity, using Leases prefixed with name. Leases are written
+// with leaseClient, and the Users of claimed shards are listed with reader.
+func (c *ShardingConfig) NewCoordinator(leaseClient client.Client, reader client.Reader, name, identity string) *sharding.Coordinator {
+	return &sharding.Coordinator{
+		Client:        leaseClient,
+		Reader:        reader,
+		Name:          name,
+		Identity:      identity,
+		Namespace:     c.LeaseNamespace,
+		Shards:        c.Shards,
+		LeaseDuration: c.LeaseDuration.Duration,
+		RenewInterval: c.RenewInterval.Duration,
+	}
+}
+
+// +k8s:deepcopy-gen=true
+
+type ControllersConfig struct {
+	// Defaults are the settings of every controller without an override. They
+	// default to the settings of controller-runtime: a single worker, a
+	// backoff from 5ms to 1000s, and 10 requests per second with a burst of
+	// 100.
+	Defaults ControllerConfig `json:"defaults"`
+
+	// Overrides are the settings of individual controllers, by controller name
+	// such as personal-organization. Unset fields are taken from Defaults.
+	Overrides map[string]ControllerConfig `json:"overrides,omitempty"`
+}
+
+// +k8s:deepcopy-gen=true
+
+type ControllerConfig struct {
+	// MaxConcurrentReconciles is the number of workers reconciling requests
+	// in parallel. A request is never reconciled by two workers at once.
+	MaxConcurrentReconciles int `json:"maxConcurrentReconciles,omitempty"`
+
+	// BaseBackoff is the delay before a failed request is retried. The delay
+	// doubles on every consecutive failure of the request.
+	BaseBackoff metav1.Duration `json:"baseBackoff,omitempty"`
+
+	// MaxBackoff is the maximum delay before a failed request is retried.
+	MaxBackoff metav1.Duration `json:"maxBackoff,omitempty"`
+
+	// QPS is the number of requests per second added to the queue of the
+	// controller, shared by every request.
+	QPS float32 `json:"qps,omitempty"`
+
+	// Burst is the number of requests added to the queue at once above QPS.
+	Burst int `json:"burst,omitempty"`
+}
+
+func SetDefaults_ControllersConfig(obj *ControllersConfig) {
+	if obj.Defaults.MaxConcurrentReconciles == 0 {
+		obj.Defaults.MaxConcurrentReconciles = 1
+	}
+
+	if obj.Defaults.BaseBackoff.Duration == 0 {
+		obj.Defaults.BaseBackoff = metav1.Duration{Duration: 5 * time.Millisecond}
+	}
+
+	if obj.Defaults.MaxBackoff.Duration == 0 {
+		obj.Defaults.MaxBackoff = metav1.Duration{Duration: 1000 * time.Second}
+	}
+
+	if obj.Defaults.QPS == 0 {
+		obj.Defaults.QPS = 10
+	}
+
+	if obj.Defaults.Burst == 0 {
+		obj.Defaults.Burst = 100
+	}
+}
+
+// Controller returns the settings of the named controller, its override
+// merged over the defaults.
+func (c *ControllersConfig) Controller(name string) ControllerConfig {
+	settings := c.Defaults
+	override := c.Overrides[name]
+	if override.MaxConcurrentReconciles != 0 {
+		settings.MaxConcurrentReconciles = override.MaxConcurrentReconciles
+	}
+	if override.BaseBackoff.Duration != 0 {
+		settings.BaseBackoff = override.BaseBackoff
+	}
+	if override.MaxBackoff.Duration != 0 {
+		settings.MaxBackoff = override.MaxBackoff
+	}
+	if override.QPS != 0 {
+		settings.QPS = override.QPS
+	}
+	if override.Burst != 0 {
+		settings.Burst = override.Burst
+	}
+	return settings
+}
+
+// Options returns the options of the named controller.
+func (c *ControllersConfig) Options(name string) controller.Options {
+	return controller.Options{
+		MaxConcurrentReconciles: c.Controller(name).MaxConcurrentReconciles,
+		RateLimiter:             c.RateLimiter(name),
+	}
+}
+
+// RateLimiter returns a new rate limiter of the named controller. Requests
+// are delayed by the larger of their backoff and the wait for the QPS bucket.
+func (c *ControllersConfig) RateLimiter(name string) workqueue.TypedRateLimiter[reconcile.Request] {
+	settings := c.Controller(name)
+	return workqueue.NewTypedMaxOfRateLimiter(
+		workqueue.NewTypedItemExponentialFailureRateLimiter[reconcile.Request](settings.BaseBackoff.Duration, settings.MaxBackoff.Duration),
+		&workqueue.TypedBucketRateLimiter[reconcile.Request]{Limiter: rate.NewLimiter(rate.Limit(settings.QPS), settings.Burst)},
+	)
+}
+
+// +k8s:deepcopy-gen=true
+
+type ClientConfig struct {
+	// QPS is the number of requests per second sent by the client. Defaults
+	// to 20.
+	QPS float32 `json:"qps"`
+
+	// Burst is the number of requests sent at once above QPS. Defaults to 30.
+	Burst int `json:"burst"`
+}
+
+func SetDefaults_ClientConfig(obj *ClientConfig) {
+	if obj.QPS == 0 {
+		obj.QPS = 20
+	}
+
+	if obj.Burst == 0 {
+		obj.Burst = 30
+	}
+}
+
+// Apply sets the rate limits of the client on a copy of restConfig. The
+// clients created from the returned config, and from copies of it, share a
+// single rate limiter.
+func (c *ClientConfig) Apply(restConfig *rest.Config) *rest.Config {
+	restConfig = rest.CopyConfig(restConfig)
+	restConfig.QPS = c.QPS
+	restConfig.Burst = c.Burst
+	restConfig.RateLimiter = flowcontrol.NewTokenBucketRateLimiter(c.QPS, c.Burst)
+	return restConfig
+}
+
+// +k8s:deepcopy-gen=true
+
 type TLSConfig struct {
 	// SecretRef is a reference to a secret that contains the server key and
 	// certificate. If provided, CertDir will be ignored, and CertName and KeyName
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+)
+
+func TestControllersConfig(t *testing.T) {
+	c := &ControllersConfig{
+		Overrides: map[string]ControllerConfig{
+			"personal-organization": {MaxConcurrentReconciles: 8, MaxBackoff: metav1.Duration{Duration: time.Minute}},
+		},
+	}
+	SetDefaults_ControllersConfig(c)
+
+	if got := c.Controller("role-catalog"); got != c.Defaults {
+		t.Errorf("expected a controller without override to use the defaults, got %+v", got)
+	}
+
+	got := c.Controller("personal-organization")
+	want := c.Defaults
+	want.MaxConcurrentReconciles = 8
+	want.MaxBackoff = metav1.Duration{Duration: time.Minute}
+	if got != want {
+		t.Errorf("expected the override to be merged over the defaults, got %+v, want %+v", got, want)
+	}
+
+	opts := c.Options("personal-organization")
+	if opts.MaxConcurrentReconciles != 8 {
+		t.Errorf("expected 8 workers, got %d", opts.MaxConcurrentReconciles)
+	}
+	if opts.RateLimiter == nil {
+		t.Error("expected a rate limiter")
+	}
+	if c.RateLimiter("personal-organization") == opts.RateLimiter {
+		t.Error("expected a new rate limiter for every call")
+	}
+}
+
+func TestClientConfigApply(t *testing.T) {
+	c := &ClientConfig{}
+	SetDefaults_ClientConfig(c)
+	config := c.Apply(&rest.Config{Host: "https://127.0.0.1:6443"})
+
+	// Impersonated clients are created from copies of the config.
+	newClient := func() *rest.RESTClient {
+		t.Helper()
+		clientConfig := rest.CopyConfig(config)
+		clientConfig.Impersonate = rest.ImpersonationConfig{UserName: "user"}
+		clientConfig.NegotiatedSerializer = scheme.Codecs.WithoutConversion()
+		client, err := rest.UnversionedRESTClientFor(clientConfig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return client
+	}
+	first, second := newClient(), newClient()
+	if first.GetRateLimiter() == nil || first.GetRateLimiter() != second.GetRateLimiter() {
+		t.Error("expected the clients to share a rate limiter")
+	}
+	if first.GetRateLimiter().QPS() != c.QPS {
+		t.Errorf("expected the rate limiter to allow %v requests per second, got %v", c.QPS, first.GetRateLimiter().QPS())
+	}
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	resourcemanagercontroller "go.datum.net/datum/internal/controller/resourcemanager"
+)
+
+// Validate reports invalid settings of a defaulted config.
+func (c *DatumControllerManager) Validate() error {
+	var errs []error
+
+	if c.Tracing.SamplingPercent < 0 || c.Tracing.SamplingPercent > 100 {
+		errs = append(errs, fmt.Errorf("tracing.samplingPercent must be between 0 and 100, got %d", c.Tracing.SamplingPercent))
+	}
+
+	if c.HealthProbes.ReconcileStallTimeout.Duration <= 0 {
+		errs = append(errs, errors.New("healthProbes.reconcileStallTimeout must be positive"))
+	}
+
+	if c.Sharding.Shards <= 0 {
+		errs = append(errs, fmt.Errorf("sharding.shards must be positive, got %d", c.Sharding.Shards))
+	}
+	if c.Sharding.RenewInterval.Duration >= c.Sharding.LeaseDuration.Duration {
+		errs = append(errs, errors.New("sharding.renewInterval must be shorter than sharding.leaseDuration"))
+	}
+
+	errs = append(errs, validateController("controllers.defaults", c.Controllers.Defaults)...)
+	for _, name := range slices.Sorted(maps.Keys(c.Controllers.Overrides)) {
+		if !controllerNames.Has(name) {
+			errs = append(errs, fmt.Errorf("controllers.overrides[%s] is not a controller, expected one of %v", name, sets.List(controllerNames)))
+			continue
+		}
+		errs = append(errs, validateController(fmt.Sprintf("controllers.overrides[%s]", name), c.Controllers.Controller(name))...)
+	}
+
+	if c.Client.QPS <= 0 || c.Client.Burst <= 0 {
+		errs = append(errs, errors.New("client.qps and client.burst must be positive"))
+	}
+	if c.ImpersonatedClient.QPS <= 0 || c.ImpersonatedClient.Burst <= 0 {
+		errs = append(errs, errors.New("impersonatedClient.qps and impersonatedClient.burst must be positive"))
+	}
+
+	names := map[string]bool{}
+	for i, rule := range c.UserOnboardingController.Rules {
+		if rule.Name == "" {
+			errs = append(errs, fmt.Errorf("userOnboardingController.rules[%d].name is required", i))
+		} else if names[rule.Name] {
+			errs = append(errs, fmt.Errorf("userOnboardingController.rules[%d].name %q is not unique", i, rule.Name))
+		}
+		names[rule.Name] = true
+		if len(rule.Domains) == 0 {
+			errs = append(errs, fmt.Errorf("userOnboardingController.rules[%d].domains is required", i))
+		}
+	}
+
+	switch c.UserWaitlistController.Order {
+	case resourcemanagercontroller.WaitlistOrderFIFO, resourcemanagercontroller.WaitlistOrderPriority:
+	default:
+		errs = append(errs, fmt.Errorf("userWaitlistController.order %q is not FIFO or Priority", c.UserWaitlistController.Order))
+	}
+	if c.UserWaitlistController.BatchSize < 0 {
+		errs = append(errs, fmt.Errorf("userWaitlistController.batchSize must be positive, got %d", c.UserWaitlistController.BatchSize))
+	}
+	if c.UserWaitlistController.MaxPositions < 0 {
+		errs = append(errs, fmt.Errorf("userWaitlistController.maxPositions must be positive, got %d", c.UserWaitlistController.MaxPositions))
+	}
+
+	if c.PersonalWorkspaceAuditor.Interval.Duration < 0 {
+		errs = append(errs, errors.New("personalWorkspaceAuditor.interval must be positive"))
+	}
+
+	switch c.UserOffboardingController.Action {
+	case resourcemanagercontroller.OffboardingActionSuspend, resourcemanagercontroller.OffboardingActionRemove:
+	default:
+		errs = append(errs, fmt.Errorf("userOffboardingController.action %q is not Suspend or Remove", c.UserOffboardingController.Action))
+	}
+
+	switch c.OrganizationTransferController.SourceAction {
+	case resourcemanagercontroller.TransferSourceActionArchive, resourcemanagercontroller.TransferSourceActionConvert:
+	default:
+		errs = append(errs, fmt.Errorf("organizationTransferController.sourceAction %q is not Archive or Convert", c.OrganizationTransferController.SourceAction))
+	}
+
+	return errors.Join(errs...)
+}
+
+// controllerNames are the names of the controllers whose settings can be
+// overridden.
+var controllerNames = sets.New(
+	"organization-bootstrap",
+	"organization-custom-role",
+	"organization-invitation",
+	"organization-quota-usage",
+	"organization-transfer",
+	"personal-organization",
+	"personal-workspace-auditor",
+	"role-catalog",
+	"user-offboarding",
+	"user-onboarding",
+	"user-waitlist",
+)
+
+// validateController reports invalid settings of a controller, merged with the
+// defaults.
+func validateController(path string, c ControllerConfig) []error {
+	var errs []error
+	if c.MaxConcurrentReconciles <= 0 {
+		errs = append(errs, fmt.Errorf("%s.maxConcurrentReconciles must be positive, got %d", path, c.MaxConcurrentReconciles))
+	}
+	if c.BaseBackoff.Duration <= 0 {
+		errs = append(errs, fmt.Errorf("%s.baseBackoff must be positive", path))
+	}
+	if c.MaxBackoff.Duration < c.BaseBackoff.Duration {
+		errs = append(errs, fmt.Errorf("%s.maxBackoff must not be shorter than %s.baseBackoff", path, path))
+	}
+	if c.QPS <= 0 || c.Burst <= 0 {
+		errs = append(errs, fmt.Errorf("%s.qps and %s.burst must be positive", path, path))
+	}
+	return errs
+}
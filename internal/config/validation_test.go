@@ -0,0 +1,40 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	resourcemanagercontroller "go.datum.net/datum/internal/controller/resourcemanager"
+)
+
+func TestValidate(t *testing.T) {
+	valid := &DatumControllerManager{}
+	SetObjectDefaults_DatumControllerManager(valid)
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("expected defaulted config to be valid, got %v", err)
+	}
+
+	invalid := valid.DeepCopy()
+	invalid.Tracing.SamplingPercent = 150
+	invalid.UserWaitlistController.Order = "Random"
+	invalid.UserOnboardingController.Rules = []resourcemanagercontroller.OnboardingRule{
+		{Name: "staff", Domains: []string{"datum.net"}},
+		{Name: "staff"},
+	}
+	if err := invalid.Validate(); err == nil {
+		t.Fatal("expected config to be invalid")
+	}
+
+	for name, override := range map[string]ControllerConfig{
+		"unknown-controller": {MaxConcurrentReconciles: 4},
+		"user-onboarding":    {MaxBackoff: metav1.Duration{Duration: time.Millisecond}},
+	} {
+		invalid := valid.DeepCopy()
+		invalid.Controllers.Overrides = map[string]ControllerConfig{name: override}
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("expected override of %s to be invalid", name)
+		}
+	}
+}
@@ -11,12 +11,85 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClientConfig) DeepCopyInto(out *ClientConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClientConfig.
+func (in *ClientConfig) DeepCopy() *ClientConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ClientConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControllerConfig) DeepCopyInto(out *ControllerConfig) {
+	*out = *in
+	out.BaseBackoff = in.BaseBackoff
+	out.MaxBackoff = in.MaxBackoff
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControllerConfig.
+func (in *ControllerConfig) DeepCopy() *ControllerConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ControllerConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControllersConfig) DeepCopyInto(out *ControllersConfig) {
+	*out = *in
+	out.Defaults = in.Defaults
+	if in.Overrides != nil {
+		in, out := &in.Overrides, &out.Overrides
+		*out = make(map[string]ControllerConfig, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControllersConfig.
+func (in *ControllersConfig) DeepCopy() *ControllersConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ControllersConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DatumControllerManager) DeepCopyInto(out *DatumControllerManager) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.MetricsServer.DeepCopyInto(&out.MetricsServer)
+	out.HealthProbes = in.HealthProbes
+	in.Logging.DeepCopyInto(&out.Logging)
+	out.Tracing = in.Tracing
+	out.Sharding = in.Sharding
+	in.Controllers.DeepCopyInto(&out.Controllers)
+	out.Client = in.Client
+	out.ImpersonatedClient = in.ImpersonatedClient
 	out.PersonalOrganizationController = in.PersonalOrganizationController
+	out.OrganizationQuotaUsageController = in.OrganizationQuotaUsageController
+	in.OrganizationBootstrapController.DeepCopyInto(&out.OrganizationBootstrapController)
+	in.OrganizationCustomRoleController.DeepCopyInto(&out.OrganizationCustomRoleController)
+	in.OrganizationInvitationController.DeepCopyInto(&out.OrganizationInvitationController)
+	in.UserOffboardingController.DeepCopyInto(&out.UserOffboardingController)
+	out.OrganizationTransferController = in.OrganizationTransferController
+	in.UserOnboardingController.DeepCopyInto(&out.UserOnboardingController)
+	out.UserWaitlistController = in.UserWaitlistController
+	out.PersonalWorkspaceAuditor = in.PersonalWorkspaceAuditor
+	in.LastOwnerProtectionWebhook.DeepCopyInto(&out.LastOwnerProtectionWebhook)
+	in.RoleCatalogController.DeepCopyInto(&out.RoleCatalogController)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatumControllerManager.
@@ -37,6 +110,91 @@ func (in *DatumControllerManager) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HealthProbesConfig) DeepCopyInto(out *HealthProbesConfig) {
+	*out = *in
+	out.ReconcileStallTimeout = in.ReconcileStallTimeout
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HealthProbesConfig.
+func (in *HealthProbesConfig) DeepCopy() *HealthProbesConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(HealthProbesConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LogRedactionConfig) DeepCopyInto(out *LogRedactionConfig) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Keys != nil {
+		in, out := &in.Keys, &out.Keys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LogRedactionConfig.
+func (in *LogRedactionConfig) DeepCopy() *LogRedactionConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(LogRedactionConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LogSamplingConfig) DeepCopyInto(out *LogSamplingConfig) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LogSamplingConfig.
+func (in *LogSamplingConfig) DeepCopy() *LogSamplingConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(LogSamplingConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LoggingConfig) DeepCopyInto(out *LoggingConfig) {
+	*out = *in
+	if in.Levels != nil {
+		in, out := &in.Levels, &out.Levels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	in.Sampling.DeepCopyInto(&out.Sampling)
+	in.Redaction.DeepCopyInto(&out.Redaction)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoggingConfig.
+func (in *LoggingConfig) DeepCopy() *LoggingConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(LoggingConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MetricsServerConfig) DeepCopyInto(out *MetricsServerConfig) {
 	*out = *in
@@ -58,6 +216,23 @@ func (in *MetricsServerConfig) DeepCopy() *MetricsServerConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ShardingConfig) DeepCopyInto(out *ShardingConfig) {
+	*out = *in
+	out.LeaseDuration = in.LeaseDuration
+	out.RenewInterval = in.RenewInterval
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ShardingConfig.
+func (in *ShardingConfig) DeepCopy() *ShardingConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ShardingConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TLSConfig) DeepCopyInto(out *TLSConfig) {
 	*out = *in
@@ -77,3 +252,18 @@ func (in *TLSConfig) DeepCopy() *TLSConfig {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TracingConfig) DeepCopyInto(out *TracingConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TracingConfig.
+func (in *TracingConfig) DeepCopy() *TracingConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(TracingConfig)
+	in.DeepCopyInto(out)
+	return out
+}
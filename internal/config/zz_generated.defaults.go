@@ -20,4 +20,28 @@ func RegisterDefaults(scheme *runtime.Scheme) error {
 func SetObjectDefaults_DatumControllerManager(in *DatumControllerManager) {
 	SetDefaults_MetricsServerConfig(&in.MetricsServer)
 	SetDefaults_TLSConfig(&in.MetricsServer.TLS)
+	SetDefaults_HealthProbesConfig(&in.HealthProbes)
+	SetDefaults_LoggingConfig(&in.Logging)
+	SetDefaults_LogSamplingConfig(&in.Logging.Sampling)
+	SetDefaults_LogRedactionConfig(&in.Logging.Redaction)
+	SetDefaults_TracingConfig(&in.Tracing)
+	SetDefaults_ShardingConfig(&in.Sharding)
+	SetDefaults_ControllersConfig(&in.Controllers)
+	SetDefaults_ClientConfig(&in.Client)
+	SetDefaults_ClientConfig(&in.ImpersonatedClient)
+	SetDefaults_OrganizationQuotaUsageControllerConfig(&in.OrganizationQuotaUsageController)
+	SetDefaults_OrganizationBootstrapControllerConfig(&in.OrganizationBootstrapController)
+	SetDefaults_DefaultProjectConfig(&in.OrganizationBootstrapController.DefaultProject)
+	SetDefaults_OrganizationCustomRoleControllerConfig(&in.OrganizationCustomRoleController)
+	SetDefaults_OrganizationInvitationControllerConfig(&in.OrganizationInvitationController)
+	SetDefaults_UserOffboardingControllerConfig(&in.UserOffboardingController)
+	SetDefaults_OrganizationTransferControllerConfig(&in.OrganizationTransferController)
+	for i := range in.UserOnboardingController.Rules {
+		a := &in.UserOnboardingController.Rules[i]
+		SetDefaults_OnboardingRule(a)
+	}
+	SetDefaults_UserWaitlistControllerConfig(&in.UserWaitlistController)
+	SetDefaults_PersonalWorkspaceAuditorConfig(&in.PersonalWorkspaceAuditor)
+	SetDefaults_LastOwnerProtectionWebhookConfig(&in.LastOwnerProtectionWebhook)
+	SetDefaults_RoleCatalogControllerConfig(&in.RoleCatalogController)
 }
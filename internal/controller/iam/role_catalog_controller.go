@@ -0,0 +1,156 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package iam
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	iamv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
+
+	"go.datum.net/datum/internal/roles"
+	"go.datum.net/datum/internal/tracing"
+)
+
+// RoleCatalogValidCondition is the condition set on assignable roles to report
+// whether their inheritance and taxonomy annotations are valid.
+const RoleCatalogValidCondition = "CatalogValid"
+
+// +kubebuilder:object:generate=true
+
+type RoleCatalogControllerConfig struct {
+	// Namespace is the namespace containing the assignable organization roles.
+	// Defaults to datum-cloud.
+	Namespace string `json:"namespace"`
+
+	// ReadOnlyRoles are the names of assignable roles that must only grant
+	// read access. Inheriting a role that grants any other verb is reported as
+	// a permission escalation. Defaults to viewer.
+	ReadOnlyRoles []string `json:"readOnlyRoles,omitempty"`
+}
+
+// RoleCatalogController validates the assignable organization roles. It
+// resolves the inheritance graph of each role in the catalog namespace and
+// reports missing inherited roles, inheritance cycles, permission escalation
+// and invalid taxonomy annotations as a status condition and Events.
+type RoleCatalogController struct {
+	Client client.Client
+
+	Config RoleCatalogControllerConfig
+
+	Recorder record.EventRecorder
+
+	// Options configures the workers and rate limiting of the controller.
+	Options controller.Options
+}
+
+// +kubebuilder:rbac:groups=iam.miloapis.com,resources=roles,verbs=get;list;watch
+// +kubebuilder:rbac:groups=iam.miloapis.com,resources=roles/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
+// Reconcile validates a role of the catalog.
+func (r *RoleCatalogController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := logf.FromContext(ctx)
+
+	role := &iamv1alpha1.Role{}
+	if err := r.Client.Get(ctx, req.NamespacedName, role); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, fmt.Errorf("failed to get role: %w", err)
+	}
+	if !role.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	// Inherited roles may live in any namespace, so the graph is built from
+	// all roles.
+	allRoles := &iamv1alpha1.RoleList{}
+	if err := r.Client.List(ctx, allRoles); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to list roles: %w", err)
+	}
+	var catalog []iamv1alpha1.Role
+	for _, item := range allRoles.Items {
+		if item.Namespace == r.Config.Namespace && item.DeletionTimestamp.IsZero() {
+			catalog = append(catalog, item)
+		}
+	}
+
+	key := roles.KeyOf(role)
+	problems := roles.NewGraph(allRoles.Items).Validate(key, slices.Contains(r.Config.ReadOnlyRoles, role.Name))
+	problems = append(problems, roles.ValidateTaxonomy(catalog)[key]...)
+
+	condition := metav1.Condition{
+		Type:               RoleCatalogValidCondition,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Valid",
+		Message:            "Role inheritance and taxonomy annotations are valid",
+		ObservedGeneration: role.Generation,
+	}
+	if len(problems) > 0 {
+		messages := make([]string, len(problems))
+		for i, p := range problems {
+			messages[i] = p.String()
+		}
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = string(problems[0].Type)
+		condition.Message = strings.Join(messages, "; ")
+	}
+
+	if !meta.SetStatusCondition(&role.Status.Conditions, condition) {
+		return ctrl.Result{}, nil
+	}
+	if err := r.Client.Status().Update(ctx, role); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to update role status: %w", err)
+	}
+
+	if len(problems) == 0 {
+		logger.Info("Role catalog entry is valid", "role", key.String())
+		r.Recorder.Event(role, corev1.EventTypeNormal, condition.Reason, condition.Message)
+		return ctrl.Result{}, nil
+	}
+	logger.Info("Role catalog entry is invalid", "role", key.String(), "problems", len(problems))
+	for _, p := range problems {
+		r.Recorder.Event(role, corev1.EventTypeWarning, string(p.Type), p.Message)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *RoleCatalogController) SetupWithManager(mgr ctrl.Manager) error {
+	// A change to any role can affect the inheritance of every catalog role,
+	// so every change enqueues the whole catalog.
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("role-catalog").
+		Watches(&iamv1alpha1.Role{}, handler.EnqueueRequestsFromMapFunc(r.catalogRoles)).
+		WithOptions(r.Options).
+		Complete(tracing.Reconciler("role-catalog", r))
+}
+
+func (r *RoleCatalogController) catalogRoles(ctx context.Context, _ client.Object) []reconcile.Request {
+	catalog := &iamv1alpha1.RoleList{}
+	if err := r.Client.List(ctx, catalog, client.InNamespace(r.Config.Namespace)); err != nil {
+		logf.FromContext(ctx).Error(err, "failed to list catalog roles")
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(catalog.Items))
+	for _, role := range catalog.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&role)})
+	}
+	return requests
+}
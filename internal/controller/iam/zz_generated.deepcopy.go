@@ -0,0 +1,29 @@
+//go:build !ignore_autogenerated
+
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Code generated by controller-gen. DO NOT EDIT.
+
+package iam
+
+import ()
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RoleCatalogControllerConfig) DeepCopyInto(out *RoleCatalogControllerConfig) {
+	*out = *in
+	if in.ReadOnlyRoles != nil {
+		in, out := &in.ReadOnlyRoles, &out.ReadOnlyRoles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RoleCatalogControllerConfig.
+func (in *RoleCatalogControllerConfig) DeepCopy() *RoleCatalogControllerConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(RoleCatalogControllerConfig)
+	in.DeepCopyInto(out)
+	return out
+}
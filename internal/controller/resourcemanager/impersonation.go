@@ -0,0 +1,40 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package resourcemanager
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	iamv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
+)
+
+// +kubebuilder:rbac:groups=core,resources=users;groups;uids,verbs=impersonate
+// +kubebuilder:rbac:groups=authentication.k8s.io,resources=userextras/*,verbs=impersonate
+
+// newOrganizationUserClient returns a client that impersonates the user within
+// the context of the organization.
+//
+// The project webhook requires parent context in UserInfo.Extra fields, and
+// also looks up the requesting user by UID to create a PolicyBinding granting
+// them ownership. Impersonating the actual user lets the webhook see the
+// correct identity and create the right PolicyBinding.
+//
+// The client keeps the transport wrappers of restConfig, so its requests are
+// traced and carry the trace context of the reconcile.
+func newOrganizationUserClient(restConfig *rest.Config, scheme *runtime.Scheme, user *iamv1alpha1.User, organization string) (client.Client, error) {
+	impersonatedConfig := rest.CopyConfig(restConfig)
+	impersonatedConfig.Impersonate = rest.ImpersonationConfig{
+		UserName: user.Spec.Email,
+		UID:      user.Name,
+		Groups:   []string{"system:authenticated"},
+		Extra: map[string][]string{
+			"iam.miloapis.com/parent-name":      {organization},
+			"iam.miloapis.com/parent-type":      {"Organization"},
+			"iam.miloapis.com/parent-api-group": {"resourcemanager.miloapis.com"},
+		},
+	}
+
+	return client.New(impersonatedConfig, client.Options{Scheme: scheme})
+}
@@ -0,0 +1,451 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package resourcemanager
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	iamv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
+	resourcemanagerv1alpha1 "go.miloapis.com/milo/pkg/apis/resourcemanager/v1alpha1"
+
+	"go.datum.net/datum/internal/tracing"
+)
+
+const (
+	// OrganizationBootstrappedAnnotation is set on an Organization once its
+	// starter resources have been provisioned. Organizations with this
+	// annotation are not bootstrapped again, so resources removed by the
+	// organization's members are not recreated.
+	OrganizationBootstrappedAnnotation = "resourcemanager.datumapis.com/bootstrapped"
+
+	// OrganizationBootstrapOwnerAnnotation is set on an Organization whose
+	// bootstrap waits for its owner to be approved, to the name of the owner.
+	// Organizations waiting for their owner are bootstrapped regardless of
+	// their age.
+	OrganizationBootstrapOwnerAnnotation = "resourcemanager.datumapis.com/bootstrap-owner"
+
+	bootstrapOwnerIndex = "metadata.annotations.bootstrap-owner"
+)
+
+// +kubebuilder:object:generate=true
+
+type OrganizationBootstrapControllerConfig struct {
+	// OwnerRoles are the roles assigned to the owner of a new organization.
+	// The owner of a standard organization is the member already holding one
+	// of these roles, such as its creator. Existing memberships of the owner
+	// are extended with any missing roles. Defaults to the owner role of the
+	// assignable organization roles.
+	OwnerRoles []resourcemanagerv1alpha1.RoleReference `json:"ownerRoles,omitempty"`
+
+	// StarterGroups are the groups created in new organizations, each bound
+	// to a role on the organization, so owners can grant the role by adding
+	// members to the group. Defaults to an editors group bound to the editor
+	// role and a viewers group bound to the viewer role of the assignable
+	// organization roles.
+	StarterGroups []StarterGroupConfig `json:"starterGroups,omitempty"`
+
+	// DefaultProject configures the project created in new organizations.
+	DefaultProject DefaultProjectConfig `json:"defaultProject"`
+
+	// Annotations are added to new organizations once they are bootstrapped,
+	// for example to show a welcome message in the portal.
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// MaxOrganizationAge limits bootstrapping to organizations created within
+	// the given duration, so enabling the controller does not provision
+	// resources for existing organizations. Organizations already waiting for
+	// their owner are not limited. Defaults to 1h.
+	MaxOrganizationAge metav1.Duration `json:"maxOrganizationAge"`
+}
+
+// +kubebuilder:object:generate=true
+
+type StarterGroupConfig struct {
+	// Name is the name of the group in the namespace of the organization.
+	Name string `json:"name"`
+
+	// Role is the role granted to the members of the group on the
+	// organization.
+	Role resourcemanagerv1alpha1.RoleReference `json:"role"`
+}
+
+// +kubebuilder:object:generate=true
+
+type DefaultProjectConfig struct {
+	// Enabled controls whether a default project is created. Defaults to true.
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// DisplayName is the display name of the default project. Defaults to
+	// "Default Project".
+	DisplayName string `json:"displayName"`
+}
+
+// OrganizationBootstrapController provisions the starter resources of newly
+// created organizations: owner membership roles, starter groups bound to
+// roles, a default project and welcome annotations.
+//
+// Organizations whose owner has no membership yet are bootstrapped once the
+// membership is created within the bootstrap window, and organizations whose
+// owner is not approved yet once the owner is approved.
+type OrganizationBootstrapController struct {
+	Client client.Client
+
+	Config OrganizationBootstrapControllerConfig
+
+	// SkipPersonalOrganizations leaves Personal organizations to the
+	// PersonalOrganizationController. It is set when UnifiedOrganizations is
+	// disabled.
+	SkipPersonalOrganizations bool
+
+	Scheme *runtime.Scheme
+
+	// RestConfig is used to create an impersonated client for project creation.
+	RestConfig *rest.Config
+
+	// Options configures the workers and rate limiting of the controller.
+	Options controller.Options
+}
+
+// +kubebuilder:rbac:groups=resourcemanager.miloapis.com,resources=organizations,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=resourcemanager.miloapis.com,resources=organizationmemberships,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups=resourcemanager.miloapis.com,resources=projects,verbs=get;list;watch;create
+// +kubebuilder:rbac:groups=iam.miloapis.com,resources=users,verbs=get;list;watch
+// +kubebuilder:rbac:groups=iam.miloapis.com,resources=groups,verbs=get;list;watch;create
+// +kubebuilder:rbac:groups=iam.miloapis.com,resources=policybindings,verbs=get;list;watch;create
+
+// Reconcile bootstraps a newly created organization.
+func (r *OrganizationBootstrapController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := logf.FromContext(ctx)
+
+	organization := &resourcemanagerv1alpha1.Organization{}
+	if err := r.Client.Get(ctx, req.NamespacedName, organization); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, fmt.Errorf("failed to get organization: %w", err)
+	}
+
+	if !organization.DeletionTimestamp.IsZero() || metav1.HasAnnotation(organization.ObjectMeta, OrganizationBootstrappedAnnotation) {
+		return ctrl.Result{}, nil
+	}
+	if r.SkipPersonalOrganizations && organization.Spec.Type == "Personal" {
+		return ctrl.Result{}, nil
+	}
+	waiting := organization.Annotations[OrganizationBootstrapOwnerAnnotation] != ""
+	if age := time.Since(organization.CreationTimestamp.Time); !waiting && age > r.Config.MaxOrganizationAge.Duration {
+		logger.Info("Organization was created before the bootstrap window, skipping", "organization", organization.Name, "age", age)
+		return ctrl.Result{}, nil
+	}
+
+	owner, err := r.findOwner(ctx, organization)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if owner == nil {
+		// The owner membership of a standard organization is created by Milo
+		// after the organization, which enqueues the organization again.
+		logger.Info("Organization owner not found yet, waiting", "organization", organization.Name)
+		return ctrl.Result{}, nil
+	}
+
+	if err := r.ensureOwnerMembership(ctx, organization, owner); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	for _, starter := range r.Config.StarterGroups {
+		if err := r.ensureStarterGroup(ctx, organization, starter); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if r.Config.DefaultProject.Enabled == nil || *r.Config.DefaultProject.Enabled {
+		// The impersonated client will not have the correct permissions until
+		// the owner's registration is approved.
+		if owner.Status.RegistrationApproval != iamv1alpha1.RegistrationApprovalStateApproved {
+			logger.Info("Organization owner is not approved, waiting to create default project", "organization", organization.Name, "user", owner.Name)
+			return ctrl.Result{}, r.waitForOwner(ctx, organization, owner.Name)
+		}
+		if err := r.ensureDefaultProject(ctx, organization, owner); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	patch := client.MergeFrom(organization.DeepCopy())
+	for key, value := range r.Config.Annotations {
+		metav1.SetMetaDataAnnotation(&organization.ObjectMeta, key, value)
+	}
+	metav1.SetMetaDataAnnotation(&organization.ObjectMeta, OrganizationBootstrappedAnnotation, "true")
+	delete(organization.Annotations, OrganizationBootstrapOwnerAnnotation)
+	if err := r.Client.Patch(ctx, organization, patch); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to mark organization as bootstrapped: %w", err)
+	}
+
+	logger.Info("Successfully bootstrapped organization", "organization", organization.Name, "owner", owner.Name)
+
+	return ctrl.Result{}, nil
+}
+
+// waitForOwner marks the organization as waiting for its owner, so it is
+// bootstrapped once the owner is approved regardless of its age.
+func (r *OrganizationBootstrapController) waitForOwner(ctx context.Context, organization *resourcemanagerv1alpha1.Organization, owner string) error {
+	if current, ok := organization.Annotations[OrganizationBootstrapOwnerAnnotation]; ok && current == owner {
+		return nil
+	}
+	patch := client.MergeFrom(organization.DeepCopy())
+	metav1.SetMetaDataAnnotation(&organization.ObjectMeta, OrganizationBootstrapOwnerAnnotation, owner)
+	if err := r.Client.Patch(ctx, organization, patch); err != nil {
+		return fmt.Errorf("failed to mark organization as waiting for its owner: %w", err)
+	}
+	return nil
+}
+
+// findOwner returns the user owning the organization: the User controlling a
+// personal organization, or the member of the oldest membership holding one of
+// the owner roles otherwise. Nil is returned when no owner exists yet.
+func (r *OrganizationBootstrapController) findOwner(ctx context.Context, organization *resourcemanagerv1alpha1.Organization) (*iamv1alpha1.User, error) {
+	var ownerName string
+	if ref := metav1.GetControllerOf(organization); ref != nil && ref.Kind == "User" {
+		ownerName = ref.Name
+	} else {
+		memberships := &resourcemanagerv1alpha1.OrganizationMembershipList{}
+		if err := r.Client.List(ctx, memberships, client.InNamespace(organizationNamespace(organization.Name))); err != nil {
+			return nil, fmt.Errorf("failed to list organization memberships: %w", err)
+		}
+		owners := slices.DeleteFunc(memberships.Items, func(m resourcemanagerv1alpha1.OrganizationMembership) bool {
+			return !slices.ContainsFunc(m.Spec.Roles, func(role resourcemanagerv1alpha1.RoleReference) bool {
+				return slices.Contains(r.Config.OwnerRoles, role)
+			})
+		})
+		if oldest := oldestMembership(owners); oldest != nil {
+			ownerName = oldest.Spec.UserRef.Name
+		}
+	}
+	if ownerName == "" {
+		return nil, nil
+	}
+
+	user := &iamv1alpha1.User{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Name: ownerName}, user); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get organization owner: %w", err)
+	}
+	return user, nil
+}
+
+// ensureOwnerMembership makes sure the owner is a member of the organization
+// with all configured owner roles.
+func (r *OrganizationBootstrapController) ensureOwnerMembership(ctx context.Context, organization *resourcemanagerv1alpha1.Organization, owner *iamv1alpha1.User) error {
+	memberships := &resourcemanagerv1alpha1.OrganizationMembershipList{}
+	if err := r.Client.List(ctx, memberships, client.InNamespace(organizationNamespace(organization.Name))); err != nil {
+		return fmt.Errorf("failed to list organization memberships: %w", err)
+	}
+
+	for i := range memberships.Items {
+		membership := &memberships.Items[i]
+		if membership.Spec.UserRef.Name != owner.Name {
+			continue
+		}
+		roles := mergeRoles(membership.Spec.Roles, r.Config.OwnerRoles)
+		if len(roles) == len(membership.Spec.Roles) {
+			return nil
+		}
+		membership.Spec.Roles = roles
+		if err := r.Client.Update(ctx, membership); err != nil {
+			return fmt.Errorf("failed to update owner membership: %w", err)
+		}
+		return nil
+	}
+
+	membership := &resourcemanagerv1alpha1.OrganizationMembership{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("membership-%s", owner.Name),
+			Namespace: organizationNamespace(organization.Name),
+		},
+		Spec: resourcemanagerv1alpha1.OrganizationMembershipSpec{
+			OrganizationRef: resourcemanagerv1alpha1.OrganizationReference{
+				Name: organization.Name,
+			},
+			UserRef: resourcemanagerv1alpha1.MemberReference{
+				Name: owner.Name,
+			},
+			Roles: slices.Clone(r.Config.OwnerRoles),
+		},
+	}
+	if err := r.Client.Create(ctx, membership); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create owner membership: %w", err)
+	}
+	return nil
+}
+
+// ensureStarterGroup creates the starter group in the organization and binds
+// its role to the group on the organization.
+func (r *OrganizationBootstrapController) ensureStarterGroup(ctx context.Context, organization *resourcemanagerv1alpha1.Organization, starter StarterGroupConfig) error {
+	group := &iamv1alpha1.Group{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      starter.Name,
+			Namespace: organizationNamespace(organization.Name),
+		},
+	}
+	if err := r.Client.Create(ctx, group); apierrors.IsAlreadyExists(err) {
+		if err := r.Client.Get(ctx, client.ObjectKeyFromObject(group), group); err != nil {
+			return fmt.Errorf("failed to get starter group: %w", err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("failed to create starter group: %w", err)
+	}
+
+	binding := &iamv1alpha1.PolicyBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%s", group.Name, starter.Role.Name),
+			Namespace: group.Namespace,
+		},
+		Spec: iamv1alpha1.PolicyBindingSpec{
+			RoleRef: iamv1alpha1.RoleReference{
+				Name:      starter.Role.Name,
+				Namespace: starter.Role.Namespace,
+			},
+			Subjects: []iamv1alpha1.Subject{
+				{
+					Kind:      "Group",
+					Name:      group.Name,
+					Namespace: group.Namespace,
+					UID:       string(group.UID),
+				},
+			},
+			ResourceSelector: iamv1alpha1.ResourceSelector{
+				ResourceRef: &iamv1alpha1.ResourceReference{
+					APIGroup: "resourcemanager.miloapis.com",
+					Kind:     "Organization",
+					Name:     organization.Name,
+					UID:      string(organization.UID),
+				},
+			},
+		},
+	}
+	if err := r.Client.Create(ctx, binding); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create starter group policy binding: %w", err)
+	}
+	return nil
+}
+
+// ensureDefaultProject creates the default project of the organization on
+// behalf of the owner.
+func (r *OrganizationBootstrapController) ensureDefaultProject(ctx context.Context, organization *resourcemanagerv1alpha1.Organization, owner *iamv1alpha1.User) error {
+	logger := logf.FromContext(ctx)
+
+	project := &resourcemanagerv1alpha1.Project{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: fmt.Sprintf("default-project-%s", hashPersonalOrgName(string(organization.UID))),
+		},
+	}
+
+	// The impersonated user only has org-scoped permissions and cannot GET
+	// projects at the cluster scope, so use the controller's own client.
+	err := r.Client.Get(ctx, client.ObjectKeyFromObject(project), &resourcemanagerv1alpha1.Project{})
+	if err == nil {
+		return nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to check for existing default project: %w", err)
+	}
+
+	impersonatedClient, err := newOrganizationUserClient(r.RestConfig, r.Scheme, owner, organization.Name)
+	if err != nil {
+		return fmt.Errorf("failed to create impersonated client: %w", err)
+	}
+
+	logger.Info("Creating default project", "organization", organization.Name, "project", project.Name)
+	metav1.SetMetaDataAnnotation(&project.ObjectMeta, "kubernetes.io/display-name", r.Config.DefaultProject.DisplayName)
+	if err := impersonatedClient.Create(ctx, project); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create default project: %w", err)
+	}
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *OrganizationBootstrapController) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &resourcemanagerv1alpha1.Organization{}, bootstrapOwnerIndex, bootstrapOwner); err != nil {
+		return err
+	}
+
+	// Organizations waiting for their owner are enqueued when a membership is
+	// created in them, and when their owner changes, such as when approved.
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&resourcemanagerv1alpha1.Organization{}).
+		Watches(&resourcemanagerv1alpha1.OrganizationMembership{}, handler.EnqueueRequestsFromMapFunc(membershipOrganization)).
+		Watches(&iamv1alpha1.User{}, handler.EnqueueRequestsFromMapFunc(r.ownedOrganizations)).
+		Named("organization-bootstrap").
+		WithOptions(r.Options).
+		Complete(tracing.Reconciler("organization-bootstrap", r))
+}
+
+// ownedOrganizations returns the organizations waiting for the user to be
+// approved.
+func (r *OrganizationBootstrapController) ownedOrganizations(ctx context.Context, obj client.Object) []reconcile.Request {
+	organizations := &resourcemanagerv1alpha1.OrganizationList{}
+	if err := r.Client.List(ctx, organizations, client.MatchingFields{bootstrapOwnerIndex: obj.GetName()}); err != nil {
+		logf.FromContext(ctx).Error(err, "failed to list organizations waiting for their owner")
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(organizations.Items))
+	for _, organization := range organizations.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&organization)})
+	}
+	return requests
+}
+
+func bootstrapOwner(obj client.Object) []string {
+	if owner := obj.GetAnnotations()[OrganizationBootstrapOwnerAnnotation]; owner != "" {
+		return []string{owner}
+	}
+	return nil
+}
+
+func organizationNamespace(organization string) string {
+	return fmt.Sprintf("organization-%s", organization)
+}
+
+// oldestMembership returns the membership created first, or nil when there are
+// no memberships.
+func oldestMembership(memberships []resourcemanagerv1alpha1.OrganizationMembership) *resourcemanagerv1alpha1.OrganizationMembership {
+	var oldest *resourcemanagerv1alpha1.OrganizationMembership
+	for i := range memberships {
+		m := &memberships[i]
+		if !m.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if oldest == nil || m.CreationTimestamp.Before(&oldest.CreationTimestamp) ||
+			(m.CreationTimestamp.Equal(&oldest.CreationTimestamp) && m.Name < oldest.Name) {
+			oldest = m
+		}
+	}
+	return oldest
+}
+
+// mergeRoles returns roles with any role of additional it does not contain
+// appended.
+func mergeRoles(roles, additional []resourcemanagerv1alpha1.RoleReference) []resourcemanagerv1alpha1.RoleReference {
+	merged := slices.Clone(roles)
+	for _, role := range additional {
+		if !slices.Contains(merged, role) {
+			merged = append(merged, role)
+		}
+	}
+	return merged
+}
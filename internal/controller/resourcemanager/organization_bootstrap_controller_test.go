@@ -0,0 +1,200 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package resourcemanager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	iamv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
+	resourcemanagerv1alpha1 "go.miloapis.com/milo/pkg/apis/resourcemanager/v1alpha1"
+)
+
+func TestOrganizationBootstrapController(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iamv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := resourcemanagerv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	ownerRole := resourcemanagerv1alpha1.RoleReference{Name: "owner", Namespace: "datum-cloud"}
+	adminRole := resourcemanagerv1alpha1.RoleReference{Name: "admin", Namespace: "datum-cloud"}
+	viewerRole := resourcemanagerv1alpha1.RoleReference{Name: "viewer", Namespace: "datum-cloud"}
+	config := OrganizationBootstrapControllerConfig{
+		OwnerRoles:         []resourcemanagerv1alpha1.RoleReference{ownerRole, adminRole},
+		StarterGroups:      []StarterGroupConfig{{Name: "viewers", Role: viewerRole}},
+		DefaultProject:     DefaultProjectConfig{Enabled: ptr.To(false)},
+		Annotations:        map[string]string{"datumapis.com/welcome": "true"},
+		MaxOrganizationAge: metav1.Duration{Duration: time.Hour},
+	}
+
+	newOrganization := func(orgType string) *resourcemanagerv1alpha1.Organization {
+		return &resourcemanagerv1alpha1.Organization{
+			ObjectMeta: metav1.ObjectMeta{Name: "acme", CreationTimestamp: metav1.Now()},
+			Spec:       resourcemanagerv1alpha1.OrganizationSpec{Type: orgType},
+		}
+	}
+	user := &iamv1alpha1.User{ObjectMeta: metav1.ObjectMeta{Name: "user-1"}}
+	creatorMembership := &resourcemanagerv1alpha1.OrganizationMembership{
+		ObjectMeta: metav1.ObjectMeta{Name: "creator", Namespace: "organization-acme"},
+		Spec: resourcemanagerv1alpha1.OrganizationMembershipSpec{
+			OrganizationRef: resourcemanagerv1alpha1.OrganizationReference{Name: "acme"},
+			UserRef:         resourcemanagerv1alpha1.MemberReference{Name: "user-1"},
+			Roles:           []resourcemanagerv1alpha1.RoleReference{ownerRole},
+		},
+	}
+
+	t.Run("adds owner roles, starter groups and marks organization bootstrapped", func(t *testing.T) {
+		c := fake.NewClientBuilder().WithScheme(scheme).
+			WithObjects(newOrganization("Standard"), user, creatorMembership.DeepCopy()).
+			Build()
+		r := &OrganizationBootstrapController{Client: c, Config: config, Scheme: scheme}
+
+		if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKey{Name: "acme"}}); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+
+		membership := &resourcemanagerv1alpha1.OrganizationMembership{}
+		if err := c.Get(context.Background(), client.ObjectKeyFromObject(creatorMembership), membership); err != nil {
+			t.Fatal(err)
+		}
+		if len(membership.Spec.Roles) != 2 || membership.Spec.Roles[1] != adminRole {
+			t.Errorf("membership roles = %v, want %v added", membership.Spec.Roles, adminRole)
+		}
+
+		if err := c.Get(context.Background(), client.ObjectKey{Namespace: "organization-acme", Name: "viewers"}, &iamv1alpha1.Group{}); err != nil {
+			t.Errorf("expected starter group to be created: %v", err)
+		}
+		binding := &iamv1alpha1.PolicyBinding{}
+		if err := c.Get(context.Background(), client.ObjectKey{Namespace: "organization-acme", Name: "viewers-viewer"}, binding); err != nil {
+			t.Fatalf("expected starter group policy binding to be created: %v", err)
+		}
+		if binding.Spec.RoleRef.Name != "viewer" || len(binding.Spec.Subjects) != 1 || binding.Spec.Subjects[0].Name != "viewers" ||
+			binding.Spec.ResourceSelector.ResourceRef == nil || binding.Spec.ResourceSelector.ResourceRef.Name != "acme" {
+			t.Errorf("unexpected starter group policy binding %+v", binding.Spec)
+		}
+
+		organization := &resourcemanagerv1alpha1.Organization{}
+		if err := c.Get(context.Background(), client.ObjectKey{Name: "acme"}, organization); err != nil {
+			t.Fatal(err)
+		}
+		if organization.Annotations[OrganizationBootstrappedAnnotation] != "true" || organization.Annotations["datumapis.com/welcome"] != "true" {
+			t.Errorf("organization annotations = %v", organization.Annotations)
+		}
+	})
+
+	t.Run("skips personal organizations in legacy mode", func(t *testing.T) {
+		c := fake.NewClientBuilder().WithScheme(scheme).
+			WithObjects(newOrganization("Personal"), user, creatorMembership.DeepCopy()).
+			Build()
+		r := &OrganizationBootstrapController{Client: c, Config: config, Scheme: scheme, SkipPersonalOrganizations: true}
+
+		if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKey{Name: "acme"}}); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+
+		organization := &resourcemanagerv1alpha1.Organization{}
+		if err := c.Get(context.Background(), client.ObjectKey{Name: "acme"}, organization); err != nil {
+			t.Fatal(err)
+		}
+		if metav1.HasAnnotation(organization.ObjectMeta, OrganizationBootstrappedAnnotation) {
+			t.Error("personal organization was bootstrapped")
+		}
+	})
+
+	t.Run("waits for a member holding an owner role", func(t *testing.T) {
+		member := creatorMembership.DeepCopy()
+		member.Spec.Roles = []resourcemanagerv1alpha1.RoleReference{viewerRole}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(newOrganization("Standard"), user, member).Build()
+		r := &OrganizationBootstrapController{Client: c, Config: config, Scheme: scheme}
+
+		if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKey{Name: "acme"}}); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+
+		organization := &resourcemanagerv1alpha1.Organization{}
+		if err := c.Get(context.Background(), client.ObjectKey{Name: "acme"}, organization); err != nil {
+			t.Fatal(err)
+		}
+		if len(organization.Annotations) != 0 {
+			t.Errorf("expected organization without owner to be left alone, got annotations %v", organization.Annotations)
+		}
+		if err := c.Get(context.Background(), client.ObjectKeyFromObject(member), member); err != nil {
+			t.Fatal(err)
+		}
+		if len(member.Spec.Roles) != 1 {
+			t.Errorf("expected member without owner role not to be promoted, got roles %v", member.Spec.Roles)
+		}
+		if got := membershipOrganization(context.Background(), creatorMembership); len(got) != 1 || got[0].Name != "acme" {
+			t.Errorf("expected a membership to enqueue its organization, got %v", got)
+		}
+	})
+
+	t.Run("waits for the owner to be approved", func(t *testing.T) {
+		c := fake.NewClientBuilder().WithScheme(scheme).
+			WithObjects(newOrganization("Standard"), user, creatorMembership.DeepCopy()).
+			WithIndex(&resourcemanagerv1alpha1.Organization{}, bootstrapOwnerIndex, bootstrapOwner).
+			Build()
+		withProject := config
+		withProject.DefaultProject = DefaultProjectConfig{Enabled: ptr.To(true)}
+		r := &OrganizationBootstrapController{Client: c, Config: withProject, Scheme: scheme}
+
+		if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKey{Name: "acme"}}); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+
+		organization := &resourcemanagerv1alpha1.Organization{}
+		if err := c.Get(context.Background(), client.ObjectKey{Name: "acme"}, organization); err != nil {
+			t.Fatal(err)
+		}
+		if organization.Annotations[OrganizationBootstrapOwnerAnnotation] != "user-1" || metav1.HasAnnotation(organization.ObjectMeta, OrganizationBootstrappedAnnotation) {
+			t.Errorf("expected organization to wait for user-1, got annotations %v", organization.Annotations)
+		}
+		// Approving the owner enqueues the organization.
+		if got := r.ownedOrganizations(context.Background(), user); len(got) != 1 || got[0].Name != "acme" {
+			t.Errorf("expected the owner to enqueue the organization, got %v", got)
+		}
+	})
+
+	t.Run("bootstraps organizations waiting for their owner regardless of age", func(t *testing.T) {
+		organization := newOrganization("Standard")
+		organization.CreationTimestamp = metav1.NewTime(time.Now().Add(-2 * time.Hour))
+		organization.Annotations = map[string]string{OrganizationBootstrapOwnerAnnotation: "user-1"}
+		old := newOrganization("Standard")
+		old.Name = "old"
+		old.CreationTimestamp = organization.CreationTimestamp
+		c := fake.NewClientBuilder().WithScheme(scheme).
+			WithObjects(organization, old, user, creatorMembership.DeepCopy()).
+			Build()
+		r := &OrganizationBootstrapController{Client: c, Config: config, Scheme: scheme}
+
+		for _, name := range []string{"acme", "old"} {
+			if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKey{Name: name}}); err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+		}
+
+		if err := c.Get(context.Background(), client.ObjectKey{Name: "acme"}, organization); err != nil {
+			t.Fatal(err)
+		}
+		if !metav1.HasAnnotation(organization.ObjectMeta, OrganizationBootstrappedAnnotation) || metav1.HasAnnotation(organization.ObjectMeta, OrganizationBootstrapOwnerAnnotation) {
+			t.Errorf("expected waiting organization to be bootstrapped, got annotations %v", organization.Annotations)
+		}
+		if err := c.Get(context.Background(), client.ObjectKey{Name: "old"}, old); err != nil {
+			t.Fatal(err)
+		}
+		if metav1.HasAnnotation(old.ObjectMeta, OrganizationBootstrappedAnnotation) {
+			t.Error("expected organization created before the bootstrap window to be skipped")
+		}
+	})
+}
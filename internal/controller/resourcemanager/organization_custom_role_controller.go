@@ -0,0 +1,331 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package resourcemanager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	iamv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
+	resourcemanagerv1alpha1 "go.miloapis.com/milo/pkg/apis/resourcemanager/v1alpha1"
+
+	"go.datum.net/datum/internal/tracing"
+)
+
+const (
+	// CustomRolesAnnotation holds the custom roles an organization defines, as
+	// a JSON list of CustomRole.
+	CustomRolesAnnotation = "iam.datumapis.com/custom-roles"
+
+	// CustomRoleAssignmentsAnnotation holds the custom roles assigned to the
+	// members of an organization, as a JSON object mapping user names to
+	// custom role names. It is set on the Organization, like the definitions,
+	// so assigning a custom role requires the same permission as defining it.
+	CustomRoleAssignmentsAnnotation = "iam.datumapis.com/custom-role-assignments"
+
+	// CustomRoleLabel marks the Roles materialized from custom role
+	// definitions.
+	CustomRoleLabel = "iam.datumapis.com/custom-role"
+)
+
+// CustomRole is a role defined by an organization.
+type CustomRole struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName,omitempty"`
+	Description string `json:"description,omitempty"`
+
+	// InheritedRoles are the roles the custom role grants. Each must be in the
+	// allowlist of the controller.
+	InheritedRoles []iamv1alpha1.ScopedRoleReference `json:"inheritedRoles"`
+}
+
+// +kubebuilder:object:generate=true
+
+type OrganizationCustomRoleControllerConfig struct {
+	// AllowedRoles are the roles custom roles may inherit. Defaults to the
+	// viewer and editor assignable organization roles and the viewer roles of
+	// every service in the service catalog, so custom roles never grant more
+	// than the editor role.
+	AllowedRoles []iamv1alpha1.ScopedRoleReference `json:"allowedRoles,omitempty"`
+
+	// MaxRolesPerOrganization limits the number of custom roles an
+	// organization can define. Defaults to 20.
+	MaxRolesPerOrganization int `json:"maxRolesPerOrganization"`
+
+	// LaunchStage is the launch stage set on custom roles. Defaults to Beta.
+	LaunchStage string `json:"launchStage"`
+}
+
+// OrganizationCustomRoleController materializes the custom roles defined on an
+// Organization as Roles in the organization's namespace, and assigns them to
+// the members listed in the organization's CustomRoleAssignmentsAnnotation.
+// Memberships are updated with the controller's own permissions, so
+// assignments are only read from the Organization and never from the
+// memberships, which members may be able to update.
+//
+// Custom roles may only inherit allowlisted roles. Definitions that are not
+// valid are reported as Events on the Organization and are not materialized.
+// Roles materialized from a definition that was removed or is no longer valid
+// are deleted and unassigned.
+type OrganizationCustomRoleController struct {
+	Client client.Client
+
+	Config OrganizationCustomRoleControllerConfig
+
+	Scheme *runtime.Scheme
+
+	Recorder record.EventRecorder
+
+	// Options configures the workers and rate limiting of the controller.
+	Options controller.Options
+}
+
+// +kubebuilder:rbac:groups=resourcemanager.miloapis.com,resources=organizations,verbs=get;list;watch
+// +kubebuilder:rbac:groups=resourcemanager.miloapis.com,resources=organizationmemberships,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=iam.miloapis.com,resources=roles,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
+// Reconcile materializes the custom roles of an organization.
+func (r *OrganizationCustomRoleController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := logf.FromContext(ctx)
+
+	organization := &resourcemanagerv1alpha1.Organization{}
+	if err := r.Client.Get(ctx, req.NamespacedName, organization); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, fmt.Errorf("failed to get organization: %w", err)
+	}
+	if !organization.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	var definitions []CustomRole
+	if value, ok := organization.Annotations[CustomRolesAnnotation]; ok {
+		if err := json.Unmarshal([]byte(value), &definitions); err != nil {
+			// Leave existing roles in place until the annotation is fixed.
+			r.Recorder.Eventf(organization, corev1.EventTypeWarning, "InvalidCustomRoles",
+				"Failed to parse %s annotation: %v", CustomRolesAnnotation, err)
+			return ctrl.Result{}, nil
+		}
+	}
+
+	valid, problems := r.validate(definitions)
+	for _, problem := range problems {
+		r.Recorder.Event(organization, corev1.EventTypeWarning, "InvalidCustomRole", problem)
+	}
+
+	namespace := organizationNamespace(organization.Name)
+	var assignments map[string][]string
+	assigned := func(membership *resourcemanagerv1alpha1.OrganizationMembership) []string {
+		return assignments[membership.Spec.UserRef.Name]
+	}
+	if value, ok := organization.Annotations[CustomRoleAssignmentsAnnotation]; ok {
+		if err := json.Unmarshal([]byte(value), &assignments); err != nil {
+			r.Recorder.Eventf(organization, corev1.EventTypeWarning, "InvalidCustomRoleAssignments",
+				"Failed to parse %s annotation: %v", CustomRoleAssignmentsAnnotation, err)
+			// Keep the current assignments until the annotation is fixed.
+			assigned = func(membership *resourcemanagerv1alpha1.OrganizationMembership) []string {
+				var names []string
+				for _, ref := range membership.Spec.Roles {
+					if ref.Namespace == namespace {
+						names = append(names, ref.Name)
+					}
+				}
+				return names
+			}
+		}
+	}
+
+	for _, definition := range valid {
+		if err := r.ensureRole(ctx, organization, definition); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	existing := &iamv1alpha1.RoleList{}
+	if err := r.Client.List(ctx, existing, client.InNamespace(namespace), client.MatchingLabels{CustomRoleLabel: "true"}); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to list custom roles: %w", err)
+	}
+	customRoles := sets.New[string]()
+	for i := range existing.Items {
+		role := &existing.Items[i]
+		customRoles.Insert(role.Name)
+		if slices.ContainsFunc(valid, func(d CustomRole) bool { return d.Name == role.Name }) {
+			continue
+		}
+		logger.Info("Deleting custom role", "organization", organization.Name, "role", role.Name)
+		if err := r.Client.Delete(ctx, role); client.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to delete custom role %s: %w", role.Name, err)
+		}
+	}
+
+	for _, definition := range valid {
+		customRoles.Insert(definition.Name)
+	}
+	if err := r.assignRoles(ctx, organization, valid, customRoles, assigned); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// validate returns the valid custom role definitions, and a message for each
+// definition that is not valid.
+func (r *OrganizationCustomRoleController) validate(definitions []CustomRole) ([]CustomRole, []string) {
+	var (
+		valid    []CustomRole
+		problems []string
+		names    = map[string]bool{}
+	)
+	for _, definition := range definitions {
+		if errs := validation.IsDNS1123Subdomain(definition.Name); len(errs) > 0 {
+			problems = append(problems, fmt.Sprintf("custom role name %q is invalid: %s", definition.Name, strings.Join(errs, ", ")))
+			continue
+		}
+		if names[definition.Name] {
+			problems = append(problems, fmt.Sprintf("custom role %q is defined more than once", definition.Name))
+			continue
+		}
+		names[definition.Name] = true
+
+		if len(definition.InheritedRoles) == 0 {
+			problems = append(problems, fmt.Sprintf("custom role %q does not inherit any roles", definition.Name))
+			continue
+		}
+		var disallowed []string
+		for _, ref := range definition.InheritedRoles {
+			if !slices.Contains(r.Config.AllowedRoles, ref) {
+				disallowed = append(disallowed, ref.Namespace+"/"+ref.Name)
+			}
+		}
+		if len(disallowed) > 0 {
+			problems = append(problems, fmt.Sprintf("custom role %q inherits roles that are not allowed: %s",
+				definition.Name, strings.Join(disallowed, ", ")))
+			continue
+		}
+		// Only valid definitions count against the limit.
+		if len(valid) >= r.Config.MaxRolesPerOrganization {
+			problems = append(problems, fmt.Sprintf("custom role %q exceeds the limit of %d custom roles per organization",
+				definition.Name, r.Config.MaxRolesPerOrganization))
+			continue
+		}
+		valid = append(valid, definition)
+	}
+	return valid, problems
+}
+
+// ensureRole creates or updates the Role materialized from a custom role
+// definition. Roles in the organization namespace that were not created by this
+// controller are left untouched.
+func (r *OrganizationCustomRoleController) ensureRole(ctx context.Context, organization *resourcemanagerv1alpha1.Organization, definition CustomRole) error {
+	role := &iamv1alpha1.Role{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      definition.Name,
+			Namespace: organizationNamespace(organization.Name),
+		},
+	}
+
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, role, func() error {
+		if !role.CreationTimestamp.IsZero() && role.Labels[CustomRoleLabel] != "true" {
+			return fmt.Errorf("role %s already exists and is not a custom role", definition.Name)
+		}
+		metav1.SetMetaDataLabel(&role.ObjectMeta, CustomRoleLabel, "true")
+		metav1.SetMetaDataAnnotation(&role.ObjectMeta, "kubernetes.io/display-name", definition.DisplayName)
+		metav1.SetMetaDataAnnotation(&role.ObjectMeta, "kubernetes.io/description", definition.Description)
+		if err := controllerutil.SetControllerReference(organization, role, r.Scheme); err != nil {
+			return fmt.Errorf("failed to set controller reference: %w", err)
+		}
+		role.Spec.LaunchStage = r.Config.LaunchStage
+		role.Spec.InheritedRoles = slices.Clone(definition.InheritedRoles)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create or update custom role %s: %w", definition.Name, err)
+	}
+	return nil
+}
+
+// assignRoles sets the custom roles of every membership of the organization to
+// the valid roles assigned to it. Only the custom roles of the organization,
+// named in customRoles, are changed: other roles, including the roles of the
+// organization namespace that were not created by this controller, are left
+// untouched.
+func (r *OrganizationCustomRoleController) assignRoles(
+	ctx context.Context,
+	organization *resourcemanagerv1alpha1.Organization,
+	valid []CustomRole,
+	customRoles sets.Set[string],
+	assigned func(*resourcemanagerv1alpha1.OrganizationMembership) []string,
+) error {
+	namespace := organizationNamespace(organization.Name)
+
+	memberships := &resourcemanagerv1alpha1.OrganizationMembershipList{}
+	if err := r.Client.List(ctx, memberships, client.InNamespace(namespace)); err != nil {
+		return fmt.Errorf("failed to list organization memberships: %w", err)
+	}
+
+	for i := range memberships.Items {
+		membership := &memberships.Items[i]
+		if !membership.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		roles := slices.DeleteFunc(slices.Clone(membership.Spec.Roles), func(ref resourcemanagerv1alpha1.RoleReference) bool {
+			return ref.Namespace == namespace && customRoles.Has(ref.Name)
+		})
+		var assignedRoles []resourcemanagerv1alpha1.RoleReference
+		for _, name := range assigned(membership) {
+			if slices.ContainsFunc(valid, func(d CustomRole) bool { return d.Name == name }) {
+				assignedRoles = append(assignedRoles, resourcemanagerv1alpha1.RoleReference{Name: name, Namespace: namespace})
+			}
+		}
+		roles = mergeRoles(roles, assignedRoles)
+		if slices.Equal(roles, membership.Spec.Roles) {
+			continue
+		}
+
+		membership.Spec.Roles = roles
+		if err := r.Client.Update(ctx, membership); err != nil {
+			return fmt.Errorf("failed to update organization membership %s: %w", membership.Name, err)
+		}
+	}
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *OrganizationCustomRoleController) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&resourcemanagerv1alpha1.Organization{}).
+		Owns(&iamv1alpha1.Role{}).
+		Watches(&resourcemanagerv1alpha1.OrganizationMembership{}, handler.EnqueueRequestsFromMapFunc(membershipOrganization)).
+		Named("organization-custom-role").
+		WithOptions(r.Options).
+		Complete(tracing.Reconciler("organization-custom-role", r))
+}
+
+func membershipOrganization(_ context.Context, obj client.Object) []reconcile.Request {
+	membership, ok := obj.(*resourcemanagerv1alpha1.OrganizationMembership)
+	if !ok || membership.Spec.OrganizationRef.Name == "" {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: client.ObjectKey{Name: membership.Spec.OrganizationRef.Name}}}
+}
@@ -0,0 +1,143 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package resourcemanager
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	iamv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
+	resourcemanagerv1alpha1 "go.miloapis.com/milo/pkg/apis/resourcemanager/v1alpha1"
+)
+
+func TestOrganizationCustomRoleController(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iamv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := resourcemanagerv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	config := OrganizationCustomRoleControllerConfig{
+		AllowedRoles: []iamv1alpha1.ScopedRoleReference{
+			{Name: "projects-viewer", Namespace: "milo-system"},
+			{Name: "dns-admin", Namespace: "milo-system"},
+		},
+		MaxRolesPerOrganization: 1,
+		LaunchStage:             "Beta",
+	}
+
+	ownerRole := resourcemanagerv1alpha1.RoleReference{Name: "owner", Namespace: "datum-cloud"}
+	// Roles of the organization namespace that are not custom roles are left
+	// untouched.
+	unmanagedRole := resourcemanagerv1alpha1.RoleReference{Name: "unmanaged", Namespace: "organization-acme"}
+	organization := &resourcemanagerv1alpha1.Organization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "acme",
+			UID:  "acme-uid",
+			Annotations: map[string]string{
+				CustomRolesAnnotation: `[
+					{"name": "escalated", "inheritedRoles": [{"name": "iam-organization-admin", "namespace": "milo-system"}]},
+					{"name": "dns-operator", "displayName": "DNS Operator", "inheritedRoles": [
+						{"name": "projects-viewer", "namespace": "milo-system"},
+						{"name": "dns-admin", "namespace": "milo-system"}
+					]},
+					{"name": "dns-viewer", "inheritedRoles": [{"name": "projects-viewer", "namespace": "milo-system"}]}
+				]`,
+				CustomRoleAssignmentsAnnotation: `{"user-1": ["dns-operator", "escalated"]}`,
+			},
+		},
+	}
+	membership := &resourcemanagerv1alpha1.OrganizationMembership{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "member",
+			Namespace: "organization-acme",
+			// Assignments listed on memberships are ignored.
+			Annotations: map[string]string{"iam.datumapis.com/custom-roles": "dns-viewer"},
+		},
+		Spec: resourcemanagerv1alpha1.OrganizationMembershipSpec{
+			OrganizationRef: resourcemanagerv1alpha1.OrganizationReference{Name: "acme"},
+			UserRef:         resourcemanagerv1alpha1.MemberReference{Name: "user-1"},
+			Roles:           []resourcemanagerv1alpha1.RoleReference{ownerRole, unmanagedRole},
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(organization, membership).Build()
+	recorder := record.NewFakeRecorder(10)
+	r := &OrganizationCustomRoleController{Client: c, Config: config, Scheme: scheme, Recorder: recorder}
+	reconcile := func() {
+		t.Helper()
+		if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKey{Name: "acme"}}); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+	}
+	getMembership := func() *resourcemanagerv1alpha1.OrganizationMembership {
+		t.Helper()
+		m := &resourcemanagerv1alpha1.OrganizationMembership{}
+		if err := c.Get(context.Background(), client.ObjectKeyFromObject(membership), m); err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+
+	reconcile()
+
+	role := &iamv1alpha1.Role{}
+	if err := c.Get(context.Background(), client.ObjectKey{Namespace: "organization-acme", Name: "dns-operator"}, role); err != nil {
+		t.Fatalf("failed to get custom role: %v", err)
+	}
+	if role.Labels[CustomRoleLabel] != "true" || len(role.Spec.InheritedRoles) != 2 || role.Spec.LaunchStage != "Beta" {
+		t.Errorf("unexpected custom role %+v", role)
+	}
+	if ref := metav1.GetControllerOf(role); ref == nil || ref.Kind != "Organization" || ref.Name != "acme" {
+		t.Errorf("expected custom role to be controlled by its organization, got %v", role.OwnerReferences)
+	}
+	err := c.Get(context.Background(), client.ObjectKey{Namespace: "organization-acme", Name: "escalated"}, &iamv1alpha1.Role{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected role inheriting a role outside the allowlist not to be created, got %v", err)
+	}
+	// The invalid definition does not count against the limit, the role
+	// defined after the first valid one does.
+	err = c.Get(context.Background(), client.ObjectKey{Namespace: "organization-acme", Name: "dns-viewer"}, &iamv1alpha1.Role{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected role exceeding the limit not to be created, got %v", err)
+	}
+	if len(recorder.Events) != 2 {
+		t.Errorf("expected 2 events for the invalid roles, got %d", len(recorder.Events))
+	}
+
+	want := []resourcemanagerv1alpha1.RoleReference{ownerRole, unmanagedRole, {Name: "dns-operator", Namespace: "organization-acme"}}
+	if got := getMembership().Spec.Roles; !slices.Equal(got, want) {
+		t.Errorf("membership roles = %v, want %v", got, want)
+	}
+
+	// Removing the definition deletes the role and unassigns it.
+	current := &resourcemanagerv1alpha1.Organization{}
+	if err := c.Get(context.Background(), client.ObjectKey{Name: "acme"}, current); err != nil {
+		t.Fatal(err)
+	}
+	current.Annotations[CustomRolesAnnotation] = "[]"
+	if err := c.Update(context.Background(), current); err != nil {
+		t.Fatal(err)
+	}
+	reconcile()
+
+	err = c.Get(context.Background(), client.ObjectKey{Namespace: "organization-acme", Name: "dns-operator"}, &iamv1alpha1.Role{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected removed custom role to be deleted, got %v", err)
+	}
+	want = []resourcemanagerv1alpha1.RoleReference{ownerRole, unmanagedRole}
+	if got := getMembership().Spec.Roles; !slices.Equal(got, want) {
+		t.Errorf("membership roles = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,358 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package resourcemanager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	iamv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
+	resourcemanagerv1alpha1 "go.miloapis.com/milo/pkg/apis/resourcemanager/v1alpha1"
+
+	"go.datum.net/datum/internal/tracing"
+)
+
+// InvitationsAnnotation holds the pending invitations of an organization, as a
+// JSON list of Invitation.
+const InvitationsAnnotation = "iam.datumapis.com/invitations"
+
+const (
+	userEmailIndex         = "spec.email"
+	invitationEmailIndex   = "metadata.annotations.invitations"
+	invitationRequeueLimit = time.Hour
+)
+
+// Invitation invites the user with the given email to an organization.
+type Invitation struct {
+	Email string `json:"email"`
+
+	// Roles are assigned to the user when the invitation is accepted. Defaults
+	// to the default roles of the controller. Invitations listing a role that
+	// is neither allowed by the controller nor a custom role of the
+	// organization are rejected.
+	Roles []resourcemanagerv1alpha1.RoleReference `json:"roles,omitempty"`
+
+	// CreatedAt is set by the controller when it first sees the invitation, and
+	// is used to expire it.
+	CreatedAt *metav1.Time `json:"createdAt,omitempty"`
+}
+
+// +kubebuilder:object:generate=true
+
+type OrganizationInvitationControllerConfig struct {
+	// TTL is how long an invitation stays valid. Defaults to 7 days.
+	TTL metav1.Duration `json:"ttl"`
+
+	// DefaultRoles are assigned for invitations that do not list any roles.
+	// Defaults to the datum-cloud viewer role.
+	DefaultRoles []resourcemanagerv1alpha1.RoleReference `json:"defaultRoles,omitempty"`
+
+	// AllowedRoles are the roles invitations may assign, in addition to the
+	// custom roles of the organization. Defaults to the assignable
+	// organization roles.
+	AllowedRoles []resourcemanagerv1alpha1.RoleReference `json:"allowedRoles,omitempty"`
+}
+
+// OrganizationInvitationController grants invited users access to an
+// organization. Invitations are listed on the Organization, and are accepted
+// once a User with a matching email has signed up and been approved, by
+// creating or extending their OrganizationMembership. Invitations that are not
+// accepted within the configured TTL expire.
+//
+// Memberships are written with the controller's own permissions, so
+// invitations may only assign the allowed roles and the custom roles of the
+// organization. Other invitations are rejected and reported as Events.
+type OrganizationInvitationController struct {
+	Client client.Client
+
+	Config OrganizationInvitationControllerConfig
+
+	Recorder record.EventRecorder
+
+	// Options configures the workers and rate limiting of the controller.
+	Options controller.Options
+}
+
+// +kubebuilder:rbac:groups=resourcemanager.miloapis.com,resources=organizations,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=resourcemanager.miloapis.com,resources=organizationmemberships,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups=iam.miloapis.com,resources=users,verbs=get;list;watch
+// +kubebuilder:rbac:groups=iam.miloapis.com,resources=roles,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
+// Reconcile accepts and expires the invitations of an organization.
+func (r *OrganizationInvitationController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := logf.FromContext(ctx)
+
+	organization := &resourcemanagerv1alpha1.Organization{}
+	if err := r.Client.Get(ctx, req.NamespacedName, organization); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, fmt.Errorf("failed to get organization: %w", err)
+	}
+	if !organization.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	invitations, err := parseInvitations(organization)
+	if err != nil {
+		r.Recorder.Eventf(organization, corev1.EventTypeWarning, "InvalidInvitations",
+			"Failed to parse %s annotation: %v", InvitationsAnnotation, err)
+		return ctrl.Result{}, nil
+	}
+	if len(invitations) == 0 {
+		return ctrl.Result{}, nil
+	}
+
+	now := metav1.Now()
+	changed := false
+	var pending []Invitation
+	for _, invitation := range invitations {
+		if invitation.CreatedAt == nil {
+			invitation.CreatedAt = &now
+			changed = true
+		}
+		if now.After(invitation.CreatedAt.Add(r.Config.TTL.Duration)) {
+			logger.Info("Invitation expired", "organization", organization.Name, "email", invitation.Email)
+			r.Recorder.Eventf(organization, corev1.EventTypeNormal, "InvitationExpired",
+				"Invitation for %s expired", invitation.Email)
+			changed = true
+			continue
+		}
+
+		disallowed, err := r.disallowedRoles(ctx, organization, invitation.Roles)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if len(disallowed) > 0 {
+			logger.Info("Invitation rejected", "organization", organization.Name, "email", invitation.Email, "roles", disallowed)
+			r.Recorder.Eventf(organization, corev1.EventTypeWarning, "InvitationRejected",
+				"Invitation for %s was rejected, it assigns roles that are not allowed: %s", invitation.Email, strings.Join(disallowed, ", "))
+			changed = true
+			continue
+		}
+
+		user, err := r.findApprovedUser(ctx, invitation.Email)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if user == nil {
+			pending = append(pending, invitation)
+			continue
+		}
+
+		roles := invitation.Roles
+		if len(roles) == 0 {
+			roles = r.Config.DefaultRoles
+		}
+		if err := ensureMembership(ctx, r.Client, organization.Name, user, roles); err != nil {
+			return ctrl.Result{}, err
+		}
+		logger.Info("Invitation accepted", "organization", organization.Name, "email", invitation.Email, "user", user.Name)
+		r.Recorder.Eventf(organization, corev1.EventTypeNormal, "InvitationAccepted",
+			"Invitation for %s was accepted by user %s", invitation.Email, user.Name)
+		changed = true
+	}
+
+	if changed {
+		patch := client.MergeFromWithOptions(organization.DeepCopy(), client.MergeFromWithOptimisticLock{})
+		if len(pending) == 0 {
+			delete(organization.Annotations, InvitationsAnnotation)
+		} else {
+			value, err := json.Marshal(pending)
+			if err != nil {
+				return ctrl.Result{}, fmt.Errorf("failed to encode invitations: %w", err)
+			}
+			metav1.SetMetaDataAnnotation(&organization.ObjectMeta, InvitationsAnnotation, string(value))
+		}
+		if err := r.Client.Patch(ctx, organization, patch); err != nil {
+			if apierrors.IsConflict(err) {
+				// The invitations changed since they were read, so process
+				// them again instead of overwriting the new ones.
+				return ctrl.Result{Requeue: true}, nil
+			}
+			return ctrl.Result{}, fmt.Errorf("failed to update invitations: %w", err)
+		}
+	}
+
+	if len(pending) == 0 {
+		return ctrl.Result{}, nil
+	}
+
+	// Requeue to expire the next invitation. Users signing up are picked up
+	// by the User watch.
+	requeueAfter := invitationRequeueLimit
+	for _, invitation := range pending {
+		requeueAfter = min(requeueAfter, time.Until(invitation.CreatedAt.Add(r.Config.TTL.Duration)))
+	}
+	return ctrl.Result{RequeueAfter: max(requeueAfter, time.Second)}, nil
+}
+
+// disallowedRoles returns the roles that are neither allowed by the
+// controller nor custom roles of the organization.
+func (r *OrganizationInvitationController) disallowedRoles(ctx context.Context, organization *resourcemanagerv1alpha1.Organization, roles []resourcemanagerv1alpha1.RoleReference) ([]string, error) {
+	var disallowed []string
+	for _, ref := range roles {
+		if slices.Contains(r.Config.AllowedRoles, ref) {
+			continue
+		}
+		if ref.Namespace == organizationNamespace(organization.Name) {
+			role := &iamv1alpha1.Role{}
+			err := r.Client.Get(ctx, client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}, role)
+			if client.IgnoreNotFound(err) != nil {
+				return nil, fmt.Errorf("failed to get role %s: %w", ref.Name, err)
+			}
+			if err == nil && role.Labels[CustomRoleLabel] == "true" {
+				continue
+			}
+		}
+		disallowed = append(disallowed, ref.Namespace+"/"+ref.Name)
+	}
+	return disallowed, nil
+}
+
+// findApprovedUser returns the approved User with the given email, or nil when
+// no such user exists.
+func (r *OrganizationInvitationController) findApprovedUser(ctx context.Context, email string) (*iamv1alpha1.User, error) {
+	users := &iamv1alpha1.UserList{}
+	if err := r.Client.List(ctx, users, client.MatchingFields{userEmailIndex: strings.ToLower(email)}); err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	for i := range users.Items {
+		user := &users.Items[i]
+		if user.DeletionTimestamp.IsZero() && user.Status.RegistrationApproval == iamv1alpha1.RegistrationApprovalStateApproved {
+			return user, nil
+		}
+	}
+	return nil, nil
+}
+
+// ensureMembership makes sure the user is a member of the organization with
+// the given roles. Roles of an existing membership are extended, never
+// removed.
+func ensureMembership(ctx context.Context, c client.Client, organization string, user *iamv1alpha1.User, roles []resourcemanagerv1alpha1.RoleReference) error {
+	memberships := &resourcemanagerv1alpha1.OrganizationMembershipList{}
+	if err := c.List(ctx, memberships, client.InNamespace(organizationNamespace(organization))); err != nil {
+		return fmt.Errorf("failed to list organization memberships: %w", err)
+	}
+	for i := range memberships.Items {
+		membership := &memberships.Items[i]
+		if membership.Spec.UserRef.Name != user.Name {
+			continue
+		}
+		merged := mergeRoles(membership.Spec.Roles, roles)
+		if len(merged) == len(membership.Spec.Roles) {
+			return nil
+		}
+		membership.Spec.Roles = merged
+		if err := c.Update(ctx, membership); err != nil {
+			return fmt.Errorf("failed to update organization membership: %w", err)
+		}
+		return nil
+	}
+
+	membership := &resourcemanagerv1alpha1.OrganizationMembership{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("membership-%s", user.Name),
+			Namespace: organizationNamespace(organization),
+		},
+		Spec: resourcemanagerv1alpha1.OrganizationMembershipSpec{
+			OrganizationRef: resourcemanagerv1alpha1.OrganizationReference{
+				Name: organization,
+			},
+			UserRef: resourcemanagerv1alpha1.MemberReference{
+				Name: user.Name,
+			},
+			Roles: slices.Clone(roles),
+		},
+	}
+	if err := c.Create(ctx, membership); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create organization membership: %w", err)
+	}
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *OrganizationInvitationController) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &iamv1alpha1.User{}, userEmailIndex, userEmail); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &resourcemanagerv1alpha1.Organization{}, invitationEmailIndex, invitationEmails); err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&resourcemanagerv1alpha1.Organization{}).
+		Watches(&iamv1alpha1.User{}, handler.EnqueueRequestsFromMapFunc(r.invitingOrganizations)).
+		Named("organization-invitation").
+		WithOptions(r.Options).
+		Complete(tracing.Reconciler("organization-invitation", r))
+}
+
+// invitingOrganizations maps a User to the organizations that invited their
+// email.
+func (r *OrganizationInvitationController) invitingOrganizations(ctx context.Context, obj client.Object) []reconcile.Request {
+	user, ok := obj.(*iamv1alpha1.User)
+	if !ok || user.Spec.Email == "" {
+		return nil
+	}
+	organizations := &resourcemanagerv1alpha1.OrganizationList{}
+	if err := r.Client.List(ctx, organizations, client.MatchingFields{invitationEmailIndex: strings.ToLower(user.Spec.Email)}); err != nil {
+		logf.FromContext(ctx).Error(err, "failed to list inviting organizations")
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(organizations.Items))
+	for _, organization := range organizations.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&organization)})
+	}
+	return requests
+}
+
+func parseInvitations(organization *resourcemanagerv1alpha1.Organization) ([]Invitation, error) {
+	value, ok := organization.Annotations[InvitationsAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var invitations []Invitation
+	if err := json.Unmarshal([]byte(value), &invitations); err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+func userEmail(obj client.Object) []string {
+	user, ok := obj.(*iamv1alpha1.User)
+	if !ok || user.Spec.Email == "" {
+		return nil
+	}
+	return []string{strings.ToLower(user.Spec.Email)}
+}
+
+func invitationEmails(obj client.Object) []string {
+	organization, ok := obj.(*resourcemanagerv1alpha1.Organization)
+	if !ok {
+		return nil
+	}
+	// Invalid annotations are reported when the organization is reconciled.
+	invitations, _ := parseInvitations(organization)
+	emails := make([]string, 0, len(invitations))
+	for _, invitation := range invitations {
+		emails = append(emails, strings.ToLower(invitation.Email))
+	}
+	return emails
+}
@@ -0,0 +1,255 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package resourcemanager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	iamv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
+	resourcemanagerv1alpha1 "go.miloapis.com/milo/pkg/apis/resourcemanager/v1alpha1"
+)
+
+func TestOrganizationInvitationController(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iamv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := resourcemanagerv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	viewerRole := resourcemanagerv1alpha1.RoleReference{Name: "viewer", Namespace: "datum-cloud"}
+	editorRole := resourcemanagerv1alpha1.RoleReference{Name: "editor", Namespace: "datum-cloud"}
+	config := OrganizationInvitationControllerConfig{
+		TTL:          metav1.Duration{Duration: 24 * time.Hour},
+		DefaultRoles: []resourcemanagerv1alpha1.RoleReference{viewerRole},
+		AllowedRoles: []resourcemanagerv1alpha1.RoleReference{viewerRole, editorRole},
+	}
+
+	expired := metav1.NewTime(time.Now().Add(-48 * time.Hour))
+	invitations, err := json.Marshal([]Invitation{
+		{Email: "Approved@example.com", Roles: []resourcemanagerv1alpha1.RoleReference{editorRole}},
+		{Email: "pending@example.com"},
+		{Email: "expired@example.com", CreatedAt: &expired},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	organization := &resourcemanagerv1alpha1.Organization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "acme",
+			Annotations: map[string]string{InvitationsAnnotation: string(invitations)},
+		},
+	}
+	approved := &iamv1alpha1.User{
+		ObjectMeta: metav1.ObjectMeta{Name: "user-1"},
+		Spec:       iamv1alpha1.UserSpec{Email: "approved@example.com"},
+		Status:     iamv1alpha1.UserStatus{RegistrationApproval: iamv1alpha1.RegistrationApprovalStateApproved},
+	}
+	pending := &iamv1alpha1.User{
+		ObjectMeta: metav1.ObjectMeta{Name: "user-2"},
+		Spec:       iamv1alpha1.UserSpec{Email: "pending@example.com"},
+		Status:     iamv1alpha1.UserStatus{RegistrationApproval: iamv1alpha1.RegistrationApprovalStatePending},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(organization, approved, pending).
+		WithIndex(&iamv1alpha1.User{}, userEmailIndex, userEmail).
+		Build()
+	r := &OrganizationInvitationController{Client: c, Config: config, Recorder: record.NewFakeRecorder(10)}
+
+	result, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKey{Name: "acme"}})
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if result.RequeueAfter <= 0 || result.RequeueAfter > invitationRequeueLimit {
+		t.Errorf("RequeueAfter = %v, want a requeue for the pending invitation", result.RequeueAfter)
+	}
+
+	membership := &resourcemanagerv1alpha1.OrganizationMembership{}
+	if err := c.Get(context.Background(), client.ObjectKey{Namespace: "organization-acme", Name: "membership-user-1"}, membership); err != nil {
+		t.Fatalf("failed to get membership of invited user: %v", err)
+	}
+	if len(membership.Spec.Roles) != 1 || membership.Spec.Roles[0] != editorRole {
+		t.Errorf("membership roles = %v, want [%v]", membership.Spec.Roles, editorRole)
+	}
+
+	updated := &resourcemanagerv1alpha1.Organization{}
+	if err := c.Get(context.Background(), client.ObjectKey{Name: "acme"}, updated); err != nil {
+		t.Fatal(err)
+	}
+	remaining, err := parseInvitations(updated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(remaining) != 1 || remaining[0].Email != "pending@example.com" || remaining[0].CreatedAt == nil {
+		t.Errorf("remaining invitations = %+v, want only the pending invitation with a creation time", remaining)
+	}
+}
+
+func TestOrganizationInvitationControllerDisallowedRoles(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iamv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := resourcemanagerv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	viewerRole := resourcemanagerv1alpha1.RoleReference{Name: "viewer", Namespace: "datum-cloud"}
+	adminRole := resourcemanagerv1alpha1.RoleReference{Name: "iam-admin", Namespace: "milo-system"}
+	customRole := resourcemanagerv1alpha1.RoleReference{Name: "dns-operator", Namespace: "organization-acme"}
+	plainRole := resourcemanagerv1alpha1.RoleReference{Name: "unmanaged", Namespace: "organization-acme"}
+
+	invitations, err := json.Marshal([]Invitation{
+		{Email: "admin@example.com", Roles: []resourcemanagerv1alpha1.RoleReference{viewerRole, adminRole}},
+		{Email: "plain@example.com", Roles: []resourcemanagerv1alpha1.RoleReference{plainRole}},
+		{Email: "custom@example.com", Roles: []resourcemanagerv1alpha1.RoleReference{customRole}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	organization := &resourcemanagerv1alpha1.Organization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "acme",
+			Annotations: map[string]string{InvitationsAnnotation: string(invitations)},
+		},
+	}
+	objects := []client.Object{
+		organization,
+		&iamv1alpha1.Role{ObjectMeta: metav1.ObjectMeta{
+			Name: customRole.Name, Namespace: customRole.Namespace, Labels: map[string]string{CustomRoleLabel: "true"},
+		}},
+		&iamv1alpha1.Role{ObjectMeta: metav1.ObjectMeta{Name: plainRole.Name, Namespace: plainRole.Namespace}},
+	}
+	for i, email := range []string{"admin@example.com", "plain@example.com", "custom@example.com"} {
+		objects = append(objects, &iamv1alpha1.User{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("user-%d", i)},
+			Spec:       iamv1alpha1.UserSpec{Email: email},
+			Status:     iamv1alpha1.UserStatus{RegistrationApproval: iamv1alpha1.RegistrationApprovalStateApproved},
+		})
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(objects...).
+		WithIndex(&iamv1alpha1.User{}, userEmailIndex, userEmail).
+		Build()
+	recorder := record.NewFakeRecorder(10)
+	r := &OrganizationInvitationController{
+		Client: c,
+		Config: OrganizationInvitationControllerConfig{
+			TTL:          metav1.Duration{Duration: 24 * time.Hour},
+			DefaultRoles: []resourcemanagerv1alpha1.RoleReference{viewerRole},
+			AllowedRoles: []resourcemanagerv1alpha1.RoleReference{viewerRole},
+		},
+		Recorder: recorder,
+	}
+
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKey{Name: "acme"}}); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+
+	memberships := &resourcemanagerv1alpha1.OrganizationMembershipList{}
+	if err := c.List(context.Background(), memberships); err != nil {
+		t.Fatal(err)
+	}
+	if len(memberships.Items) != 1 || memberships.Items[0].Spec.UserRef.Name != "user-2" {
+		t.Fatalf("expected only the invitation with a custom role to be accepted, got %+v", memberships.Items)
+	}
+
+	updated := &resourcemanagerv1alpha1.Organization{}
+	if err := c.Get(context.Background(), client.ObjectKey{Name: "acme"}, updated); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := updated.Annotations[InvitationsAnnotation]; ok {
+		t.Errorf("expected rejected invitations to be removed, got %s", updated.Annotations[InvitationsAnnotation])
+	}
+
+	rejected := 0
+	for len(recorder.Events) > 0 {
+		if strings.Contains(<-recorder.Events, "InvitationRejected") {
+			rejected++
+		}
+	}
+	if rejected != 2 {
+		t.Errorf("expected 2 rejected invitations, got %d", rejected)
+	}
+}
+
+func TestOrganizationInvitationControllerConflict(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iamv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := resourcemanagerv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	invitations, err := json.Marshal([]Invitation{{Email: "new@example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	organization := &resourcemanagerv1alpha1.Organization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "acme",
+			Annotations: map[string]string{InvitationsAnnotation: string(invitations)},
+		},
+	}
+
+	// Invitations added after the organization is read must not be
+	// overwritten.
+	added, err := json.Marshal([]Invitation{{Email: "new@example.com"}, {Email: "other@example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(organization).
+		WithIndex(&iamv1alpha1.User{}, userEmailIndex, userEmail).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				if err := c.Get(ctx, key, obj, opts...); err != nil {
+					return err
+				}
+				if _, ok := obj.(*resourcemanagerv1alpha1.Organization); ok {
+					concurrent := obj.DeepCopyObject().(*resourcemanagerv1alpha1.Organization)
+					concurrent.Annotations[InvitationsAnnotation] = string(added)
+					return c.Update(ctx, concurrent)
+				}
+				return nil
+			},
+		}).
+		Build()
+	r := &OrganizationInvitationController{
+		Client: c,
+		Config: OrganizationInvitationControllerConfig{TTL: metav1.Duration{Duration: 24 * time.Hour}},
+	}
+
+	result, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKey{Name: "acme"}})
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if !result.Requeue {
+		t.Error("expected a requeue after a conflicting update")
+	}
+
+	updated := &resourcemanagerv1alpha1.Organization{}
+	if err := c.Get(context.Background(), client.ObjectKey{Name: "acme"}, updated); err != nil {
+		t.Fatal(err)
+	}
+	if updated.Annotations[InvitationsAnnotation] != string(added) {
+		t.Errorf("expected concurrently added invitations to be kept, got %s", updated.Annotations[InvitationsAnnotation])
+	}
+}
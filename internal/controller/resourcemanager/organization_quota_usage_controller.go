@@ -0,0 +1,280 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package resourcemanager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	resourcemanagerv1alpha1 "go.miloapis.com/milo/pkg/apis/resourcemanager/v1alpha1"
+
+	"go.datum.net/datum/internal/tracing"
+)
+
+// QuotaUsageAnnotation is the annotation on an Organization holding a JSON
+// encoded list of QuotaUsage entries, one per resource type granted to the
+// organization.
+const QuotaUsageAnnotation = "quota.datumapis.com/usage"
+
+var (
+	resourceGrantGVK = schema.GroupVersionKind{Group: "quota.miloapis.com", Version: "v1alpha1", Kind: "ResourceGrant"}
+	resourceClaimGVK = schema.GroupVersionKind{Group: "quota.miloapis.com", Version: "v1alpha1", Kind: "ResourceClaim"}
+)
+
+type OrganizationQuotaUsageControllerConfig struct {
+	// WarningThresholdPercent is the percentage of an allowance that must be
+	// used before a warning Event is emitted for the organization. An Event is
+	// always emitted once an allowance is exhausted. Defaults to 80.
+	WarningThresholdPercent int64 `json:"warningThresholdPercent"`
+}
+
+// QuotaUsage summarizes how much of a resource type an organization uses.
+type QuotaUsage struct {
+	ResourceType string `json:"resourceType"`
+
+	// Allowed is the total amount granted to the organization.
+	Allowed int64 `json:"allowed"`
+
+	// Used is the total amount claimed by the organization.
+	Used int64 `json:"used"`
+}
+
+// percent returns the percentage of the allowance that is used. Any usage of
+// a resource type without an allowance is reported as 100 percent.
+func (u QuotaUsage) percent() int64 {
+	if u.Allowed <= 0 {
+		if u.Used > 0 {
+			return 100
+		}
+		return 0
+	}
+	return u.Used * 100 / u.Allowed
+}
+
+// OrganizationQuotaUsageController aggregates the ResourceGrants and
+// ResourceClaims of an organization into a usage summary on the Organization.
+type OrganizationQuotaUsageController struct {
+	Client client.Client
+
+	Config OrganizationQuotaUsageControllerConfig
+
+	Recorder record.EventRecorder
+
+	// Options configures the workers and rate limiting of the controller.
+	Options controller.Options
+}
+
+// +kubebuilder:rbac:groups=resourcemanager.miloapis.com,resources=organizations,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=quota.miloapis.com,resources=resourcegrants;resourceclaims,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
+// Reconcile recalculates the quota usage of an organization from the
+// ResourceGrants and ResourceClaims in its namespace that reference the
+// organization as their consumer, records the summary in the
+// QuotaUsageAnnotation and emits an Event when a threshold is crossed.
+func (r *OrganizationQuotaUsageController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := logf.FromContext(ctx)
+
+	organization := &resourcemanagerv1alpha1.Organization{}
+	if err := r.Client.Get(ctx, req.NamespacedName, organization); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, fmt.Errorf("failed to get organization: %w", err)
+	}
+
+	if !organization.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	namespace := organizationNamespace(organization.Name)
+
+	grants := &unstructured.UnstructuredList{}
+	grants.SetGroupVersionKind(resourceGrantGVK.GroupVersion().WithKind(resourceGrantGVK.Kind + "List"))
+	if err := r.Client.List(ctx, grants, client.InNamespace(namespace)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to list resource grants: %w", err)
+	}
+
+	claims := &unstructured.UnstructuredList{}
+	claims.SetGroupVersionKind(resourceClaimGVK.GroupVersion().WithKind(resourceClaimGVK.Kind + "List"))
+	if err := r.Client.List(ctx, claims, client.InNamespace(namespace)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to list resource claims: %w", err)
+	}
+
+	usage := summarizeQuotaUsage(organization.Name, grants.Items, claims.Items)
+
+	var previous []QuotaUsage
+	if value, ok := organization.Annotations[QuotaUsageAnnotation]; ok {
+		if err := json.Unmarshal([]byte(value), &previous); err != nil {
+			logger.Info("Ignoring invalid quota usage annotation", "organization", organization.Name, "error", err.Error())
+		}
+	}
+
+	data, err := json.Marshal(usage)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to encode quota usage: %w", err)
+	}
+	if organization.Annotations[QuotaUsageAnnotation] == string(data) {
+		return ctrl.Result{}, nil
+	}
+
+	patch := client.MergeFrom(organization.DeepCopy())
+	metav1.SetMetaDataAnnotation(&organization.ObjectMeta, QuotaUsageAnnotation, string(data))
+	if err := r.Client.Patch(ctx, organization, patch); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to update quota usage: %w", err)
+	}
+	logger.Info("Updated organization quota usage", "organization", organization.Name)
+
+	for _, crossing := range quotaThresholdCrossings(previous, usage, r.Config.WarningThresholdPercent) {
+		r.Recorder.Event(organization, corev1.EventTypeWarning, crossing.reason, crossing.message)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// summarizeQuotaUsage totals the allowances of all grants and the requests of
+// all claims that reference the organization as their consumer. Claims that
+// were denied, and grants or claims that are being deleted, are not counted.
+func summarizeQuotaUsage(organization string, grants, claims []unstructured.Unstructured) []QuotaUsage {
+	usage := map[string]*QuotaUsage{}
+	entry := func(resourceType string) *QuotaUsage {
+		if _, ok := usage[resourceType]; !ok {
+			usage[resourceType] = &QuotaUsage{ResourceType: resourceType}
+		}
+		return usage[resourceType]
+	}
+
+	for i := range grants {
+		grant := &grants[i]
+		if grant.GetDeletionTimestamp() != nil || !consumedByOrganization(grant, organization) {
+			continue
+		}
+		allowances, _, _ := unstructured.NestedSlice(grant.Object, "spec", "allowances")
+		for _, a := range allowances {
+			allowance, _ := a.(map[string]any)
+			resourceType, _ := allowance["resourceType"].(string)
+			buckets, _ := allowance["buckets"].([]any)
+			for _, b := range buckets {
+				bucket, _ := b.(map[string]any)
+				amount, _ := bucket["amount"].(int64)
+				entry(resourceType).Allowed += amount
+			}
+		}
+	}
+
+	for i := range claims {
+		claim := &claims[i]
+		if claim.GetDeletionTimestamp() != nil || !consumedByOrganization(claim, organization) || claimDenied(claim) {
+			continue
+		}
+		requests, _, _ := unstructured.NestedSlice(claim.Object, "spec", "requests")
+		for _, r := range requests {
+			request, _ := r.(map[string]any)
+			resourceType, _ := request["resourceType"].(string)
+			amount, _ := request["amount"].(int64)
+			entry(resourceType).Used += amount
+		}
+	}
+
+	summary := make([]QuotaUsage, 0, len(usage))
+	for _, u := range usage {
+		summary = append(summary, *u)
+	}
+	sort.Slice(summary, func(i, j int) bool {
+		return summary[i].ResourceType < summary[j].ResourceType
+	})
+	return summary
+}
+
+func consumedByOrganization(obj *unstructured.Unstructured, organization string) bool {
+	kind, _, _ := unstructured.NestedString(obj.Object, "spec", "consumerRef", "kind")
+	name, _, _ := unstructured.NestedString(obj.Object, "spec", "consumerRef", "name")
+	return kind == "Organization" && name == organization
+}
+
+// claimDenied returns true when the quota system rejected the claim.
+func claimDenied(claim *unstructured.Unstructured) bool {
+	conditions, _, _ := unstructured.NestedSlice(claim.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, _ := c.(map[string]any)
+		if condition["type"] == "Granted" {
+			return condition["status"] == string(metav1.ConditionFalse)
+		}
+	}
+	return false
+}
+
+type quotaThresholdCrossing struct {
+	reason  string
+	message string
+}
+
+// quotaThresholdCrossings returns the thresholds that were crossed upwards
+// between the previous and the current usage of each resource type.
+func quotaThresholdCrossings(previous, current []QuotaUsage, warningPercent int64) []quotaThresholdCrossing {
+	before := map[string]int64{}
+	for _, u := range previous {
+		before[u.ResourceType] = u.percent()
+	}
+
+	var crossings []quotaThresholdCrossing
+	for _, u := range current {
+		was, now := before[u.ResourceType], u.percent()
+		switch {
+		case now >= 100 && was < 100:
+			crossings = append(crossings, quotaThresholdCrossing{
+				reason:  "QuotaExhausted",
+				message: fmt.Sprintf("%d of %d %s used", u.Used, u.Allowed, u.ResourceType),
+			})
+		case now >= warningPercent && was < warningPercent:
+			crossings = append(crossings, quotaThresholdCrossing{
+				reason:  "QuotaUsageHigh",
+				message: fmt.Sprintf("%d of %d %s used (%d%%)", u.Used, u.Allowed, u.ResourceType, now),
+			})
+		}
+	}
+	return crossings
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *OrganizationQuotaUsageController) SetupWithManager(mgr ctrl.Manager) error {
+	grant := &unstructured.Unstructured{}
+	grant.SetGroupVersionKind(resourceGrantGVK)
+	claim := &unstructured.Unstructured{}
+	claim.SetGroupVersionKind(resourceClaimGVK)
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&resourcemanagerv1alpha1.Organization{}).
+		Watches(grant, handler.EnqueueRequestsFromMapFunc(organizationForNamespace)).
+		Watches(claim, handler.EnqueueRequestsFromMapFunc(organizationForNamespace)).
+		Named("organization-quota-usage").
+		WithOptions(r.Options).
+		Complete(tracing.Reconciler("organization-quota-usage", r))
+}
+
+// organizationForNamespace maps an object in an organization namespace to the
+// organization.
+func organizationForNamespace(_ context.Context, obj client.Object) []reconcile.Request {
+	name, ok := strings.CutPrefix(obj.GetNamespace(), "organization-")
+	if !ok || name == "" {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: name}}}
+}
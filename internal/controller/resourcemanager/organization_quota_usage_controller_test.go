@@ -0,0 +1,83 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package resourcemanager
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestSummarizeQuotaUsage(t *testing.T) {
+	grant := func(consumer string, amount int64) unstructured.Unstructured {
+		return unstructured.Unstructured{Object: map[string]any{
+			"spec": map[string]any{
+				"consumerRef": map[string]any{"kind": "Organization", "name": consumer},
+				"allowances": []any{
+					map[string]any{
+						"resourceType": "resourcemanager.miloapis.com/projects",
+						"buckets":      []any{map[string]any{"amount": amount}},
+					},
+				},
+			},
+		}}
+	}
+	claim := func(consumer, granted string) unstructured.Unstructured {
+		obj := unstructured.Unstructured{Object: map[string]any{
+			"spec": map[string]any{
+				"consumerRef": map[string]any{"kind": "Organization", "name": consumer},
+				"requests": []any{
+					map[string]any{"resourceType": "resourcemanager.miloapis.com/projects", "amount": int64(1)},
+				},
+			},
+		}}
+		if granted != "" {
+			obj.Object["status"] = map[string]any{
+				"conditions": []any{map[string]any{"type": "Granted", "status": granted}},
+			}
+		}
+		return obj
+	}
+
+	got := summarizeQuotaUsage("acme",
+		[]unstructured.Unstructured{grant("acme", 10), grant("other", 5)},
+		[]unstructured.Unstructured{claim("acme", "True"), claim("acme", ""), claim("acme", "False"), claim("other", "True")},
+	)
+	want := []QuotaUsage{{ResourceType: "resourcemanager.miloapis.com/projects", Allowed: 10, Used: 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("summarizeQuotaUsage() = %+v, want %+v", got, want)
+	}
+}
+
+func TestQuotaThresholdCrossings(t *testing.T) {
+	usage := func(used int64) []QuotaUsage {
+		return []QuotaUsage{{ResourceType: "resourcemanager.miloapis.com/projects", Allowed: 10, Used: used}}
+	}
+
+	tests := []struct {
+		name     string
+		previous []QuotaUsage
+		current  []QuotaUsage
+		want     []string
+	}{
+		{name: "below threshold", previous: usage(1), current: usage(7)},
+		{name: "crosses warning threshold", previous: usage(7), current: usage(8), want: []string{"QuotaUsageHigh"}},
+		{name: "already above warning threshold", previous: usage(8), current: usage(9)},
+		{name: "exhausted", previous: usage(9), current: usage(10), want: []string{"QuotaExhausted"}},
+		{name: "exhausted from nothing", previous: nil, current: usage(10), want: []string{"QuotaExhausted"}},
+		{name: "usage drops", previous: usage(10), current: usage(5)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, c := range quotaThresholdCrossings(tt.previous, tt.current, 80) {
+				got = append(got, c.reason)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("quotaThresholdCrossings() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
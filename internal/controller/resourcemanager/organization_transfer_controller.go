@@ -0,0 +1,353 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package resourcemanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	resourcemanagerv1alpha1 "go.miloapis.com/milo/pkg/apis/resourcemanager/v1alpha1"
+
+	"go.datum.net/datum/internal/tracing"
+)
+
+const (
+	// TransferToAnnotation is set by the owner of a personal organization to
+	// request that its projects are transferred to another organization.
+	TransferToAnnotation = "resourcemanager.datumapis.com/transfer-to"
+
+	// AcceptTransfersFromAnnotation is set by an administrator of the target
+	// organization to accept transfers from the listed, comma separated,
+	// organizations.
+	AcceptTransfersFromAnnotation = "resourcemanager.datumapis.com/accept-transfers-from"
+
+	// OrganizationArchivedAnnotation is set on a personal organization that was
+	// archived after its projects were transferred.
+	OrganizationArchivedAnnotation = "resourcemanager.datumapis.com/archived"
+
+	// OrganizationTransferredAnnotation is set on a personal organization to
+	// the name of the target organization once its projects were transferred.
+	// The PersonalOrganizationController leaves transferred organizations
+	// alone, so they stay archived or converted.
+	OrganizationTransferredAnnotation = "resourcemanager.datumapis.com/transferred-to"
+
+	transferTargetIndex = "metadata.annotations.transfer-to"
+)
+
+// Conditions set on the source organization of a transfer, one for each step.
+const (
+	TransferAcceptedCondition             = "TransferAccepted"
+	TransferProjectsReparentedCondition   = "TransferProjectsReparented"
+	TransferQuotaClaimsRecreatedCondition = "TransferQuotaClaimsRecreated"
+	TransferCompletedCondition            = "TransferCompleted"
+)
+
+// TransferSourceAction is what happens to a personal organization once its
+// projects were transferred.
+type TransferSourceAction string
+
+const (
+	// TransferSourceActionArchive marks the organization as archived.
+	TransferSourceActionArchive TransferSourceAction = "Archive"
+
+	// TransferSourceActionConvert converts the organization into a Standard
+	// organization.
+	TransferSourceActionConvert TransferSourceAction = "Convert"
+)
+
+type OrganizationTransferControllerConfig struct {
+	// SourceAction is applied to personal organizations once their transfer
+	// completed. Defaults to Archive.
+	SourceAction TransferSourceAction `json:"sourceAction"`
+}
+
+// OrganizationTransferController transfers the projects of a personal
+// organization to a team organization.
+//
+// The owner of the personal organization requests a transfer with the
+// TransferToAnnotation, and an administrator of the target organization accepts
+// it with the AcceptTransfersFromAnnotation. The controller then reparents the
+// projects, recreates their quota claims in the target organization, and
+// archives or converts the personal organization. Each step is reported as a
+// status condition on the personal organization.
+type OrganizationTransferController struct {
+	Client client.Client
+
+	Config OrganizationTransferControllerConfig
+
+	// Options configures the workers and rate limiting of the controller.
+	Options controller.Options
+}
+
+// +kubebuilder:rbac:groups=resourcemanager.miloapis.com,resources=organizations,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=resourcemanager.miloapis.com,resources=organizations/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=resourcemanager.miloapis.com,resources=projects,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=quota.miloapis.com,resources=resourceclaims,verbs=get;list;watch;create;delete
+
+// Reconcile advances the transfer requested by a personal organization.
+func (r *OrganizationTransferController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := logf.FromContext(ctx)
+
+	organization := &resourcemanagerv1alpha1.Organization{}
+	if err := r.Client.Get(ctx, req.NamespacedName, organization); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, fmt.Errorf("failed to get organization: %w", err)
+	}
+	targetName := organization.Annotations[TransferToAnnotation]
+	if !organization.DeletionTimestamp.IsZero() || targetName == "" ||
+		meta.IsStatusConditionTrue(organization.Status.Conditions, TransferCompletedCondition) {
+		return ctrl.Result{}, nil
+	}
+
+	// Updating the organization replaces its status with the stored one, so
+	// conditions are collected and applied at the end.
+	var conditions []metav1.Condition
+	condition := func(conditionType string, status metav1.ConditionStatus, reason, message string) {
+		conditions = append(conditions, metav1.Condition{
+			Type:               conditionType,
+			Status:             status,
+			Reason:             reason,
+			Message:            message,
+			ObservedGeneration: organization.Generation,
+		})
+	}
+
+	// The source organization was updated but the conditions were not, such
+	// as when the status update failed. Only the conditions are missing.
+	if organization.Annotations[OrganizationTransferredAnnotation] == targetName {
+		condition(TransferAcceptedCondition, metav1.ConditionTrue, "Accepted",
+			fmt.Sprintf("The transfer was accepted by organization %s", targetName))
+		condition(TransferProjectsReparentedCondition, metav1.ConditionTrue, "Reparented",
+			fmt.Sprintf("Projects were moved to organization %s", targetName))
+		condition(TransferQuotaClaimsRecreatedCondition, metav1.ConditionTrue, "Recreated",
+			fmt.Sprintf("Quota claims were recreated in organization %s", targetName))
+		condition(TransferCompletedCondition, metav1.ConditionTrue, "Completed",
+			fmt.Sprintf("Projects were transferred to organization %s", targetName))
+		return ctrl.Result{}, r.updateConditions(ctx, organization, conditions)
+	}
+
+	target, err := r.validateTarget(ctx, organization, targetName)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	switch {
+	case organization.Spec.Type != "Personal":
+		condition(TransferAcceptedCondition, metav1.ConditionFalse, "InvalidSource", "Only personal organizations can be transferred")
+	case target == nil:
+		condition(TransferAcceptedCondition, metav1.ConditionFalse, "InvalidTarget",
+			fmt.Sprintf("Organization %s does not exist or is not a team organization", targetName))
+	case !slices.Contains(splitList(target.Annotations[AcceptTransfersFromAnnotation]), organization.Name):
+		condition(TransferAcceptedCondition, metav1.ConditionFalse, "AwaitingAcceptance",
+			fmt.Sprintf("Waiting for an administrator of organization %s to accept the transfer", targetName))
+	}
+	if len(conditions) > 0 {
+		return ctrl.Result{}, r.updateConditions(ctx, organization, conditions)
+	}
+	condition(TransferAcceptedCondition, metav1.ConditionTrue, "Accepted",
+		fmt.Sprintf("The transfer was accepted by organization %s", targetName))
+
+	projects, err := r.reparentProjects(ctx, organization.Name, targetName)
+	if err != nil {
+		condition(TransferProjectsReparentedCondition, metav1.ConditionFalse, "ReparentFailed", err.Error())
+		return ctrl.Result{}, errors.Join(err, r.updateConditions(ctx, organization, conditions))
+	}
+	condition(TransferProjectsReparentedCondition, metav1.ConditionTrue, "Reparented",
+		fmt.Sprintf("%d project(s) moved to organization %s", projects, targetName))
+
+	claims, err := r.recreateQuotaClaims(ctx, organization.Name, targetName)
+	if err != nil {
+		condition(TransferQuotaClaimsRecreatedCondition, metav1.ConditionFalse, "RecreateFailed", err.Error())
+		return ctrl.Result{}, errors.Join(err, r.updateConditions(ctx, organization, conditions))
+	}
+	condition(TransferQuotaClaimsRecreatedCondition, metav1.ConditionTrue, "Recreated",
+		fmt.Sprintf("%d quota claim(s) recreated in organization %s", claims, targetName))
+
+	patch := client.MergeFrom(organization.DeepCopy())
+	metav1.SetMetaDataAnnotation(&organization.ObjectMeta, OrganizationTransferredAnnotation, targetName)
+	outcome := "archived"
+	switch r.Config.SourceAction {
+	case TransferSourceActionConvert:
+		organization.Spec.Type = "Standard"
+		outcome = "converted to a Standard organization"
+	default:
+		metav1.SetMetaDataAnnotation(&organization.ObjectMeta, OrganizationArchivedAnnotation, "true")
+	}
+	if err := r.Client.Patch(ctx, organization, patch); err != nil {
+		condition(TransferCompletedCondition, metav1.ConditionFalse, "SourceUpdateFailed", err.Error())
+		return ctrl.Result{}, errors.Join(fmt.Errorf("failed to update transferred organization: %w", err), r.updateConditions(ctx, organization, conditions))
+	}
+	condition(TransferCompletedCondition, metav1.ConditionTrue, "Completed",
+		fmt.Sprintf("Projects were transferred to organization %s and this organization was %s", targetName, outcome))
+
+	logger.Info("Completed organization transfer", "organization", organization.Name, "target", targetName, "projects", projects, "claims", claims)
+
+	return ctrl.Result{}, r.updateConditions(ctx, organization, conditions)
+}
+
+// validateTarget returns the target organization, or nil when it does not
+// exist or cannot receive transfers.
+func (r *OrganizationTransferController) validateTarget(ctx context.Context, source *resourcemanagerv1alpha1.Organization, name string) (*resourcemanagerv1alpha1.Organization, error) {
+	if name == source.Name {
+		return nil, nil
+	}
+	target := &resourcemanagerv1alpha1.Organization{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Name: name}, target); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get target organization: %w", err)
+	}
+	if !target.DeletionTimestamp.IsZero() || target.Spec.Type == "Personal" {
+		return nil, nil
+	}
+	return target, nil
+}
+
+// reparentProjects moves the projects of the source organization to the target
+// organization and returns the number of projects moved.
+func (r *OrganizationTransferController) reparentProjects(ctx context.Context, source, target string) (int, error) {
+	projects := &resourcemanagerv1alpha1.ProjectList{}
+	if err := r.Client.List(ctx, projects); err != nil {
+		return 0, fmt.Errorf("failed to list projects: %w", err)
+	}
+
+	moved := 0
+	for i := range projects.Items {
+		project := &projects.Items[i]
+		if project.Spec.OwnerRef.Kind != "Organization" || project.Spec.OwnerRef.Name != source {
+			continue
+		}
+		project.Spec.OwnerRef.Name = target
+		if err := r.Client.Update(ctx, project); err != nil {
+			return moved, fmt.Errorf("failed to move project %s: %w", project.Name, err)
+		}
+		moved++
+	}
+	return moved, nil
+}
+
+// recreateQuotaClaims recreates the quota claims of the source organization in
+// the namespace of the target organization, consumed by the target, and
+// deletes the originals. It returns the number of claims recreated.
+func (r *OrganizationTransferController) recreateQuotaClaims(ctx context.Context, source, target string) (int, error) {
+	claims := &unstructured.UnstructuredList{}
+	claims.SetGroupVersionKind(resourceClaimGVK.GroupVersion().WithKind(resourceClaimGVK.Kind + "List"))
+	if err := r.Client.List(ctx, claims, client.InNamespace(organizationNamespace(source))); err != nil {
+		return 0, fmt.Errorf("failed to list resource claims: %w", err)
+	}
+
+	recreated := 0
+	for i := range claims.Items {
+		claim := &claims.Items[i]
+		if claim.GetDeletionTimestamp() != nil || !consumedByOrganization(claim, source) {
+			continue
+		}
+		if err := r.Client.Create(ctx, transferredClaim(claim, target)); err != nil && !apierrors.IsAlreadyExists(err) {
+			return recreated, fmt.Errorf("failed to recreate resource claim %s: %w", claim.GetName(), err)
+		}
+		if err := r.Client.Delete(ctx, claim); client.IgnoreNotFound(err) != nil {
+			return recreated, fmt.Errorf("failed to delete resource claim %s: %w", claim.GetName(), err)
+		}
+		recreated++
+	}
+	return recreated, nil
+}
+
+// transferredClaim returns a copy of the claim in the namespace of the target
+// organization and consumed by it.
+func transferredClaim(claim *unstructured.Unstructured, target string) *unstructured.Unstructured {
+	transferred := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": claim.GetAPIVersion(),
+		"kind":       claim.GetKind(),
+	}}
+	transferred.SetName(claim.GetName())
+	transferred.SetNamespace(organizationNamespace(target))
+	transferred.SetLabels(claim.GetLabels())
+	transferred.SetAnnotations(claim.GetAnnotations())
+	if spec, ok, _ := unstructured.NestedMap(claim.Object, "spec"); ok {
+		transferred.Object["spec"] = spec
+	}
+	//revive:disable-next-line:unhandled-error the spec is a map.
+	_ = unstructured.SetNestedField(transferred.Object, target, "spec", "consumerRef", "name")
+	return transferred
+}
+
+func (r *OrganizationTransferController) updateConditions(ctx context.Context, organization *resourcemanagerv1alpha1.Organization, conditions []metav1.Condition) error {
+	changed := false
+	for _, condition := range conditions {
+		if meta.SetStatusCondition(&organization.Status.Conditions, condition) {
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	if err := r.Client.Status().Update(ctx, organization); err != nil {
+		return fmt.Errorf("failed to update organization status: %w", err)
+	}
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *OrganizationTransferController) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &resourcemanagerv1alpha1.Organization{}, transferTargetIndex, transferTarget); err != nil {
+		return err
+	}
+
+	// Accepting a transfer changes the target organization, so changes to any
+	// organization also enqueue the organizations transferring to it.
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&resourcemanagerv1alpha1.Organization{}).
+		Watches(&resourcemanagerv1alpha1.Organization{}, handler.EnqueueRequestsFromMapFunc(r.transferringOrganizations)).
+		Named("organization-transfer").
+		WithOptions(r.Options).
+		Complete(tracing.Reconciler("organization-transfer", r))
+}
+
+func (r *OrganizationTransferController) transferringOrganizations(ctx context.Context, obj client.Object) []reconcile.Request {
+	organizations := &resourcemanagerv1alpha1.OrganizationList{}
+	if err := r.Client.List(ctx, organizations, client.MatchingFields{transferTargetIndex: obj.GetName()}); err != nil {
+		logf.FromContext(ctx).Error(err, "failed to list transferring organizations")
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(organizations.Items))
+	for _, organization := range organizations.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&organization)})
+	}
+	return requests
+}
+
+func transferTarget(obj client.Object) []string {
+	if target := obj.GetAnnotations()[TransferToAnnotation]; target != "" {
+		return []string{target}
+	}
+	return nil
+}
+
+// splitList splits a comma separated list, ignoring whitespace and empty
+// entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
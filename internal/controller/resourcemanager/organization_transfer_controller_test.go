@@ -0,0 +1,160 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package resourcemanager
+
+import (
+	"context"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	resourcemanagerv1alpha1 "go.miloapis.com/milo/pkg/apis/resourcemanager/v1alpha1"
+)
+
+func TestOrganizationTransferController(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := resourcemanagerv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	scheme.AddKnownTypeWithName(resourceClaimGVK, &unstructured.Unstructured{})
+	scheme.AddKnownTypeWithName(resourceClaimGVK.GroupVersion().WithKind(resourceClaimGVK.Kind+"List"), &unstructured.UnstructuredList{})
+
+	source := &resourcemanagerv1alpha1.Organization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "personal-org-1",
+			Annotations: map[string]string{TransferToAnnotation: "team"},
+		},
+		Spec: resourcemanagerv1alpha1.OrganizationSpec{Type: "Personal"},
+	}
+	target := &resourcemanagerv1alpha1.Organization{
+		ObjectMeta: metav1.ObjectMeta{Name: "team"},
+		Spec:       resourcemanagerv1alpha1.OrganizationSpec{Type: "Standard"},
+	}
+	project := &resourcemanagerv1alpha1.Project{
+		ObjectMeta: metav1.ObjectMeta{Name: "personal-project"},
+		Spec: resourcemanagerv1alpha1.ProjectSpec{
+			OwnerRef: resourcemanagerv1alpha1.OwnerReference{Kind: "Organization", Name: "personal-org-1"},
+		},
+	}
+	claim := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": resourceClaimGVK.GroupVersion().String(),
+		"kind":       resourceClaimGVK.Kind,
+		"metadata": map[string]any{
+			"name":      "project-personal-project",
+			"namespace": "organization-personal-org-1",
+		},
+		"spec": map[string]any{
+			"consumerRef": map[string]any{"kind": "Organization", "name": "personal-org-1"},
+			"requests":    []any{map[string]any{"resourceType": "resourcemanager.miloapis.com/projects", "amount": int64(1)}},
+		},
+	}}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(source, target, project, claim).
+		WithStatusSubresource(&resourcemanagerv1alpha1.Organization{}).
+		Build()
+	r := &OrganizationTransferController{Client: c, Config: OrganizationTransferControllerConfig{SourceAction: TransferSourceActionArchive}}
+	reconcile := func() *resourcemanagerv1alpha1.Organization {
+		t.Helper()
+		if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKey{Name: "personal-org-1"}}); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+		organization := &resourcemanagerv1alpha1.Organization{}
+		if err := c.Get(context.Background(), client.ObjectKey{Name: "personal-org-1"}, organization); err != nil {
+			t.Fatal(err)
+		}
+		return organization
+	}
+
+	// The transfer waits until the target organization accepts it.
+	organization := reconcile()
+	accepted := meta.FindStatusCondition(organization.Status.Conditions, TransferAcceptedCondition)
+	if accepted == nil || accepted.Status != metav1.ConditionFalse || accepted.Reason != "AwaitingAcceptance" {
+		t.Fatalf("expected transfer to await acceptance, got %+v", accepted)
+	}
+
+	acceptingTarget := target.DeepCopy()
+	if err := c.Get(context.Background(), client.ObjectKey{Name: "team"}, acceptingTarget); err != nil {
+		t.Fatal(err)
+	}
+	metav1.SetMetaDataAnnotation(&acceptingTarget.ObjectMeta, AcceptTransfersFromAnnotation, "other, personal-org-1")
+	if err := c.Update(context.Background(), acceptingTarget); err != nil {
+		t.Fatal(err)
+	}
+
+	organization = reconcile()
+	for _, conditionType := range []string{TransferAcceptedCondition, TransferProjectsReparentedCondition, TransferQuotaClaimsRecreatedCondition, TransferCompletedCondition} {
+		if !meta.IsStatusConditionTrue(organization.Status.Conditions, conditionType) {
+			t.Errorf("expected condition %s to be true, got %+v", conditionType, meta.FindStatusCondition(organization.Status.Conditions, conditionType))
+		}
+	}
+	if organization.Annotations[OrganizationArchivedAnnotation] != "true" || organization.Annotations[OrganizationTransferredAnnotation] != "team" {
+		t.Errorf("expected source organization to be archived, got annotations %v", organization.Annotations)
+	}
+
+	moved := &resourcemanagerv1alpha1.Project{}
+	if err := c.Get(context.Background(), client.ObjectKeyFromObject(project), moved); err != nil {
+		t.Fatal(err)
+	}
+	if moved.Spec.OwnerRef.Name != "team" {
+		t.Errorf("project owner = %s, want team", moved.Spec.OwnerRef.Name)
+	}
+
+	recreated := &unstructured.Unstructured{}
+	recreated.SetGroupVersionKind(resourceClaimGVK)
+	if err := c.Get(context.Background(), client.ObjectKey{Namespace: "organization-team", Name: "project-personal-project"}, recreated); err != nil {
+		t.Fatalf("expected claim to be recreated in the target organization: %v", err)
+	}
+	if !consumedByOrganization(recreated, "team") {
+		t.Errorf("expected recreated claim to be consumed by the target organization, got %v", recreated.Object["spec"])
+	}
+	old := &unstructured.Unstructured{}
+	old.SetGroupVersionKind(resourceClaimGVK)
+	if err := c.Get(context.Background(), client.ObjectKeyFromObject(claim), old); !apierrors.IsNotFound(err) {
+		t.Errorf("expected source claim to be deleted, got %v", err)
+	}
+}
+
+func TestOrganizationTransferControllerConvertedSource(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := resourcemanagerv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	// The source was converted, but the conditions were not stored.
+	source := &resourcemanagerv1alpha1.Organization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "personal-org-1",
+			Annotations: map[string]string{
+				TransferToAnnotation:              "team",
+				OrganizationTransferredAnnotation: "team",
+			},
+		},
+		Spec: resourcemanagerv1alpha1.OrganizationSpec{Type: "Standard"},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(source).
+		WithStatusSubresource(&resourcemanagerv1alpha1.Organization{}).
+		Build()
+	r := &OrganizationTransferController{Client: c, Config: OrganizationTransferControllerConfig{SourceAction: TransferSourceActionConvert}}
+
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(source)}); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	organization := &resourcemanagerv1alpha1.Organization{}
+	if err := c.Get(context.Background(), client.ObjectKeyFromObject(source), organization); err != nil {
+		t.Fatal(err)
+	}
+	for _, conditionType := range []string{TransferAcceptedCondition, TransferProjectsReparentedCondition, TransferQuotaClaimsRecreatedCondition, TransferCompletedCondition} {
+		if !meta.IsStatusConditionTrue(organization.Status.Conditions, conditionType) {
+			t.Errorf("expected condition %s to be true, got %+v", conditionType, meta.FindStatusCondition(organization.Status.Conditions, conditionType))
+		}
+	}
+}
@@ -12,14 +12,22 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	iamv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
 	resourcemanagerv1alpha1 "go.miloapis.com/milo/pkg/apis/resourcemanager/v1alpha1"
+
+	"go.datum.net/datum/internal/sharding"
+	"go.datum.net/datum/internal/tracing"
 )
 
 type PersonalOrganizationControllerConfig struct {
@@ -32,6 +40,12 @@ type PersonalOrganizationControllerConfig struct {
 	// The namespace the owner role exists in that will be assigned to the user
 	// the organization is being created for.
 	RoleNamespace string `json:"roleNamespace"`
+
+	// OmitUserNames leaves the names of users out of the display names and
+	// descriptions of new personal organizations and projects. Existing
+	// organizations keep their display name, which cannot be changed.
+	// Defaults to false.
+	OmitUserNames bool `json:"omitUserNames,omitempty"`
 }
 
 // PersonalOrganizationController reconciles a User object
@@ -46,6 +60,18 @@ type PersonalOrganizationController struct {
 
 	// RestConfig is used to create an impersonated client for project creation.
 	RestConfig *rest.Config
+
+	// Repairs receives users to reconcile outside of User events, such as the
+	// users with an incomplete personal workspace found by the
+	// PersonalWorkspaceAuditor.
+	Repairs <-chan event.GenericEvent
+
+	// Shards restricts the controller to the Users of the shards owned by the
+	// replica. When nil, the controller reconciles every User.
+	Shards *sharding.Coordinator
+
+	// Options configures the workers and rate limiting of the controller.
+	Options controller.Options
 }
 
 // +kubebuilder:rbac:groups=iam.datumapis.com,resources=users,verbs=get;list;watch
@@ -80,15 +106,29 @@ func (r *PersonalOrganizationController) Reconcile(ctx context.Context, req ctrl
 	personalOrg := &resourcemanagerv1alpha1.Organization{
 		ObjectMeta: metav1.ObjectMeta{
 			// Create a unique name for the personal organization.
-			Name: fmt.Sprintf("personal-org-%s", hashPersonalOrgName(string(user.UID))),
+			Name: PersonalOrganizationName(user.UID),
 		},
 	}
 
+	// Organizations whose projects were transferred are archived or converted
+	// to Standard organizations, which updating them would undo.
+	existingOrg := &resourcemanagerv1alpha1.Organization{}
+	if err := r.Client.Get(ctx, client.ObjectKeyFromObject(personalOrg), existingOrg); client.IgnoreNotFound(err) != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to get personal organization: %w", err)
+	} else if err == nil && existingOrg.Annotations[OrganizationTransferredAnnotation] != "" {
+		logger.Info("Personal organization was transferred, skipping reconciliation", "organization", personalOrg.Name)
+		return ctrl.Result{}, nil
+	}
+
 	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, personalOrg, func() error {
 		logger.Info("Creating or updating personal organization", "organization", personalOrg.Name)
-		// TODO: Remove once portal uses the description annotation
-		metav1.SetMetaDataAnnotation(&personalOrg.ObjectMeta, "kubernetes.io/display-name", fmt.Sprintf("%s %s's Personal Org", user.Spec.GivenName, user.Spec.FamilyName))
-		metav1.SetMetaDataAnnotation(&personalOrg.ObjectMeta, "kubernetes.io/description", fmt.Sprintf("%s %s's Personal Org", user.Spec.GivenName, user.Spec.FamilyName))
+		// The display name of existing personal organizations cannot be
+		// changed, so omitting user names only applies to new ones.
+		if !r.Config.OmitUserNames || personalOrg.ResourceVersion == "" {
+			// TODO: Remove once portal uses the description annotation
+			metav1.SetMetaDataAnnotation(&personalOrg.ObjectMeta, "kubernetes.io/display-name", r.personalName(user, "Personal Org"))
+			metav1.SetMetaDataAnnotation(&personalOrg.ObjectMeta, "kubernetes.io/description", r.personalName(user, "Personal Org"))
+		}
 		if err := controllerutil.SetControllerReference(user, personalOrg, r.Scheme); err != nil {
 			return fmt.Errorf("failed to set controller reference: %w", err)
 		}
@@ -101,10 +141,11 @@ func (r *PersonalOrganizationController) Reconcile(ctx context.Context, req ctrl
 
 	// Now we need to create the OrganizationMembership for the user to grant them
 	// access to the personal organization.
+	membershipKey := personalMembershipKey(user)
 	membership := &resourcemanagerv1alpha1.OrganizationMembership{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      fmt.Sprintf("membership-%s", user.Name),
-			Namespace: fmt.Sprintf("organization-%s", personalOrg.Name),
+			Name:      membershipKey.Name,
+			Namespace: membershipKey.Namespace,
 		},
 	}
 
@@ -138,10 +179,9 @@ func (r *PersonalOrganizationController) Reconcile(ctx context.Context, req ctrl
 	}
 
 	// Create a default personal project in the personal organization.
-	personalProjectID := hashPersonalOrgName(string(user.UID))
 	personalProject := &resourcemanagerv1alpha1.Project{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: fmt.Sprintf("personal-project-%s", personalProjectID),
+			Name: PersonalProjectName(user.UID),
 		},
 	}
 
@@ -155,23 +195,7 @@ func (r *PersonalOrganizationController) Reconcile(ctx context.Context, req ctrl
 			return ctrl.Result{}, fmt.Errorf("failed to check for existing personal project: %w", err)
 		}
 
-		// The project webhook requires parent context in UserInfo.Extra fields,
-		// and also looks up the requesting user by UID to create a PolicyBinding
-		// granting them ownership. We impersonate the actual user so the webhook
-		// sees the correct identity and creates the right PolicyBinding.
-		impersonatedConfig := rest.CopyConfig(r.RestConfig)
-		impersonatedConfig.Impersonate = rest.ImpersonationConfig{
-			UserName: user.Spec.Email,
-			UID:      user.Name,
-			Groups:   []string{"system:authenticated"},
-			Extra: map[string][]string{
-				"iam.miloapis.com/parent-name":      {personalOrg.Name},
-				"iam.miloapis.com/parent-type":      {"Organization"},
-				"iam.miloapis.com/parent-api-group": {"resourcemanager.miloapis.com"},
-			},
-		}
-
-		impersonatedClient, err := client.New(impersonatedConfig, client.Options{Scheme: r.Scheme})
+		impersonatedClient, err := newOrganizationUserClient(r.RestConfig, r.Scheme, user, personalOrg.Name)
 		if err != nil {
 			return ctrl.Result{}, fmt.Errorf("failed to create impersonated client: %w", err)
 		}
@@ -180,7 +204,7 @@ func (r *PersonalOrganizationController) Reconcile(ctx context.Context, req ctrl
 		// the webhook sees the actual user's identity.
 		logger.Info("Creating personal project", "organization", personalOrg.Name, "project", personalProject.Name)
 		metav1.SetMetaDataAnnotation(&personalProject.ObjectMeta, "kubernetes.io/display-name", "Personal Project")
-		metav1.SetMetaDataAnnotation(&personalProject.ObjectMeta, "kubernetes.io/description", fmt.Sprintf("%s %s's Personal Project", user.Spec.GivenName, user.Spec.FamilyName))
+		metav1.SetMetaDataAnnotation(&personalProject.ObjectMeta, "kubernetes.io/description", r.personalName(user, "Personal Project"))
 
 		if err := impersonatedClient.Create(ctx, personalProject); err != nil {
 			if apierrors.IsAlreadyExists(err) {
@@ -197,12 +221,45 @@ func (r *PersonalOrganizationController) Reconcile(ctx context.Context, req ctrl
 	return ctrl.Result{}, nil
 }
 
+// personalName returns the name of a personal resource of the user, prefixed
+// with the name of the user unless user names are omitted.
+func (r *PersonalOrganizationController) personalName(user *iamv1alpha1.User, name string) string {
+	if r.Config.OmitUserNames {
+		return name
+	}
+	return fmt.Sprintf("%s %s's %s", user.Spec.GivenName, user.Spec.FamilyName, name)
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *PersonalOrganizationController) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&iamv1alpha1.User{}).
+	b := r.Shards.For(ctrl.NewControllerManagedBy(mgr), &iamv1alpha1.User{}).
 		Named("personal-organization").
-		Complete(r)
+		WithOptions(r.Shards.Options(r.Options))
+	if r.Repairs != nil {
+		b = b.WatchesRawSource(source.Channel(r.Repairs, &handler.EnqueueRequestForObject{}))
+	}
+	return b.Complete(tracing.Reconciler("personal-organization", r.Shards.Reconciler(r)))
+}
+
+// PersonalOrganizationName returns the name of the personal organization of
+// the user with the given UID.
+func PersonalOrganizationName(uid types.UID) string {
+	return fmt.Sprintf("personal-org-%s", hashPersonalOrgName(string(uid)))
+}
+
+// PersonalProjectName returns the name of the default project in the personal
+// organization of the user with the given UID.
+func PersonalProjectName(uid types.UID) string {
+	return fmt.Sprintf("personal-project-%s", hashPersonalOrgName(string(uid)))
+}
+
+// personalMembershipKey returns the key of the membership of the user in their
+// personal organization.
+func personalMembershipKey(user *iamv1alpha1.User) types.NamespacedName {
+	return types.NamespacedName{
+		Namespace: fmt.Sprintf("organization-%s", PersonalOrganizationName(user.UID)),
+		Name:      fmt.Sprintf("membership-%s", user.Name),
+	}
 }
 
 func hashPersonalOrgName(name string) string {
@@ -2,7 +2,21 @@
 
 package resourcemanager
 
-import "testing"
+import (
+	"context"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	iamv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
+	resourcemanagerv1alpha1 "go.miloapis.com/milo/pkg/apis/resourcemanager/v1alpha1"
+)
 
 func TestHashPersonalOrgName(t *testing.T) {
 	first := hashPersonalOrgName("uid-123")
@@ -17,3 +31,74 @@ func TestHashPersonalOrgName(t *testing.T) {
 		t.Fatal("hashPersonalOrgName() returned same value for different inputs")
 	}
 }
+
+func TestPersonalOrganizationController(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iamv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := resourcemanagerv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	pending := &iamv1alpha1.User{
+		ObjectMeta: metav1.ObjectMeta{Name: "pending", UID: types.UID("pending")},
+		Spec:       iamv1alpha1.UserSpec{GivenName: "Ada", FamilyName: "Lovelace"},
+	}
+	anonymous := &iamv1alpha1.User{
+		ObjectMeta: metav1.ObjectMeta{Name: "anonymous", UID: types.UID("anonymous")},
+		Spec:       iamv1alpha1.UserSpec{GivenName: "Grace", FamilyName: "Hopper"},
+	}
+	transferred := &iamv1alpha1.User{ObjectMeta: metav1.ObjectMeta{Name: "transferred", UID: types.UID("transferred")}}
+	converted := &resourcemanagerv1alpha1.Organization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        PersonalOrganizationName(transferred.UID),
+			Annotations: map[string]string{OrganizationTransferredAnnotation: "team"},
+		},
+		Spec: resourcemanagerv1alpha1.OrganizationSpec{Type: "Standard"},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pending, anonymous, transferred, converted).Build()
+	r := &PersonalOrganizationController{
+		Client: c,
+		Scheme: scheme,
+		Config: PersonalOrganizationControllerConfig{RoleName: "owner", RoleNamespace: "datum-cloud"},
+	}
+	reconcile := func(user *iamv1alpha1.User) *resourcemanagerv1alpha1.Organization {
+		t.Helper()
+		if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(user)}); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+		organization := &resourcemanagerv1alpha1.Organization{}
+		if err := c.Get(context.Background(), client.ObjectKey{Name: PersonalOrganizationName(user.UID)}, organization); err != nil {
+			t.Fatal(err)
+		}
+		return organization
+	}
+
+	organization := reconcile(pending)
+	if organization.Spec.Type != "Personal" {
+		t.Errorf("personal organization type = %s, want Personal", organization.Spec.Type)
+	}
+	if got, want := organization.Annotations["kubernetes.io/display-name"], "Ada Lovelace's Personal Org"; got != want {
+		t.Errorf("personal organization display name = %q, want %q", got, want)
+	}
+
+	// Omitting user names only applies to new organizations, as the display
+	// name of personal organizations cannot be changed.
+	r.Config.OmitUserNames = true
+	if got, want := reconcile(pending).Annotations["kubernetes.io/display-name"], "Ada Lovelace's Personal Org"; got != want {
+		t.Errorf("existing personal organization display name = %q, want %q", got, want)
+	}
+	if got, want := reconcile(anonymous).Annotations["kubernetes.io/display-name"], "Personal Org"; got != want {
+		t.Errorf("new personal organization display name = %q, want %q", got, want)
+	}
+
+	// A converted organization is not turned back into a personal one.
+	if organization := reconcile(transferred); organization.Spec.Type != "Standard" {
+		t.Errorf("transferred organization type = %s, want Standard", organization.Spec.Type)
+	}
+	err := c.Get(context.Background(), personalMembershipKey(transferred), &resourcemanagerv1alpha1.OrganizationMembership{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected no membership to be created in a transferred organization, got %v", err)
+	}
+}
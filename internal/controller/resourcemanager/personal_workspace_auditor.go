@@ -0,0 +1,225 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package resourcemanager
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	iamv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
+	resourcemanagerv1alpha1 "go.miloapis.com/milo/pkg/apis/resourcemanager/v1alpha1"
+
+	"go.datum.net/datum/internal/sharding"
+	"go.datum.net/datum/internal/tracing"
+)
+
+// Resources of a personal workspace, used as the resource label of the
+// inconsistency metrics.
+const (
+	workspaceResourceOrganization = "organization"
+	workspaceResourceMembership   = "membership"
+	workspaceResourceProject      = "project"
+)
+
+var (
+	personalWorkspaceInconsistencies = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "datum_personal_workspace_inconsistencies",
+		Help: "Number of approved users missing a resource of their personal workspace, by resource, as of the last audit.",
+	}, []string{"resource"})
+	personalWorkspaceRepairs = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "datum_personal_workspace_repairs_total",
+		Help: "Number of users sent for repair by the personal workspace auditor.",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(personalWorkspaceInconsistencies, personalWorkspaceRepairs)
+}
+
+// auditRequest is the single request the auditor is reconciled with, as each
+// audit scans every user.
+var auditRequest = reconcile.Request{NamespacedName: types.NamespacedName{Name: "personal-workspaces"}}
+
+// +kubebuilder:object:generate=true
+
+type PersonalWorkspaceAuditorConfig struct {
+	// Enabled runs the auditor. The auditor never runs when
+	// UnifiedOrganizations is enabled, as personal workspaces are not created.
+	Enabled bool `json:"enabled"`
+
+	// Interval is the time between two audits. Defaults to 1h.
+	Interval metav1.Duration `json:"interval"`
+
+	// GracePeriod is how long after signing up a user is expected to have a
+	// complete personal workspace. Younger users are not audited. Defaults to
+	// 10m.
+	GracePeriod metav1.Duration `json:"gracePeriod"`
+
+	// Repair enqueues users with an incomplete personal workspace to the
+	// personal organization controller, which recreates the missing
+	// resources.
+	Repair bool `json:"repair"`
+}
+
+// PersonalWorkspaceAuditor periodically verifies that every approved user has
+// the personal organization, organization membership and personal project
+// created by the PersonalOrganizationController. Inconsistencies are exported
+// as metrics, and the users are optionally enqueued for repair. Users whose
+// personal organization was transferred are not audited.
+//
+// With sharding, every replica audits the users of the shards it owns, so
+// repairs are only sent for users its PersonalOrganizationController
+// reconciles, and the metrics of each replica cover its own users.
+type PersonalWorkspaceAuditor struct {
+	Client client.Client
+
+	Config PersonalWorkspaceAuditorConfig
+
+	// Repairs receives users to repair when Config.Repair is set. It is
+	// consumed by the PersonalOrganizationController.
+	Repairs chan<- event.GenericEvent
+
+	// Shards restricts the audit to the Users of the shards owned by the
+	// replica. When nil, every User is audited on the leader.
+	Shards *sharding.Coordinator
+
+	// Options configures the workers and rate limiting of the controller.
+	Options controller.Options
+}
+
+// +kubebuilder:rbac:groups=iam.miloapis.com,resources=users,verbs=get;list;watch
+// +kubebuilder:rbac:groups=resourcemanager.miloapis.com,resources=organizations,verbs=get;list;watch
+// +kubebuilder:rbac:groups=resourcemanager.miloapis.com,resources=organizationmemberships,verbs=get;list;watch
+// +kubebuilder:rbac:groups=resourcemanager.miloapis.com,resources=projects,verbs=get;list;watch
+
+// Reconcile audits the personal workspaces of all approved users, and requeues
+// the next audit.
+func (r *PersonalWorkspaceAuditor) Reconcile(ctx context.Context, _ ctrl.Request) (ctrl.Result, error) {
+	logger := logf.FromContext(ctx)
+
+	inconsistencies, repairs, err := r.audit(ctx, time.Now())
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	for _, resource := range []string{workspaceResourceOrganization, workspaceResourceMembership, workspaceResourceProject} {
+		personalWorkspaceInconsistencies.WithLabelValues(resource).Set(float64(inconsistencies[resource]))
+	}
+	logger.Info("Audited personal workspaces", "inconsistent", len(repairs))
+
+	if r.Config.Repair && r.Repairs != nil {
+		for _, user := range repairs {
+			select {
+			case r.Repairs <- event.GenericEvent{Object: user}:
+				personalWorkspaceRepairs.Inc()
+			case <-ctx.Done():
+				return ctrl.Result{}, ctx.Err()
+			}
+		}
+	}
+
+	return ctrl.Result{RequeueAfter: r.Config.Interval.Duration}, nil
+}
+
+// audit returns the number of audited users missing each resource of their
+// personal workspace, and the users with an incomplete personal workspace.
+func (r *PersonalWorkspaceAuditor) audit(ctx context.Context, now time.Time) (map[string]int, []*iamv1alpha1.User, error) {
+	logger := logf.FromContext(ctx)
+
+	users := &iamv1alpha1.UserList{}
+	if err := r.Client.List(ctx, users); err != nil {
+		return nil, nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	organizations := &resourcemanagerv1alpha1.OrganizationList{}
+	if err := r.Client.List(ctx, organizations); err != nil {
+		return nil, nil, fmt.Errorf("failed to list organizations: %w", err)
+	}
+	memberships := &resourcemanagerv1alpha1.OrganizationMembershipList{}
+	if err := r.Client.List(ctx, memberships); err != nil {
+		return nil, nil, fmt.Errorf("failed to list organization memberships: %w", err)
+	}
+	projects := &resourcemanagerv1alpha1.ProjectList{}
+	if err := r.Client.List(ctx, projects); err != nil {
+		return nil, nil, fmt.Errorf("failed to list projects: %w", err)
+	}
+
+	organizationNames := sets.New[string]()
+	transferred := sets.New[string]()
+	for _, organization := range organizations.Items {
+		organizationNames.Insert(organization.Name)
+		if organization.Annotations[OrganizationTransferredAnnotation] != "" {
+			transferred.Insert(organization.Name)
+		}
+	}
+	membershipKeys := sets.New[types.NamespacedName]()
+	for _, membership := range memberships.Items {
+		membershipKeys.Insert(client.ObjectKeyFromObject(&membership))
+	}
+	projectNames := sets.New[string]()
+	for _, project := range projects.Items {
+		projectNames.Insert(project.Name)
+	}
+
+	inconsistencies := map[string]int{}
+	var repairs []*iamv1alpha1.User
+	for i := range users.Items {
+		user := &users.Items[i]
+		if !user.DeletionTimestamp.IsZero() ||
+			user.Status.RegistrationApproval != iamv1alpha1.RegistrationApprovalStateApproved ||
+			now.Sub(user.CreationTimestamp.Time) < r.Config.GracePeriod.Duration ||
+			(r.Shards != nil && !r.Shards.Owns(user)) ||
+			transferred.Has(PersonalOrganizationName(user.UID)) {
+			continue
+		}
+
+		var missing []string
+		if !organizationNames.Has(PersonalOrganizationName(user.UID)) {
+			missing = append(missing, workspaceResourceOrganization)
+		}
+		if !membershipKeys.Has(personalMembershipKey(user)) {
+			missing = append(missing, workspaceResourceMembership)
+		}
+		if !projectNames.Has(PersonalProjectName(user.UID)) {
+			missing = append(missing, workspaceResourceProject)
+		}
+		if len(missing) == 0 {
+			continue
+		}
+		logger.Info("User is missing personal workspace resources", "user", user.Name, "missing", missing)
+		for _, resource := range missing {
+			inconsistencies[resource]++
+		}
+		repairs = append(repairs, user)
+	}
+
+	return inconsistencies, repairs, nil
+}
+
+// SetupWithManager sets up the controller with the Manager. The first audit
+// runs once the manager starts, and each audit requeues the next one.
+func (r *PersonalWorkspaceAuditor) SetupWithManager(mgr ctrl.Manager) error {
+	start := source.Func(func(_ context.Context, queue workqueue.TypedRateLimitingInterface[reconcile.Request]) error {
+		queue.Add(auditRequest)
+		return nil
+	})
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("personal-workspace-auditor").
+		WatchesRawSource(start).
+		WithOptions(r.Shards.Options(r.Options)).
+		Complete(tracing.Reconciler("personal-workspace-auditor", r))
+}
@@ -0,0 +1,113 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package resourcemanager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	iamv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
+	resourcemanagerv1alpha1 "go.miloapis.com/milo/pkg/apis/resourcemanager/v1alpha1"
+)
+
+func TestPersonalWorkspaceAuditor(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iamv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := resourcemanagerv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	newUser := func(name string, age time.Duration, approval iamv1alpha1.RegistrationApprovalState) *iamv1alpha1.User {
+		return &iamv1alpha1.User{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				UID:               types.UID(name),
+				CreationTimestamp: metav1.NewTime(now.Add(-age)),
+			},
+			Status: iamv1alpha1.UserStatus{RegistrationApproval: approval},
+		}
+	}
+	workspace := func(user *iamv1alpha1.User, withProject bool) []client.Object {
+		key := personalMembershipKey(user)
+		objects := []client.Object{
+			&resourcemanagerv1alpha1.Organization{ObjectMeta: metav1.ObjectMeta{Name: PersonalOrganizationName(user.UID)}},
+			&resourcemanagerv1alpha1.OrganizationMembership{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}},
+		}
+		if withProject {
+			objects = append(objects, &resourcemanagerv1alpha1.Project{ObjectMeta: metav1.ObjectMeta{Name: PersonalProjectName(user.UID)}})
+		}
+		return objects
+	}
+
+	complete := newUser("complete", time.Hour, iamv1alpha1.RegistrationApprovalStateApproved)
+	noProject := newUser("no-project", time.Hour, iamv1alpha1.RegistrationApprovalStateApproved)
+	empty := newUser("empty", time.Hour, iamv1alpha1.RegistrationApprovalStateApproved)
+	recent := newUser("recent", time.Minute, iamv1alpha1.RegistrationApprovalStateApproved)
+	pending := newUser("pending", time.Hour, iamv1alpha1.RegistrationApprovalStatePending)
+	// The personal project of a transferred organization moved to the
+	// target organization.
+	transferred := newUser("transferred", time.Hour, iamv1alpha1.RegistrationApprovalStateApproved)
+	transferredWorkspace := workspace(transferred, false)
+	transferredWorkspace[0].SetAnnotations(map[string]string{OrganizationTransferredAnnotation: "acme"})
+
+	objects := []client.Object{complete, noProject, empty, recent, pending, transferred}
+	objects = append(objects, workspace(complete, true)...)
+	objects = append(objects, workspace(noProject, false)...)
+	objects = append(objects, transferredWorkspace...)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+
+	repairs := make(chan event.GenericEvent, 10)
+	auditor := &PersonalWorkspaceAuditor{
+		Client: c,
+		Config: PersonalWorkspaceAuditorConfig{
+			Enabled:     true,
+			Interval:    metav1.Duration{Duration: time.Hour},
+			GracePeriod: metav1.Duration{Duration: 10 * time.Minute},
+			Repair:      true,
+		},
+		Repairs: repairs,
+	}
+
+	result, err := auditor.Reconcile(context.Background(), ctrl.Request{NamespacedName: auditRequest.NamespacedName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.RequeueAfter != time.Hour {
+		t.Errorf("expected the next audit in 1h, got %s", result.RequeueAfter)
+	}
+
+	inconsistencies, _, err := auditor.audit(context.Background(), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for resource, expected := range map[string]int{
+		workspaceResourceOrganization: 1,
+		workspaceResourceMembership:   1,
+		workspaceResourceProject:      2,
+	} {
+		if inconsistencies[resource] != expected {
+			t.Errorf("expected %d users missing a %s, got %d", expected, resource, inconsistencies[resource])
+		}
+	}
+
+	close(repairs)
+	var repaired []string
+	for repair := range repairs {
+		repaired = append(repaired, repair.Object.GetName())
+	}
+	if len(repaired) != 2 || repaired[0] != "empty" || repaired[1] != "no-project" {
+		t.Errorf("expected empty and no-project to be repaired, got %v", repaired)
+	}
+}
@@ -0,0 +1,343 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package resourcemanager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	iamv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
+	resourcemanagerv1alpha1 "go.miloapis.com/milo/pkg/apis/resourcemanager/v1alpha1"
+
+	"go.datum.net/datum/internal/roles"
+	"go.datum.net/datum/internal/sharding"
+	"go.datum.net/datum/internal/tracing"
+)
+
+const (
+	// OffboardingRecordAnnotation holds the OffboardingRecord of a User whose
+	// memberships were revoked.
+	OffboardingRecordAnnotation = "iam.datumapis.com/offboarding-record"
+
+	// SuspendedRolesAnnotation holds the roles of a suspended
+	// OrganizationMembership, as a JSON list, so they can be restored when
+	// the user is reactivated.
+	SuspendedRolesAnnotation = "iam.datumapis.com/suspended-roles"
+
+	membershipUserIndex = "spec.userRef.name"
+)
+
+// OffboardingAction is what happens to the memberships of an offboarded user.
+type OffboardingAction string
+
+const (
+	// OffboardingActionSuspend removes all roles from the memberships of the
+	// user, and restores them if the user is reactivated.
+	OffboardingActionSuspend OffboardingAction = "Suspend"
+
+	// OffboardingActionRemove deletes the memberships of the user.
+	OffboardingActionRemove OffboardingAction = "Remove"
+)
+
+// +kubebuilder:object:generate=true
+
+type UserOffboardingControllerConfig struct {
+	// Action is applied to the memberships of users that are deactivated or
+	// whose registration is rejected. Defaults to Suspend.
+	Action OffboardingAction `json:"action"`
+
+	// OwnerRoles are the roles that make a member an owner of an organization.
+	// Roles inheriting one of them also count. The membership of the last
+	// owner of an organization is left in place for staff to assign a new
+	// owner. Defaults to the owner role of the assignable organization roles.
+	OwnerRoles []resourcemanagerv1alpha1.RoleReference `json:"ownerRoles,omitempty"`
+}
+
+// OffboardingRecord is the audit record of an offboarded user.
+type OffboardingRecord struct {
+	Time metav1.Time `json:"time"`
+
+	// Reason is why the user was offboarded: Inactive or Rejected.
+	Reason string `json:"reason"`
+
+	Action OffboardingAction `json:"action"`
+
+	// Memberships are the memberships that were suspended or removed.
+	Memberships []OffboardedMembership `json:"memberships,omitempty"`
+
+	// OwnedOrganizations are the organizations the user was the last owner
+	// of, whose memberships were left in place.
+	OwnedOrganizations []string `json:"ownedOrganizations,omitempty"`
+}
+
+// OffboardedMembership is a membership revoked by offboarding.
+type OffboardedMembership struct {
+	Organization string                                  `json:"organization"`
+	Roles        []resourcemanagerv1alpha1.RoleReference `json:"roles,omitempty"`
+}
+
+// UserOffboardingController revokes the organization memberships of users that
+// are deactivated or whose registration is rejected. Memberships are suspended
+// or removed according to the configured action, and what happened is recorded
+// on the User and as Events.
+//
+// Members are never promoted to owners. The membership of the last owner of an
+// organization is left in place, and a warning Event asks staff to assign a new
+// owner.
+//
+// Memberships of Personal organizations are left alone, since the user is the
+// only member of their personal organization.
+type UserOffboardingController struct {
+	Client client.Client
+
+	Config UserOffboardingControllerConfig
+
+	Recorder record.EventRecorder
+
+	// Shards restricts the controller to the Users of the shards owned by the
+	// replica. When nil, the controller reconciles every User.
+	Shards *sharding.Coordinator
+
+	// Options configures the workers and rate limiting of the controller.
+	Options controller.Options
+}
+
+// +kubebuilder:rbac:groups=iam.miloapis.com,resources=users,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=iam.miloapis.com,resources=roles,verbs=get;list;watch
+// +kubebuilder:rbac:groups=resourcemanager.miloapis.com,resources=organizations,verbs=get;list;watch
+// +kubebuilder:rbac:groups=resourcemanager.miloapis.com,resources=organizationmemberships,verbs=get;list;watch;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
+// Reconcile offboards or reactivates a user.
+func (r *UserOffboardingController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := logf.FromContext(ctx)
+
+	user := &iamv1alpha1.User{}
+	if err := r.Client.Get(ctx, req.NamespacedName, user); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, fmt.Errorf("failed to get user: %w", err)
+	}
+	if !user.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	memberships := &resourcemanagerv1alpha1.OrganizationMembershipList{}
+	if err := r.Client.List(ctx, memberships, client.MatchingFields{membershipUserIndex: user.Name}); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to list user memberships: %w", err)
+	}
+
+	reason := offboardingReason(user)
+	if reason == "" {
+		if !metav1.HasAnnotation(user.ObjectMeta, OffboardingRecordAnnotation) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, r.reactivate(ctx, user, memberships.Items)
+	}
+
+	audit := &OffboardingRecord{}
+	if value, ok := user.Annotations[OffboardingRecordAnnotation]; ok {
+		if err := json.Unmarshal([]byte(value), audit); err != nil {
+			logger.Error(err, "Ignoring invalid offboarding record", "user", user.Name)
+			audit = &OffboardingRecord{}
+		}
+	}
+	changed := false
+	if audit.Time.IsZero() {
+		audit.Time = metav1.Now()
+		audit.Reason = reason
+		audit.Action = r.Config.Action
+		changed = true
+	}
+
+	var graph *roles.Graph
+	for i := range memberships.Items {
+		membership := &memberships.Items[i]
+		organizationName := membership.Spec.OrganizationRef.Name
+		if !membership.DeletionTimestamp.IsZero() || !strings.HasPrefix(membership.Namespace, "organization-") ||
+			metav1.HasAnnotation(membership.ObjectMeta, SuspendedRolesAnnotation) {
+			continue
+		}
+
+		organization := &resourcemanagerv1alpha1.Organization{}
+		if err := r.Client.Get(ctx, client.ObjectKey{Name: organizationName}, organization); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return ctrl.Result{}, fmt.Errorf("failed to get organization: %w", err)
+		}
+		if organization.Spec.Type == "Personal" {
+			continue
+		}
+
+		if graph == nil {
+			allRoles := &iamv1alpha1.RoleList{}
+			if err := r.Client.List(ctx, allRoles); err != nil {
+				return ctrl.Result{}, fmt.Errorf("failed to list roles: %w", err)
+			}
+			graph = roles.NewGraph(allRoles.Items)
+		}
+		last, err := r.lastOwner(ctx, graph, membership)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if last {
+			if !slices.Contains(audit.OwnedOrganizations, organizationName) {
+				logger.Info("Offboarded user is the last owner of an organization, leaving membership for staff", "user", user.Name, "organization", organizationName)
+				audit.OwnedOrganizations = append(audit.OwnedOrganizations, organizationName)
+				r.Recorder.Eventf(organization, corev1.EventTypeWarning, "LastOwnerOffboarded",
+					"Offboarded user %s is the last owner; assign the owner role to another member", user.Name)
+				changed = true
+			}
+			continue
+		}
+
+		if err := r.revoke(ctx, membership); err != nil {
+			return ctrl.Result{}, err
+		}
+		logger.Info("Revoked organization membership of offboarded user", "user", user.Name, "organization", organizationName, "action", r.Config.Action)
+		audit.Memberships = append(audit.Memberships, OffboardedMembership{Organization: organizationName, Roles: membership.Spec.Roles})
+		r.Recorder.Eventf(user, corev1.EventTypeNormal, "MembershipRevoked",
+			"%s membership in organization %s because the user is %s", r.Config.Action, organizationName, strings.ToLower(reason))
+		changed = true
+	}
+
+	if !changed {
+		return ctrl.Result{}, nil
+	}
+	value, err := json.Marshal(audit)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to encode offboarding record: %w", err)
+	}
+	patch := client.MergeFrom(user.DeepCopy())
+	metav1.SetMetaDataAnnotation(&user.ObjectMeta, OffboardingRecordAnnotation, string(value))
+	if err := r.Client.Patch(ctx, user, patch); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to record offboarding: %w", err)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// offboardingReason returns why the user should be offboarded, or an empty
+// string when the user is in good standing.
+func offboardingReason(user *iamv1alpha1.User) string {
+	switch {
+	case user.Status.RegistrationApproval == iamv1alpha1.RegistrationApprovalStateRejected:
+		return "Rejected"
+	case user.Status.State == iamv1alpha1.UserStateInactive:
+		return "Inactive"
+	default:
+		return ""
+	}
+}
+
+// lastOwner reports whether the membership is the last membership of its
+// organization holding an owner role.
+func (r *UserOffboardingController) lastOwner(ctx context.Context, graph *roles.Graph, membership *resourcemanagerv1alpha1.OrganizationMembership) (bool, error) {
+	if !graph.Grants(membership, r.Config.OwnerRoles) {
+		return false, nil
+	}
+
+	memberships := &resourcemanagerv1alpha1.OrganizationMembershipList{}
+	if err := r.Client.List(ctx, memberships, client.InNamespace(membership.Namespace)); err != nil {
+		return false, fmt.Errorf("failed to list organization memberships: %w", err)
+	}
+	for i := range memberships.Items {
+		other := &memberships.Items[i]
+		if other.Spec.UserRef.Name == membership.Spec.UserRef.Name || !other.DeletionTimestamp.IsZero() ||
+			metav1.HasAnnotation(other.ObjectMeta, SuspendedRolesAnnotation) {
+			continue
+		}
+		if graph.Grants(other, r.Config.OwnerRoles) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// revoke suspends or removes the membership.
+func (r *UserOffboardingController) revoke(ctx context.Context, membership *resourcemanagerv1alpha1.OrganizationMembership) error {
+	if r.Config.Action == OffboardingActionRemove {
+		if err := r.Client.Delete(ctx, membership); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to remove organization membership: %w", err)
+		}
+		return nil
+	}
+
+	roles, err := json.Marshal(membership.Spec.Roles)
+	if err != nil {
+		return fmt.Errorf("failed to encode membership roles: %w", err)
+	}
+	suspended := membership.DeepCopy()
+	metav1.SetMetaDataAnnotation(&suspended.ObjectMeta, SuspendedRolesAnnotation, string(roles))
+	suspended.Spec.Roles = nil
+	if err := r.Client.Update(ctx, suspended); err != nil {
+		return fmt.Errorf("failed to suspend organization membership: %w", err)
+	}
+	return nil
+}
+
+// reactivate restores the suspended memberships of a user that is back in
+// good standing, and clears their offboarding record.
+func (r *UserOffboardingController) reactivate(ctx context.Context, user *iamv1alpha1.User, memberships []resourcemanagerv1alpha1.OrganizationMembership) error {
+	logger := logf.FromContext(ctx)
+
+	for i := range memberships {
+		membership := &memberships[i]
+		value, ok := membership.Annotations[SuspendedRolesAnnotation]
+		if !ok {
+			continue
+		}
+		var roles []resourcemanagerv1alpha1.RoleReference
+		if err := json.Unmarshal([]byte(value), &roles); err != nil {
+			logger.Error(err, "Ignoring invalid suspended roles", "membership", client.ObjectKeyFromObject(membership))
+		}
+		membership.Spec.Roles = mergeRoles(membership.Spec.Roles, roles)
+		delete(membership.Annotations, SuspendedRolesAnnotation)
+		if err := r.Client.Update(ctx, membership); err != nil {
+			return fmt.Errorf("failed to restore organization membership: %w", err)
+		}
+		logger.Info("Restored organization membership of reactivated user", "user", user.Name, "organization", membership.Spec.OrganizationRef.Name)
+	}
+
+	patch := client.MergeFrom(user.DeepCopy())
+	delete(user.Annotations, OffboardingRecordAnnotation)
+	if err := r.Client.Patch(ctx, user, patch); err != nil {
+		return fmt.Errorf("failed to clear offboarding record: %w", err)
+	}
+	r.Recorder.Event(user, corev1.EventTypeNormal, "UserReactivated", "Suspended organization memberships were restored")
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *UserOffboardingController) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &resourcemanagerv1alpha1.OrganizationMembership{}, membershipUserIndex, membershipUser); err != nil {
+		return err
+	}
+
+	return r.Shards.For(ctrl.NewControllerManagedBy(mgr), &iamv1alpha1.User{}).
+		Named("user-offboarding").
+		WithOptions(r.Shards.Options(r.Options)).
+		Complete(tracing.Reconciler("user-offboarding", r.Shards.Reconciler(r)))
+}
+
+func membershipUser(obj client.Object) []string {
+	membership, ok := obj.(*resourcemanagerv1alpha1.OrganizationMembership)
+	if !ok || membership.Spec.UserRef.Name == "" {
+		return nil
+	}
+	return []string{membership.Spec.UserRef.Name}
+}
@@ -0,0 +1,188 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package resourcemanager
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	iamv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
+	resourcemanagerv1alpha1 "go.miloapis.com/milo/pkg/apis/resourcemanager/v1alpha1"
+)
+
+func TestUserOffboardingController(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iamv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := resourcemanagerv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	ownerRole := resourcemanagerv1alpha1.RoleReference{Name: "owner", Namespace: "datum-cloud"}
+	adminRole := resourcemanagerv1alpha1.RoleReference{Name: "admin", Namespace: "datum-cloud"}
+	viewerRole := resourcemanagerv1alpha1.RoleReference{Name: "viewer", Namespace: "datum-cloud"}
+
+	newMembership := func(name, user string, created time.Time, roles ...resourcemanagerv1alpha1.RoleReference) *resourcemanagerv1alpha1.OrganizationMembership {
+		return &resourcemanagerv1alpha1.OrganizationMembership{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "organization-acme", CreationTimestamp: metav1.NewTime(created)},
+			Spec: resourcemanagerv1alpha1.OrganizationMembershipSpec{
+				OrganizationRef: resourcemanagerv1alpha1.OrganizationReference{Name: "acme"},
+				UserRef:         resourcemanagerv1alpha1.MemberReference{Name: user},
+				Roles:           roles,
+			},
+		}
+	}
+	newObjects := func(secondRole resourcemanagerv1alpha1.RoleReference) []client.Object {
+		now := time.Now()
+		return []client.Object{
+			&iamv1alpha1.Role{ObjectMeta: metav1.ObjectMeta{Name: "owner", Namespace: "datum-cloud"}},
+			&iamv1alpha1.Role{
+				ObjectMeta: metav1.ObjectMeta{Name: "admin", Namespace: "datum-cloud"},
+				Spec:       iamv1alpha1.RoleSpec{InheritedRoles: []iamv1alpha1.ScopedRoleReference{{Name: "owner"}}},
+			},
+			&resourcemanagerv1alpha1.Organization{
+				ObjectMeta: metav1.ObjectMeta{Name: "acme"},
+				Spec:       resourcemanagerv1alpha1.OrganizationSpec{Type: "Standard"},
+			},
+			&iamv1alpha1.User{
+				ObjectMeta: metav1.ObjectMeta{Name: "leaver"},
+				Status:     iamv1alpha1.UserStatus{State: iamv1alpha1.UserStateInactive},
+			},
+			newMembership("leaver", "leaver", now.Add(-3*time.Hour), ownerRole),
+			newMembership("first", "user-1", now.Add(-2*time.Hour), viewerRole),
+			newMembership("second", "user-2", now.Add(-time.Hour), secondRole),
+		}
+	}
+	getMembership := func(t *testing.T, c client.Client, name string) *resourcemanagerv1alpha1.OrganizationMembership {
+		t.Helper()
+		m := &resourcemanagerv1alpha1.OrganizationMembership{}
+		if err := c.Get(context.Background(), client.ObjectKey{Namespace: "organization-acme", Name: name}, m); err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+
+	t.Run("suspends memberships", func(t *testing.T) {
+		c := fake.NewClientBuilder().WithScheme(scheme).
+			WithObjects(newObjects(adminRole)...).
+			WithIndex(&resourcemanagerv1alpha1.OrganizationMembership{}, membershipUserIndex, membershipUser).
+			Build()
+		r := &UserOffboardingController{
+			Client:   c,
+			Config:   UserOffboardingControllerConfig{Action: OffboardingActionSuspend, OwnerRoles: []resourcemanagerv1alpha1.RoleReference{ownerRole}},
+			Recorder: record.NewFakeRecorder(10),
+		}
+
+		if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKey{Name: "leaver"}}); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+
+		suspended := getMembership(t, c, "leaver")
+		if len(suspended.Spec.Roles) != 0 || !metav1.HasAnnotation(suspended.ObjectMeta, SuspendedRolesAnnotation) {
+			t.Errorf("expected membership to be suspended, got roles %v and annotations %v", suspended.Spec.Roles, suspended.Annotations)
+		}
+		if roles := getMembership(t, c, "first").Spec.Roles; len(roles) != 1 {
+			t.Errorf("expected other members to be unchanged, got roles %v", roles)
+		}
+
+		user := &iamv1alpha1.User{}
+		if err := c.Get(context.Background(), client.ObjectKey{Name: "leaver"}, user); err != nil {
+			t.Fatal(err)
+		}
+		audit := OffboardingRecord{}
+		if err := json.Unmarshal([]byte(user.Annotations[OffboardingRecordAnnotation]), &audit); err != nil {
+			t.Fatalf("failed to decode offboarding record: %v", err)
+		}
+		if audit.Reason != "Inactive" || len(audit.Memberships) != 1 || len(audit.OwnedOrganizations) != 0 {
+			t.Errorf("unexpected offboarding record %+v", audit)
+		}
+
+		// Reactivating the user restores the suspended membership.
+		user.Status.State = iamv1alpha1.UserStateActive
+		if err := c.Update(context.Background(), user); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKey{Name: "leaver"}}); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+		restored := getMembership(t, c, "leaver")
+		if len(restored.Spec.Roles) != 1 || restored.Spec.Roles[0] != ownerRole || metav1.HasAnnotation(restored.ObjectMeta, SuspendedRolesAnnotation) {
+			t.Errorf("expected membership to be restored, got roles %v and annotations %v", restored.Spec.Roles, restored.Annotations)
+		}
+	})
+
+	t.Run("leaves the membership of the last owner", func(t *testing.T) {
+		c := fake.NewClientBuilder().WithScheme(scheme).
+			WithObjects(newObjects(viewerRole)...).
+			WithIndex(&resourcemanagerv1alpha1.OrganizationMembership{}, membershipUserIndex, membershipUser).
+			Build()
+		recorder := record.NewFakeRecorder(10)
+		r := &UserOffboardingController{
+			Client:   c,
+			Config:   UserOffboardingControllerConfig{Action: OffboardingActionRemove, OwnerRoles: []resourcemanagerv1alpha1.RoleReference{ownerRole}},
+			Recorder: recorder,
+		}
+
+		for range 2 {
+			if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKey{Name: "leaver"}}); err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+		}
+
+		if roles := getMembership(t, c, "leaver").Spec.Roles; len(roles) != 1 || roles[0] != ownerRole {
+			t.Errorf("expected membership of the last owner to be left in place, got roles %v", roles)
+		}
+		for _, name := range []string{"first", "second"} {
+			if roles := getMembership(t, c, name).Spec.Roles; len(roles) != 1 || roles[0] != viewerRole {
+				t.Errorf("expected member %s not to be promoted, got roles %v", name, roles)
+			}
+		}
+
+		user := &iamv1alpha1.User{}
+		if err := c.Get(context.Background(), client.ObjectKey{Name: "leaver"}, user); err != nil {
+			t.Fatal(err)
+		}
+		audit := OffboardingRecord{}
+		if err := json.Unmarshal([]byte(user.Annotations[OffboardingRecordAnnotation]), &audit); err != nil {
+			t.Fatalf("failed to decode offboarding record: %v", err)
+		}
+		if len(audit.Memberships) != 0 || len(audit.OwnedOrganizations) != 1 || audit.OwnedOrganizations[0] != "acme" {
+			t.Errorf("unexpected offboarding record %+v", audit)
+		}
+		if len(recorder.Events) != 1 {
+			t.Errorf("expected a single warning event, got %d events", len(recorder.Events))
+		}
+	})
+
+	t.Run("removes memberships", func(t *testing.T) {
+		c := fake.NewClientBuilder().WithScheme(scheme).
+			WithObjects(newObjects(adminRole)...).
+			WithIndex(&resourcemanagerv1alpha1.OrganizationMembership{}, membershipUserIndex, membershipUser).
+			Build()
+		r := &UserOffboardingController{
+			Client:   c,
+			Config:   UserOffboardingControllerConfig{Action: OffboardingActionRemove, OwnerRoles: []resourcemanagerv1alpha1.RoleReference{ownerRole}},
+			Recorder: record.NewFakeRecorder(10),
+		}
+
+		if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKey{Name: "leaver"}}); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+
+		err := c.Get(context.Background(), client.ObjectKey{Namespace: "organization-acme", Name: "leaver"}, &resourcemanagerv1alpha1.OrganizationMembership{})
+		if !apierrors.IsNotFound(err) {
+			t.Errorf("expected membership to be removed, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,195 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package resourcemanager
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	iamv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
+	resourcemanagerv1alpha1 "go.miloapis.com/milo/pkg/apis/resourcemanager/v1alpha1"
+
+	"go.datum.net/datum/internal/sharding"
+	"go.datum.net/datum/internal/tracing"
+)
+
+const (
+	// OnboardingQueueLabel is set to OnboardingQueueReview on pending users
+	// that did not match any onboarding rule and need to be reviewed by staff.
+	OnboardingQueueLabel = "iam.datumapis.com/onboarding-queue"
+
+	OnboardingQueueReview = "review"
+
+	// OnboardingRuleAnnotation records the name of the onboarding rule a user
+	// matched.
+	OnboardingRuleAnnotation = "iam.datumapis.com/onboarding-rule"
+)
+
+// +kubebuilder:object:generate=true
+
+type UserOnboardingControllerConfig struct {
+	// Rules are matched against the email domain of new users, in order. The
+	// first matching rule applies. Users that do not match any rule are added
+	// to the review queue.
+	Rules []OnboardingRule `json:"rules,omitempty"`
+}
+
+// +kubebuilder:object:generate=true
+
+// OnboardingRule onboards the users of a set of email domains.
+type OnboardingRule struct {
+	// Name identifies the rule in annotations, logs and Events.
+	Name string `json:"name"`
+
+	// Domains are the email domains the rule matches, such as example.com. A
+	// domain starting with "*." matches all of its subdomains.
+	Domains []string `json:"domains"`
+
+	// AutoApprove approves the registration of matching users. Matching users
+	// that are not approved automatically are released by the
+	// UserWaitlistController when it is enabled, and otherwise wait to be
+	// approved by staff.
+	AutoApprove bool `json:"autoApprove"`
+
+	// Organization is the organization approved users are added to, if any.
+	Organization string `json:"organization,omitempty"`
+
+	// Roles are assigned to users added to the organization. Defaults to the
+	// datum-cloud viewer role.
+	Roles []resourcemanagerv1alpha1.RoleReference `json:"roles,omitempty"`
+}
+
+// UserOnboardingController drives the registration of new users with the
+// onboarding rules configured for the environment. Users whose email domain
+// matches a rule can be approved automatically and added to an organization,
+// all other pending users are labeled for review.
+type UserOnboardingController struct {
+	Client client.Client
+
+	Config UserOnboardingControllerConfig
+
+	Recorder record.EventRecorder
+
+	// Shards restricts the controller to the Users of the shards owned by the
+	// replica. When nil, the controller reconciles every User.
+	Shards *sharding.Coordinator
+
+	// Options configures the workers and rate limiting of the controller.
+	Options controller.Options
+}
+
+// +kubebuilder:rbac:groups=iam.miloapis.com,resources=users,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=iam.miloapis.com,resources=users/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=resourcemanager.miloapis.com,resources=organizations,verbs=get;list;watch
+// +kubebuilder:rbac:groups=resourcemanager.miloapis.com,resources=organizationmemberships,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
+// Reconcile applies the onboarding rules to a user.
+func (r *UserOnboardingController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := logf.FromContext(ctx)
+
+	user := &iamv1alpha1.User{}
+	if err := r.Client.Get(ctx, req.NamespacedName, user); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, fmt.Errorf("failed to get user: %w", err)
+	}
+	if !user.DeletionTimestamp.IsZero() || user.Status.RegistrationApproval == iamv1alpha1.RegistrationApprovalStateRejected {
+		return ctrl.Result{}, nil
+	}
+
+	rule := matchOnboardingRule(r.Config.Rules, user.Spec.Email)
+	pending := user.Status.RegistrationApproval != iamv1alpha1.RegistrationApprovalStateApproved
+
+	patch := client.MergeFrom(user.DeepCopy())
+	if rule == nil {
+		if !pending || user.Labels[OnboardingQueueLabel] == OnboardingQueueReview {
+			return ctrl.Result{}, nil
+		}
+		metav1.SetMetaDataLabel(&user.ObjectMeta, OnboardingQueueLabel, OnboardingQueueReview)
+		if err := r.Client.Patch(ctx, user, patch); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to add user to the review queue: %w", err)
+		}
+		logger.Info("User did not match any onboarding rule, added to the review queue", "user", user.Name)
+		return ctrl.Result{}, nil
+	}
+
+	if user.Labels[OnboardingQueueLabel] != "" || user.Annotations[OnboardingRuleAnnotation] != rule.Name {
+		delete(user.Labels, OnboardingQueueLabel)
+		metav1.SetMetaDataAnnotation(&user.ObjectMeta, OnboardingRuleAnnotation, rule.Name)
+		if err := r.Client.Patch(ctx, user, patch); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to record onboarding rule: %w", err)
+		}
+	}
+
+	if pending && rule.AutoApprove {
+		user.Status.RegistrationApproval = iamv1alpha1.RegistrationApprovalStateApproved
+		if err := r.Client.Status().Update(ctx, user); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to approve user: %w", err)
+		}
+		logger.Info("Approved user by onboarding rule", "user", user.Name, "rule", rule.Name)
+		r.Recorder.Eventf(user, corev1.EventTypeNormal, "AutoApproved", "Registration approved by onboarding rule %s", rule.Name)
+		pending = false
+	}
+
+	// Memberships are only created for approved users, so users waiting for
+	// approval do not gain access to the organization.
+	if pending || rule.Organization == "" {
+		return ctrl.Result{}, nil
+	}
+	organization := &resourcemanagerv1alpha1.Organization{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Name: rule.Organization}, organization); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.Recorder.Eventf(user, corev1.EventTypeWarning, "OrganizationNotFound",
+				"Organization %s of onboarding rule %s does not exist", rule.Organization, rule.Name)
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, fmt.Errorf("failed to get organization: %w", err)
+	}
+	if err := ensureMembership(ctx, r.Client, organization.Name, user, rule.Roles); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// matchOnboardingRule returns the first rule matching the domain of the email,
+// or nil when no rule matches.
+func matchOnboardingRule(rules []OnboardingRule, email string) *OnboardingRule {
+	_, domain, ok := strings.Cut(strings.ToLower(email), "@")
+	if !ok || domain == "" {
+		return nil
+	}
+	for i := range rules {
+		for _, pattern := range rules[i].Domains {
+			pattern = strings.ToLower(pattern)
+			if suffix, wildcard := strings.CutPrefix(pattern, "*."); wildcard {
+				if strings.HasSuffix(domain, "."+suffix) {
+					return &rules[i]
+				}
+			} else if domain == pattern {
+				return &rules[i]
+			}
+		}
+	}
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *UserOnboardingController) SetupWithManager(mgr ctrl.Manager) error {
+	return r.Shards.For(ctrl.NewControllerManagedBy(mgr), &iamv1alpha1.User{}).
+		Named("user-onboarding").
+		WithOptions(r.Shards.Options(r.Options)).
+		Complete(tracing.Reconciler("user-onboarding", r.Shards.Reconciler(r)))
+}
@@ -0,0 +1,140 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package resourcemanager
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	iamv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
+	resourcemanagerv1alpha1 "go.miloapis.com/milo/pkg/apis/resourcemanager/v1alpha1"
+)
+
+func TestUserOnboardingController(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iamv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := resourcemanagerv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	viewerRole := resourcemanagerv1alpha1.RoleReference{Name: "viewer", Namespace: "datum-cloud"}
+	config := UserOnboardingControllerConfig{
+		Rules: []OnboardingRule{
+			{
+				Name:         "staff",
+				Domains:      []string{"datum.net", "*.datum.net"},
+				AutoApprove:  true,
+				Organization: "datum",
+				Roles:        []resourcemanagerv1alpha1.RoleReference{viewerRole},
+			},
+			{
+				Name:    "partners",
+				Domains: []string{"partner.example"},
+			},
+		},
+	}
+
+	newUser := func(name, email string) *iamv1alpha1.User {
+		return &iamv1alpha1.User{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       iamv1alpha1.UserSpec{Email: email},
+			Status:     iamv1alpha1.UserStatus{RegistrationApproval: iamv1alpha1.RegistrationApprovalStatePending},
+		}
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(
+			&resourcemanagerv1alpha1.Organization{ObjectMeta: metav1.ObjectMeta{Name: "datum"}},
+			newUser("staff", "Alice@Eng.Datum.net"),
+			newUser("partner", "bob@partner.example"),
+			newUser("unknown", "eve@example.com"),
+		).
+		WithStatusSubresource(&iamv1alpha1.User{}).
+		Build()
+	r := &UserOnboardingController{Client: c, Config: config, Recorder: record.NewFakeRecorder(10)}
+	reconcile := func(name string) *iamv1alpha1.User {
+		t.Helper()
+		if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKey{Name: name}}); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+		user := &iamv1alpha1.User{}
+		if err := c.Get(context.Background(), client.ObjectKey{Name: name}, user); err != nil {
+			t.Fatal(err)
+		}
+		return user
+	}
+
+	t.Run("approves matching users and adds them to the organization", func(t *testing.T) {
+		user := reconcile("staff")
+		if user.Status.RegistrationApproval != iamv1alpha1.RegistrationApprovalStateApproved {
+			t.Errorf("registration approval = %s, want Approved", user.Status.RegistrationApproval)
+		}
+		if user.Annotations[OnboardingRuleAnnotation] != "staff" {
+			t.Errorf("onboarding rule = %q, want staff", user.Annotations[OnboardingRuleAnnotation])
+		}
+
+		memberships := &resourcemanagerv1alpha1.OrganizationMembershipList{}
+		if err := c.List(context.Background(), memberships, client.InNamespace("organization-datum")); err != nil {
+			t.Fatal(err)
+		}
+		if len(memberships.Items) != 1 || memberships.Items[0].Spec.UserRef.Name != "staff" {
+			t.Fatalf("expected a membership for the staff user, got %+v", memberships.Items)
+		}
+		if roles := memberships.Items[0].Spec.Roles; len(roles) != 1 || roles[0] != viewerRole {
+			t.Errorf("membership roles = %v, want %v", roles, viewerRole)
+		}
+	})
+
+	t.Run("leaves matching users pending without auto approval", func(t *testing.T) {
+		user := reconcile("partner")
+		if user.Status.RegistrationApproval != iamv1alpha1.RegistrationApprovalStatePending {
+			t.Errorf("registration approval = %s, want Pending", user.Status.RegistrationApproval)
+		}
+		if _, ok := user.Labels[OnboardingQueueLabel]; ok {
+			t.Errorf("expected matching user not to be queued for review, got labels %v", user.Labels)
+		}
+	})
+
+	t.Run("queues unmatched users for review", func(t *testing.T) {
+		user := reconcile("unknown")
+		if user.Status.RegistrationApproval != iamv1alpha1.RegistrationApprovalStatePending {
+			t.Errorf("registration approval = %s, want Pending", user.Status.RegistrationApproval)
+		}
+		if user.Labels[OnboardingQueueLabel] != OnboardingQueueReview {
+			t.Errorf("expected user to be queued for review, got labels %v", user.Labels)
+		}
+	})
+}
+
+func TestMatchOnboardingRule(t *testing.T) {
+	rules := []OnboardingRule{
+		{Name: "exact", Domains: []string{"example.com"}},
+		{Name: "wildcard", Domains: []string{"*.example.com"}},
+	}
+	tests := map[string]string{
+		"a@example.com":      "exact",
+		"a@EXAMPLE.com":      "exact",
+		"a@eu.example.com":   "wildcard",
+		"a@notexample.com":   "",
+		"a@example.com.evil": "",
+		"invalid":            "",
+	}
+	for email, want := range tests {
+		got := ""
+		if rule := matchOnboardingRule(rules, email); rule != nil {
+			got = rule.Name
+		}
+		if got != want {
+			t.Errorf("matchOnboardingRule(%q) = %q, want %q", email, got, want)
+		}
+	}
+}
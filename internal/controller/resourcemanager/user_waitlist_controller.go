@@ -0,0 +1,371 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package resourcemanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	iamv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
+	resourcemanagerv1alpha1 "go.miloapis.com/milo/pkg/apis/resourcemanager/v1alpha1"
+
+	"go.datum.net/datum/internal/tracing"
+)
+
+const (
+	// WaitlistPriorityAnnotation sets the priority of a waitlisted user when
+	// the waitlist is released in priority order. Users with a higher priority
+	// are approved first. Defaults to 0.
+	WaitlistPriorityAnnotation = "iam.datumapis.com/waitlist-priority"
+
+	// WaitlistPositionAnnotation is set by the controller to the 1-based
+	// position of a pending user near the front of the waitlist.
+	WaitlistPositionAnnotation = "iam.datumapis.com/waitlist-position"
+
+	// WaitlistReleasedAnnotation records when the controller approved a user.
+	// It is removed once the user's resources have been provisioned.
+	WaitlistReleasedAnnotation = "iam.datumapis.com/waitlist-released-at"
+)
+
+// WaitlistOrder is the order in which waitlisted users are approved.
+type WaitlistOrder string
+
+const (
+	// WaitlistOrderFIFO approves users in the order they signed up.
+	WaitlistOrderFIFO WaitlistOrder = "FIFO"
+
+	// WaitlistOrderPriority approves users by descending priority, and in the
+	// order they signed up within the same priority.
+	WaitlistOrderPriority WaitlistOrder = "Priority"
+)
+
+var (
+	waitlistDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "datum_user_waitlist_depth",
+		Help: "Number of pending users in the waitlist.",
+	})
+	waitlistPaused = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "datum_user_waitlist_paused",
+		Help: "Whether releasing the waitlist is paused because of provisioning failures.",
+	})
+	waitlistProvisioningFailures = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "datum_user_waitlist_provisioning_failures",
+		Help: "Number of released users whose resources were not provisioned within the provisioning timeout.",
+	})
+	waitlistReleased = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "datum_user_waitlist_released_total",
+		Help: "Number of users approved from the waitlist.",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(waitlistDepth, waitlistPaused, waitlistProvisioningFailures, waitlistReleased)
+}
+
+// waitlistRequest is the single request the waitlist is reconciled with, as
+// releasing users depends on the state of the whole waitlist.
+var waitlistRequest = reconcile.Request{NamespacedName: types.NamespacedName{Name: "waitlist"}}
+
+// +kubebuilder:object:generate=true
+
+type UserWaitlistControllerConfig struct {
+	// Order is the order users are approved in, FIFO or Priority. Defaults to
+	// FIFO.
+	Order WaitlistOrder `json:"order"`
+
+	// BatchSize is the number of users approved at once. Defaults to 10.
+	BatchSize int `json:"batchSize"`
+
+	// Interval is the minimum time between two batches. Defaults to 1m.
+	Interval metav1.Duration `json:"interval"`
+
+	// ProvisioningTimeout is how long the resources of an approved user may
+	// take to be provisioned before counting as a provisioning failure.
+	// Defaults to 10m.
+	ProvisioningTimeout metav1.Duration `json:"provisioningTimeout"`
+
+	// MaxProvisioningFailures pauses releasing the waitlist while at least this
+	// many approved users have not been provisioned within the provisioning
+	// timeout. Releasing resumes once their resources are provisioned, or
+	// their WaitlistReleasedAnnotation is removed. Defaults to 5.
+	MaxProvisioningFailures int `json:"maxProvisioningFailures"`
+
+	// MaxPositions is the number of users at the front of the waitlist that
+	// are annotated with their position, so releasing a batch does not patch
+	// every waitlisted user. The length of the whole waitlist is reported by
+	// the datum_user_waitlist_depth metric. Defaults to 100.
+	MaxPositions int `json:"maxPositions"`
+}
+
+// UserWaitlistController releases waitlisted users in batches. Pending users
+// are approved in FIFO or priority order, at most BatchSize at a time and one
+// batch per Interval, so the organizations, memberships and projects created
+// for new users do not overload the control plane. Users near the front of the
+// waitlist are annotated with their position.
+type UserWaitlistController struct {
+	Client client.Client
+
+	Config UserWaitlistControllerConfig
+
+	// SkipProvisioningCheck disables pausing on provisioning failures, for
+	// environments where personal projects are not created for new users.
+	SkipProvisioningCheck bool
+
+	// Onboarding is set when the UserOnboardingController runs. Pending users
+	// are then only waitlisted once they matched an onboarding rule, and users
+	// it queues for review are left to staff. Without any onboarding rule,
+	// every user is queued for review and nobody is released. When unset,
+	// every pending user is waitlisted.
+	Onboarding bool
+
+	// Options configures the workers and rate limiting of the controller.
+	Options controller.Options
+
+	// lastRelease is when the last batch was approved. Requests for the
+	// waitlist are never processed concurrently.
+	lastRelease time.Time
+}
+
+// +kubebuilder:rbac:groups=iam.miloapis.com,resources=users,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=iam.miloapis.com,resources=users/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=resourcemanager.miloapis.com,resources=projects,verbs=get;list;watch
+
+// Reconcile approves the next batch of waitlisted users when it is due.
+func (r *UserWaitlistController) Reconcile(ctx context.Context, _ ctrl.Request) (ctrl.Result, error) {
+	logger := logf.FromContext(ctx)
+
+	users := &iamv1alpha1.UserList{}
+	if err := r.Client.List(ctx, users); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	now := time.Now()
+	var waitlist []*iamv1alpha1.User
+	failures := 0
+	for i := range users.Items {
+		user := &users.Items[i]
+		if !user.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if user.Status.RegistrationApproval == iamv1alpha1.RegistrationApprovalStateApproved {
+			failed, err := r.checkProvisioning(ctx, user, now)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			if failed {
+				failures++
+			}
+			continue
+		}
+		if r.waitlisted(user) {
+			waitlist = append(waitlist, user)
+		}
+	}
+	r.sortWaitlist(waitlist)
+
+	paused := failures >= r.Config.MaxProvisioningFailures
+	waitlistProvisioningFailures.Set(float64(failures))
+	waitlistPaused.Set(boolToFloat(paused))
+
+	var requeueAfter time.Duration
+	switch {
+	case len(waitlist) == 0:
+	case paused:
+		logger.Info("Waitlist paused because of provisioning failures", "failures", failures, "waitlisted", len(waitlist))
+		requeueAfter = r.Config.Interval.Duration
+	case now.Before(r.lastRelease.Add(r.Config.Interval.Duration)):
+		requeueAfter = r.lastRelease.Add(r.Config.Interval.Duration).Sub(now)
+	default:
+		batch := waitlist[:min(r.Config.BatchSize, len(waitlist))]
+		for _, user := range batch {
+			if err := r.release(ctx, user, now); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		r.lastRelease = now
+		waitlistReleased.Add(float64(len(batch)))
+		logger.Info("Released users from the waitlist", "released", len(batch), "waitlisted", len(waitlist)-len(batch))
+		waitlist = waitlist[len(batch):]
+		if len(waitlist) > 0 {
+			requeueAfter = r.Config.Interval.Duration
+		}
+	}
+
+	waitlistDepth.Set(float64(len(waitlist)))
+	if err := r.updatePositions(ctx, waitlist); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// waitlisted reports whether a user that has not been approved is waiting in
+// the waitlist. With onboarding, only users handled by the
+// UserOnboardingController are waitlisted: users that matched an onboarding
+// rule without being approved automatically, or that were queued elsewhere than
+// for review. Users that were not handled yet may still be queued for review.
+func (r *UserWaitlistController) waitlisted(user *iamv1alpha1.User) bool {
+	if user.Status.RegistrationApproval == iamv1alpha1.RegistrationApprovalStateRejected {
+		return false
+	}
+	switch queue := user.Labels[OnboardingQueueLabel]; queue {
+	case OnboardingQueueReview:
+		return false
+	case "":
+		return !r.Onboarding || metav1.HasAnnotation(user.ObjectMeta, OnboardingRuleAnnotation)
+	default:
+		return true
+	}
+}
+
+// sortWaitlist sorts users in the order they are released.
+func (r *UserWaitlistController) sortWaitlist(users []*iamv1alpha1.User) {
+	slices.SortStableFunc(users, func(a, b *iamv1alpha1.User) int {
+		if r.Config.Order == WaitlistOrderPriority {
+			if c := waitlistPriority(b) - waitlistPriority(a); c != 0 {
+				return c
+			}
+		}
+		if c := a.CreationTimestamp.Compare(b.CreationTimestamp.Time); c != 0 {
+			return c
+		}
+		if a.Name < b.Name {
+			return -1
+		}
+		if a.Name > b.Name {
+			return 1
+		}
+		return 0
+	})
+}
+
+// waitlistPriority returns the priority of a user. Invalid priorities are
+// treated as the default priority.
+func waitlistPriority(user *iamv1alpha1.User) int {
+	priority, err := strconv.Atoi(user.Annotations[WaitlistPriorityAnnotation])
+	if err != nil {
+		return 0
+	}
+	return priority
+}
+
+// release approves a waitlisted user.
+func (r *UserWaitlistController) release(ctx context.Context, user *iamv1alpha1.User, now time.Time) error {
+	patch := client.MergeFrom(user.DeepCopy())
+	delete(user.Annotations, WaitlistPositionAnnotation)
+	if !r.SkipProvisioningCheck {
+		metav1.SetMetaDataAnnotation(&user.ObjectMeta, WaitlistReleasedAnnotation, now.UTC().Format(time.RFC3339))
+	}
+	if err := r.Client.Patch(ctx, user, patch); err != nil {
+		return fmt.Errorf("failed to annotate released user: %w", err)
+	}
+
+	user.Status.RegistrationApproval = iamv1alpha1.RegistrationApprovalStateApproved
+	if err := r.Client.Status().Update(ctx, user); err != nil {
+		return fmt.Errorf("failed to approve user: %w", err)
+	}
+	return nil
+}
+
+// checkProvisioning reports whether the resources of a user released from the
+// waitlist failed to be provisioned within the provisioning timeout. Released
+// users whose personal project exists are no longer tracked.
+func (r *UserWaitlistController) checkProvisioning(ctx context.Context, user *iamv1alpha1.User, now time.Time) (bool, error) {
+	value, ok := user.Annotations[WaitlistReleasedAnnotation]
+	if !ok || r.SkipProvisioningCheck {
+		return false, nil
+	}
+
+	project := &resourcemanagerv1alpha1.Project{}
+	err := r.Client.Get(ctx, client.ObjectKey{Name: PersonalProjectName(user.UID)}, project)
+	switch {
+	case err == nil:
+		patch := client.MergeFrom(user.DeepCopy())
+		delete(user.Annotations, WaitlistReleasedAnnotation)
+		if err := r.Client.Patch(ctx, user, patch); err != nil {
+			return false, fmt.Errorf("failed to mark user as provisioned: %w", err)
+		}
+		return false, nil
+	case !apierrors.IsNotFound(err):
+		return false, fmt.Errorf("failed to get personal project: %w", err)
+	}
+
+	released, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		// An invalid timestamp cannot be expired, so count it right away to
+		// surface it.
+		return true, nil
+	}
+	return now.After(released.Add(r.Config.ProvisioningTimeout.Duration)), nil
+}
+
+// updatePositions annotates the users at the front of the waitlist with their
+// position, and removes the position of users further back. Users are only
+// patched when their position changed.
+func (r *UserWaitlistController) updatePositions(ctx context.Context, waitlist []*iamv1alpha1.User) error {
+	var errs []error
+	for i, user := range waitlist {
+		var position string
+		if i < r.Config.MaxPositions {
+			position = strconv.Itoa(i + 1)
+		}
+		if user.Annotations[WaitlistPositionAnnotation] == position {
+			continue
+		}
+		patch := client.MergeFrom(user.DeepCopy())
+		if position == "" {
+			delete(user.Annotations, WaitlistPositionAnnotation)
+		} else {
+			metav1.SetMetaDataAnnotation(&user.ObjectMeta, WaitlistPositionAnnotation, position)
+		}
+		if err := r.Client.Patch(ctx, user, patch); err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("failed to update waitlist position of user %s: %w", user.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *UserWaitlistController) SetupWithManager(mgr ctrl.Manager) error {
+	enqueueWaitlist := handler.EnqueueRequestsFromMapFunc(func(context.Context, client.Object) []reconcile.Request {
+		return []reconcile.Request{waitlistRequest}
+	})
+	// Only personal projects mark users as provisioned.
+	enqueuePersonalProject := handler.EnqueueRequestsFromMapFunc(func(_ context.Context, obj client.Object) []reconcile.Request {
+		if !strings.HasPrefix(obj.GetName(), "personal-project-") {
+			return nil
+		}
+		return []reconcile.Request{waitlistRequest}
+	})
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("user-waitlist").
+		Watches(&iamv1alpha1.User{}, enqueueWaitlist).
+		Watches(&resourcemanagerv1alpha1.Project{}, enqueuePersonalProject).
+		WithOptions(r.Options).
+		Complete(tracing.Reconciler("user-waitlist", r))
+}
@@ -0,0 +1,196 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package resourcemanager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	iamv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
+	resourcemanagerv1alpha1 "go.miloapis.com/milo/pkg/apis/resourcemanager/v1alpha1"
+)
+
+func TestUserWaitlistController(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iamv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := resourcemanagerv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	config := UserWaitlistControllerConfig{
+		Order:                   WaitlistOrderPriority,
+		BatchSize:               2,
+		Interval:                metav1.Duration{Duration: time.Hour},
+		ProvisioningTimeout:     metav1.Duration{Duration: 10 * time.Minute},
+		MaxProvisioningFailures: 1,
+		MaxPositions:            10,
+	}
+	signedUp := time.Now().Add(-time.Hour)
+	// Users are waitlisted once they matched an onboarding rule.
+	newUser := func(name string, order int, annotations map[string]string) *iamv1alpha1.User {
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[OnboardingRuleAnnotation] = "waitlist"
+		return &iamv1alpha1.User{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				UID:               types.UID(name),
+				CreationTimestamp: metav1.NewTime(signedUp.Add(time.Duration(order) * time.Minute)),
+				Annotations:       annotations,
+			},
+			Status: iamv1alpha1.UserStatus{RegistrationApproval: iamv1alpha1.RegistrationApprovalStatePending},
+		}
+	}
+	getUser := func(t *testing.T, c client.Client, name string) *iamv1alpha1.User {
+		t.Helper()
+		user := &iamv1alpha1.User{}
+		if err := c.Get(context.Background(), client.ObjectKey{Name: name}, user); err != nil {
+			t.Fatal(err)
+		}
+		return user
+	}
+
+	t.Run("releases batches in priority order", func(t *testing.T) {
+		review := newUser("review", 0, nil)
+		review.Labels = map[string]string{OnboardingQueueLabel: OnboardingQueueReview}
+		// Users that were not onboarded yet may still be queued for review.
+		unprocessed := newUser("unprocessed", 0, nil)
+		delete(unprocessed.Annotations, OnboardingRuleAnnotation)
+		c := fake.NewClientBuilder().WithScheme(scheme).
+			WithObjects(
+				review,
+				unprocessed,
+				newUser("first", 1, nil),
+				newUser("second", 2, nil),
+				newUser("third", 3, nil),
+				newUser("vip", 4, map[string]string{WaitlistPriorityAnnotation: "10"}),
+			).
+			WithStatusSubresource(&iamv1alpha1.User{}).
+			Build()
+		r := &UserWaitlistController{Client: c, Config: config, Onboarding: true}
+
+		result, err := r.Reconcile(context.Background(), waitlistRequest)
+		if err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+		if result.RequeueAfter != time.Hour {
+			t.Errorf("RequeueAfter = %s, want the batch interval", result.RequeueAfter)
+		}
+
+		for name, want := range map[string]iamv1alpha1.RegistrationApprovalState{
+			"vip":         iamv1alpha1.RegistrationApprovalStateApproved,
+			"first":       iamv1alpha1.RegistrationApprovalStateApproved,
+			"second":      iamv1alpha1.RegistrationApprovalStatePending,
+			"third":       iamv1alpha1.RegistrationApprovalStatePending,
+			"review":      iamv1alpha1.RegistrationApprovalStatePending,
+			"unprocessed": iamv1alpha1.RegistrationApprovalStatePending,
+		} {
+			if got := getUser(t, c, name).Status.RegistrationApproval; got != want {
+				t.Errorf("user %s registration approval = %s, want %s", name, got, want)
+			}
+		}
+		for name, want := range map[string]string{"second": "1", "third": "2", "review": "", "unprocessed": ""} {
+			if got := getUser(t, c, name).Annotations[WaitlistPositionAnnotation]; got != want {
+				t.Errorf("user %s waitlist position = %q, want %q", name, got, want)
+			}
+		}
+		if _, ok := getUser(t, c, "vip").Annotations[WaitlistReleasedAnnotation]; !ok {
+			t.Error("expected released user to be annotated with the release time")
+		}
+
+		// The next batch waits for the interval.
+		if _, err := r.Reconcile(context.Background(), ctrl.Request{}); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+		if got := getUser(t, c, "second").Status.RegistrationApproval; got != iamv1alpha1.RegistrationApprovalStatePending {
+			t.Errorf("expected next batch to wait for the interval, got %s", got)
+		}
+	})
+
+	t.Run("pauses on provisioning failures", func(t *testing.T) {
+		stuck := newUser("stuck", 0, map[string]string{
+			WaitlistReleasedAnnotation: time.Now().Add(-time.Hour).UTC().Format(time.RFC3339),
+		})
+		stuck.Status.RegistrationApproval = iamv1alpha1.RegistrationApprovalStateApproved
+		provisioned := newUser("provisioned", 1, map[string]string{
+			WaitlistReleasedAnnotation: time.Now().Add(-time.Hour).UTC().Format(time.RFC3339),
+		})
+		provisioned.Status.RegistrationApproval = iamv1alpha1.RegistrationApprovalStateApproved
+		c := fake.NewClientBuilder().WithScheme(scheme).
+			WithObjects(
+				stuck,
+				provisioned,
+				&resourcemanagerv1alpha1.Project{ObjectMeta: metav1.ObjectMeta{
+					Name: "personal-project-" + hashPersonalOrgName("provisioned"),
+				}},
+				newUser("waiting", 2, nil),
+			).
+			WithStatusSubresource(&iamv1alpha1.User{}).
+			Build()
+		r := &UserWaitlistController{Client: c, Config: config}
+
+		if _, err := r.Reconcile(context.Background(), waitlistRequest); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+		if got := getUser(t, c, "waiting").Status.RegistrationApproval; got != iamv1alpha1.RegistrationApprovalStatePending {
+			t.Errorf("expected waitlist to be paused, got registration approval %s", got)
+		}
+		if _, ok := getUser(t, c, "provisioned").Annotations[WaitlistReleasedAnnotation]; ok {
+			t.Error("expected provisioned user to no longer be tracked")
+		}
+
+		// Once the stuck user is provisioned, releasing resumes.
+		if err := c.Create(context.Background(), &resourcemanagerv1alpha1.Project{ObjectMeta: metav1.ObjectMeta{
+			Name: "personal-project-" + hashPersonalOrgName("stuck"),
+		}}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.Reconcile(context.Background(), waitlistRequest); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+		if got := getUser(t, c, "waiting").Status.RegistrationApproval; got != iamv1alpha1.RegistrationApprovalStateApproved {
+			t.Errorf("expected waitlist to resume, got registration approval %s", got)
+		}
+	})
+
+	t.Run("waitlists every pending user without onboarding", func(t *testing.T) {
+		unprocessed := newUser("unprocessed", 0, nil)
+		delete(unprocessed.Annotations, OnboardingRuleAnnotation)
+		c := fake.NewClientBuilder().WithScheme(scheme).
+			WithObjects(
+				unprocessed,
+				newUser("first", 1, nil),
+				newUser("second", 2, map[string]string{WaitlistPositionAnnotation: "3"}),
+				newUser("third", 3, map[string]string{WaitlistPositionAnnotation: "4"}),
+			).
+			WithStatusSubresource(&iamv1alpha1.User{}).
+			Build()
+		limited := config
+		limited.BatchSize = 1
+		limited.MaxPositions = 1
+		r := &UserWaitlistController{Client: c, Config: limited}
+
+		if _, err := r.Reconcile(context.Background(), waitlistRequest); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+		if got := getUser(t, c, "unprocessed").Status.RegistrationApproval; got != iamv1alpha1.RegistrationApprovalStateApproved {
+			t.Errorf("expected user not handled by onboarding to be released, got registration approval %s", got)
+		}
+		for name, want := range map[string]string{"first": "1", "second": "", "third": ""} {
+			if got := getUser(t, c, name).Annotations[WaitlistPositionAnnotation]; got != want {
+				t.Errorf("user %s waitlist position = %q, want %q", name, got, want)
+			}
+		}
+	})
+}
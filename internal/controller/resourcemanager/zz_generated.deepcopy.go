@@ -0,0 +1,229 @@
+//go:build !ignore_autogenerated
+
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Code generated by controller-gen. DO NOT EDIT.
+
+package resourcemanager
+
+import (
+	iamv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
+	"go.miloapis.com/milo/pkg/apis/resourcemanager/v1alpha1"
+)
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DefaultProjectConfig) DeepCopyInto(out *DefaultProjectConfig) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DefaultProjectConfig.
+func (in *DefaultProjectConfig) DeepCopy() *DefaultProjectConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(DefaultProjectConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OnboardingRule) DeepCopyInto(out *OnboardingRule) {
+	*out = *in
+	if in.Domains != nil {
+		in, out := &in.Domains, &out.Domains
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Roles != nil {
+		in, out := &in.Roles, &out.Roles
+		*out = make([]v1alpha1.RoleReference, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OnboardingRule.
+func (in *OnboardingRule) DeepCopy() *OnboardingRule {
+	if in == nil {
+		return nil
+	}
+	out := new(OnboardingRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OrganizationBootstrapControllerConfig) DeepCopyInto(out *OrganizationBootstrapControllerConfig) {
+	*out = *in
+	if in.OwnerRoles != nil {
+		in, out := &in.OwnerRoles, &out.OwnerRoles
+		*out = make([]v1alpha1.RoleReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.StarterGroups != nil {
+		in, out := &in.StarterGroups, &out.StarterGroups
+		*out = make([]StarterGroupConfig, len(*in))
+		copy(*out, *in)
+	}
+	in.DefaultProject.DeepCopyInto(&out.DefaultProject)
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	out.MaxOrganizationAge = in.MaxOrganizationAge
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OrganizationBootstrapControllerConfig.
+func (in *OrganizationBootstrapControllerConfig) DeepCopy() *OrganizationBootstrapControllerConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(OrganizationBootstrapControllerConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OrganizationCustomRoleControllerConfig) DeepCopyInto(out *OrganizationCustomRoleControllerConfig) {
+	*out = *in
+	if in.AllowedRoles != nil {
+		in, out := &in.AllowedRoles, &out.AllowedRoles
+		*out = make([]iamv1alpha1.ScopedRoleReference, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OrganizationCustomRoleControllerConfig.
+func (in *OrganizationCustomRoleControllerConfig) DeepCopy() *OrganizationCustomRoleControllerConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(OrganizationCustomRoleControllerConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OrganizationInvitationControllerConfig) DeepCopyInto(out *OrganizationInvitationControllerConfig) {
+	*out = *in
+	out.TTL = in.TTL
+	if in.DefaultRoles != nil {
+		in, out := &in.DefaultRoles, &out.DefaultRoles
+		*out = make([]v1alpha1.RoleReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedRoles != nil {
+		in, out := &in.AllowedRoles, &out.AllowedRoles
+		*out = make([]v1alpha1.RoleReference, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OrganizationInvitationControllerConfig.
+func (in *OrganizationInvitationControllerConfig) DeepCopy() *OrganizationInvitationControllerConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(OrganizationInvitationControllerConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PersonalWorkspaceAuditorConfig) DeepCopyInto(out *PersonalWorkspaceAuditorConfig) {
+	*out = *in
+	out.Interval = in.Interval
+	out.GracePeriod = in.GracePeriod
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PersonalWorkspaceAuditorConfig.
+func (in *PersonalWorkspaceAuditorConfig) DeepCopy() *PersonalWorkspaceAuditorConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(PersonalWorkspaceAuditorConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StarterGroupConfig) DeepCopyInto(out *StarterGroupConfig) {
+	*out = *in
+	out.Role = in.Role
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StarterGroupConfig.
+func (in *StarterGroupConfig) DeepCopy() *StarterGroupConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(StarterGroupConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UserOffboardingControllerConfig) DeepCopyInto(out *UserOffboardingControllerConfig) {
+	*out = *in
+	if in.OwnerRoles != nil {
+		in, out := &in.OwnerRoles, &out.OwnerRoles
+		*out = make([]v1alpha1.RoleReference, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserOffboardingControllerConfig.
+func (in *UserOffboardingControllerConfig) DeepCopy() *UserOffboardingControllerConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(UserOffboardingControllerConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UserOnboardingControllerConfig) DeepCopyInto(out *UserOnboardingControllerConfig) {
+	*out = *in
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]OnboardingRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserOnboardingControllerConfig.
+func (in *UserOnboardingControllerConfig) DeepCopy() *UserOnboardingControllerConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(UserOnboardingControllerConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UserWaitlistControllerConfig) DeepCopyInto(out *UserWaitlistControllerConfig) {
+	*out = *in
+	out.Interval = in.Interval
+	out.ProvisioningTimeout = in.ProvisioningTimeout
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserWaitlistControllerConfig.
+func (in *UserWaitlistControllerConfig) DeepCopy() *UserWaitlistControllerConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(UserWaitlistControllerConfig)
+	in.DeepCopyInto(out)
+	return out
+}
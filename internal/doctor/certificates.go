@@ -0,0 +1,48 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package doctor
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CheckCertificate reports whether the serving certificate in the tls.crt key
+// of the given secret is expired, or expires within warnBefore.
+func CheckCertificate(ctx context.Context, c client.Reader, check string, key types.NamespacedName, warnBefore time.Duration, now time.Time) Result {
+	secret := &corev1.Secret{}
+	err := c.Get(ctx, key, secret)
+	if apierrors.IsNotFound(err) {
+		return warn(check, "Point the doctor at the secret holding the certificate, or ignore this if the certificate is not issued into a secret.",
+			"secret %s not found", key)
+	}
+	if err != nil {
+		return fail(check, "Check the connection to the API server.", "failed to get secret %s: %v", key, err)
+	}
+
+	block, _ := pem.Decode(secret.Data[corev1.TLSCertKey])
+	if block == nil {
+		return fail(check, "Reissue the certificate.", "secret %s has no PEM certificate in %s", key, corev1.TLSCertKey)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return fail(check, "Reissue the certificate.", "secret %s has an invalid certificate: %v", key, err)
+	}
+
+	notAfter := cert.NotAfter.UTC().Format(time.RFC3339)
+	remediation := "Renew the certificate, and check that cert-manager is running and the Certificate is ready."
+	if now.After(cert.NotAfter) {
+		return fail(check, remediation, "expired on %s", notAfter)
+	}
+	if cert.NotAfter.Sub(now) < warnBefore {
+		return warn(check, remediation, "expires on %s", notAfter)
+	}
+	return pass(check, "expires on %s", notAfter)
+}
@@ -0,0 +1,60 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package doctor
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"go.datum.net/datum/pkg/features"
+)
+
+// Options configures the diagnostics of a running environment.
+type Options struct {
+	// FeatureGates are the datum feature gates, compared with those of Milo.
+	FeatureGates map[string]bool
+
+	// ConfigDir is the directory containing the services and overlays
+	// kustomizations.
+	ConfigDir string
+
+	// StuckUserThreshold is how long after signing up a user is expected to
+	// have a personal organization and project.
+	StuckUserThreshold time.Duration
+
+	// MetricsCertificateSecret and WebhookCertificateSecret hold the serving
+	// certificates of the controller manager. Empty names skip the check, and
+	// the webhook certificate is only checked when LastOwnerProtection is
+	// enabled.
+	MetricsCertificateSecret types.NamespacedName
+	WebhookCertificateSecret types.NamespacedName
+
+	// CertificateExpiryWarning is how long before expiry certificates are
+	// reported.
+	CertificateExpiryWarning time.Duration
+}
+
+// Diagnose checks a running environment: feature gate alignment between
+// Milo and datum, the assignable roles, the service policies, users stuck
+// without a personal organization or project, and serving certificates.
+// apiServer is used to read the metrics of the Milo API server.
+func Diagnose(ctx context.Context, apiServer rest.Interface, c client.Reader, opts Options) Report {
+	now := time.Now()
+	unifiedOrganizations := opts.FeatureGates[string(features.UnifiedOrganizations)]
+
+	report := CheckFeatureGates(ctx, apiServer, opts.FeatureGates)
+	report = append(report, CheckRoles(ctx, c)...)
+	report = append(report, CheckPolicies(ctx, c, opts.ConfigDir, unifiedOrganizations)...)
+	report = append(report, CheckUsers(ctx, c, unifiedOrganizations, opts.StuckUserThreshold, now)...)
+	if opts.MetricsCertificateSecret.Name != "" {
+		report = append(report, CheckCertificate(ctx, c, "metrics certificate", opts.MetricsCertificateSecret, opts.CertificateExpiryWarning, now))
+	}
+	if opts.WebhookCertificateSecret.Name != "" && opts.FeatureGates[string(features.LastOwnerProtection)] {
+		report = append(report, CheckCertificate(ctx, c, "webhook certificate", opts.WebhookCertificateSecret, opts.CertificateExpiryWarning, now))
+	}
+	return report
+}
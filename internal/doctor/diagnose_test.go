@@ -0,0 +1,244 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package doctor
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	restfake "k8s.io/client-go/rest/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	iamv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
+	resourcemanagerv1alpha1 "go.miloapis.com/milo/pkg/apis/resourcemanager/v1alpha1"
+
+	"go.datum.net/datum/internal/catalog"
+	resourcemanagercontroller "go.datum.net/datum/internal/controller/resourcemanager"
+	"go.datum.net/datum/internal/quota"
+)
+
+func newScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	s := runtime.NewScheme()
+	if err := corev1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := iamv1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := resourcemanagerv1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func resultsByCheck(report Report) map[string]Result {
+	results := map[string]Result{}
+	for _, result := range report {
+		results[result.Check] = result
+	}
+	return results
+}
+
+func TestCheckFeatureGates(t *testing.T) {
+	metrics := `# HELP kubernetes_feature_enabled [BETA] This metric records the data about the stage and enablement of a k8s feature.
+# TYPE kubernetes_feature_enabled gauge
+kubernetes_feature_enabled{name="UnifiedOrganizations",stage="ALPHA"} 1
+kubernetes_feature_enabled{name="LastOwnerProtection",stage="ALPHA"} 0
+`
+	apiServer := &restfake.RESTClient{
+		NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		Client: restfake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Path != "/metrics" {
+				return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(strings.NewReader(""))}, nil
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(metrics))}, nil
+		}),
+	}
+
+	results := resultsByCheck(CheckFeatureGates(context.Background(), apiServer, map[string]bool{
+		"UnifiedOrganizations": false,
+		"LastOwnerProtection":  false,
+		"UserWaitlist":         true,
+	}))
+	if result := results["feature gate UnifiedOrganizations"]; result.Status != StatusFail {
+		t.Errorf("expected misaligned gate to fail, got %+v", result)
+	}
+	if result := results["feature gate LastOwnerProtection"]; result.Status != StatusPass {
+		t.Errorf("expected aligned gate to pass, got %+v", result)
+	}
+	if result := results["feature gate UserWaitlist"]; result.Status != StatusPass {
+		t.Errorf("expected datum only gate to pass, got %+v", result)
+	}
+}
+
+func TestCheckRoles(t *testing.T) {
+	var objects []client.Object
+	for _, assignable := range catalog.AssignableRoles {
+		role := &iamv1alpha1.Role{ObjectMeta: metav1.ObjectMeta{Namespace: catalog.RoleNamespace, Name: string(assignable.Level)}}
+		for _, ref := range catalog.InheritedRoles(assignable, catalog.Services) {
+			role.Spec.InheritedRoles = append(role.Spec.InheritedRoles, iamv1alpha1.ScopedRoleReference{Name: ref.Name, Namespace: ref.Namespace})
+			// Leave out one of the service roles inherited by owners.
+			if assignable.Level == catalog.LevelOwner && ref.Namespace == catalog.ServiceRoleNamespace {
+				continue
+			}
+			objects = append(objects, &iamv1alpha1.Role{ObjectMeta: metav1.ObjectMeta{Namespace: ref.Namespace, Name: ref.Name}})
+		}
+		if assignable.Level == catalog.LevelViewer {
+			role.Spec.InheritedRoles = role.Spec.InheritedRoles[1:]
+		}
+		if assignable.Level != catalog.LevelEditor {
+			objects = append(objects, role)
+		}
+	}
+	// Roles inherited by several assignable roles are only created once.
+	c := fake.NewClientBuilder().WithScheme(newScheme(t)).WithObjects(dedupe(objects)...).Build()
+
+	results := resultsByCheck(CheckRoles(context.Background(), c))
+	if result := results["role datum-cloud/owner"]; result.Status != StatusFail || !strings.Contains(result.Message, "inherits missing roles") {
+		t.Errorf("expected owner to inherit missing roles, got %+v", result)
+	}
+	if result := results["role datum-cloud/editor"]; result.Status != StatusFail || result.Message != "role is missing" {
+		t.Errorf("expected editor to be missing, got %+v", result)
+	}
+	if result := results["role datum-cloud/viewer"]; result.Status != StatusWarn {
+		t.Errorf("expected viewer to differ from the catalog, got %+v", result)
+	}
+}
+
+func dedupe(objects []client.Object) []client.Object {
+	seen := map[types.NamespacedName]bool{}
+	var unique []client.Object
+	for _, obj := range objects {
+		key := client.ObjectKeyFromObject(obj)
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, obj)
+		}
+	}
+	return unique
+}
+
+func TestCheckPolicies(t *testing.T) {
+	objects, err := quota.Render("../../config/services")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Install every policy except the claim policies of the DNS service.
+	var installed []client.Object
+	for _, obj := range objects {
+		if obj.GetKind() == "ClaimCreationPolicy" && strings.HasPrefix(obj.GetName(), "project-dns") {
+			continue
+		}
+		installed = append(installed, obj)
+	}
+	c := fake.NewClientBuilder().WithScheme(newScheme(t)).WithObjects(installed...).Build()
+
+	results := resultsByCheck(CheckPolicies(context.Background(), c, "../../config", false))
+	if result := results["policies dns.networking.miloapis.com"]; result.Status != StatusFail || !strings.Contains(result.Message, "ClaimCreationPolicy/") {
+		t.Errorf("expected missing DNS claim policies to fail, got %+v", result)
+	}
+	if result := results["policies resourcemanager.miloapis.com"]; result.Status != StatusPass {
+		t.Errorf("expected resourcemanager policies to pass, got %+v", result)
+	}
+	if _, ok := results["policies search.miloapis.com"]; ok {
+		t.Error("expected services without policies to be skipped")
+	}
+
+	results = resultsByCheck(CheckPolicies(context.Background(), c, "../../config", true))
+	if result := results["policies unified-organizations"]; result.Status != StatusFail {
+		t.Errorf("expected missing overlay policies to fail, got %+v", result)
+	}
+}
+
+func TestCheckUsers(t *testing.T) {
+	now := time.Now()
+	newUser := func(name string, age time.Duration, approval iamv1alpha1.RegistrationApprovalState) *iamv1alpha1.User {
+		return &iamv1alpha1.User{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				UID:               types.UID(name),
+				CreationTimestamp: metav1.NewTime(now.Add(-age)),
+			},
+			Status: iamv1alpha1.UserStatus{RegistrationApproval: approval},
+		}
+	}
+	organization := func(user string) *resourcemanagerv1alpha1.Organization {
+		return &resourcemanagerv1alpha1.Organization{ObjectMeta: metav1.ObjectMeta{Name: resourcemanagercontroller.PersonalOrganizationName(types.UID(user))}}
+	}
+	project := func(user string) *resourcemanagerv1alpha1.Project {
+		return &resourcemanagerv1alpha1.Project{ObjectMeta: metav1.ObjectMeta{Name: resourcemanagercontroller.PersonalProjectName(types.UID(user))}}
+	}
+
+	c := fake.NewClientBuilder().WithScheme(newScheme(t)).WithObjects(
+		newUser("provisioned", 2*time.Hour, iamv1alpha1.RegistrationApprovalStateApproved), organization("provisioned"), project("provisioned"),
+		newUser("pending", 2*time.Hour, iamv1alpha1.RegistrationApprovalStatePending), organization("pending"),
+		newUser("no-org", 2*time.Hour, iamv1alpha1.RegistrationApprovalStateApproved),
+		newUser("no-project", 2*time.Hour, iamv1alpha1.RegistrationApprovalStateApproved), organization("no-project"),
+		newUser("new", time.Minute, iamv1alpha1.RegistrationApprovalStateApproved),
+	).Build()
+
+	results := resultsByCheck(CheckUsers(context.Background(), c, false, time.Hour, now))
+	if result := results["users personal organization"]; result.Status != StatusFail || !strings.HasSuffix(result.Message, ": no-org") {
+		t.Errorf("expected no-org to be stuck, got %+v", result)
+	}
+	if result := results["users personal project"]; result.Status != StatusFail || !strings.HasSuffix(result.Message, ": no-project") {
+		t.Errorf("expected no-project to be stuck, got %+v", result)
+	}
+
+	if report := CheckUsers(context.Background(), c, true, time.Hour, now); report.Failed() {
+		t.Errorf("expected users to be skipped with UnifiedOrganizations, got %+v", report)
+	}
+}
+
+func TestCheckCertificate(t *testing.T) {
+	now := time.Now()
+	newSecret := func(name string, notAfter time.Time) *corev1.Secret {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		template := &x509.Certificate{SerialNumber: big.NewInt(1), NotBefore: now.Add(-time.Hour), NotAfter: notAfter}
+		der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "datum-system", Name: name},
+			Data:       map[string][]byte{corev1.TLSCertKey: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})},
+		}
+	}
+	c := fake.NewClientBuilder().WithScheme(newScheme(t)).WithObjects(
+		newSecret("valid", now.Add(90*24*time.Hour)),
+		newSecret("expiring", now.Add(24*time.Hour)),
+		newSecret("expired", now.Add(-time.Minute)),
+	).Build()
+
+	for name, status := range map[string]Status{
+		"valid":    StatusPass,
+		"expiring": StatusWarn,
+		"expired":  StatusFail,
+		"missing":  StatusWarn,
+	} {
+		key := types.NamespacedName{Namespace: "datum-system", Name: name}
+		if result := CheckCertificate(context.Background(), c, "certificate", key, 30*24*time.Hour, now); result.Status != status {
+			t.Errorf("expected %s certificate to be %s, got %+v", name, status, result)
+		}
+	}
+}
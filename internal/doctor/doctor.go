@@ -0,0 +1,77 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package doctor checks that an environment is able to run the Datum
+// controller manager and that a running environment is healthy, and reports
+// problems along with how to fix them.
+package doctor
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// Status is the outcome of a check.
+type Status string
+
+const (
+	StatusPass Status = "PASS"
+	StatusWarn Status = "WARN"
+	StatusFail Status = "FAIL"
+)
+
+// Result is the outcome of a single check.
+type Result struct {
+	// Check names what was checked.
+	Check string
+
+	Status Status
+
+	// Message describes the outcome.
+	Message string
+
+	// Remediation describes how to fix a warning or failure.
+	Remediation string
+}
+
+// Report is the outcome of a set of checks.
+type Report []Result
+
+// Failed reports whether any check failed.
+func (r Report) Failed() bool {
+	for _, result := range r {
+		if result.Status == StatusFail {
+			return true
+		}
+	}
+	return false
+}
+
+// Print writes the report as a table, with the remediation of warnings and
+// failures below them.
+func (r Report) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, result := range r {
+		if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\n", result.Status, result.Check, result.Message); err != nil {
+			return err
+		}
+		if result.Status != StatusPass && result.Remediation != "" {
+			if _, err := fmt.Fprintf(tw, "\t\t  -> %s\n", result.Remediation); err != nil {
+				return err
+			}
+		}
+	}
+	return tw.Flush()
+}
+
+func pass(check, format string, args ...any) Result {
+	return Result{Check: check, Status: StatusPass, Message: fmt.Sprintf(format, args...)}
+}
+
+func warn(check, remediation, format string, args ...any) Result {
+	return Result{Check: check, Status: StatusWarn, Message: fmt.Sprintf(format, args...), Remediation: remediation}
+}
+
+func fail(check, remediation, format string, args ...any) Result {
+	return Result{Check: check, Status: StatusFail, Message: fmt.Sprintf(format, args...), Remediation: remediation}
+}
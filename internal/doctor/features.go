@@ -0,0 +1,76 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/prometheus/common/expfmt"
+	"k8s.io/client-go/rest"
+	"k8s.io/utils/ptr"
+)
+
+// featureEnabledMetric is the metric Kubernetes API servers, Milo included,
+// export for each of their feature gates.
+const featureEnabledMetric = "kubernetes_feature_enabled"
+
+// CheckFeatureGates compares the given datum feature gates with the feature
+// gates of the same name reported by the metrics of the Milo API server. Gates
+// unknown to Milo only affect datum and always pass.
+func CheckFeatureGates(ctx context.Context, apiServer rest.Interface, gates map[string]bool) Report {
+	data, err := apiServer.Get().AbsPath("/metrics").DoRaw(ctx)
+	if err != nil {
+		return Report{warn("feature gates", "Grant get on the /metrics non-resource URL, or compare the --feature-gates of milo and datum by hand.",
+			"unable to read the milo metrics: %v", err)}
+	}
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(bytes.NewReader(data))
+	if err != nil {
+		return Report{warn("feature gates", "Compare the --feature-gates of milo and datum by hand.",
+			"unable to parse the milo metrics: %v", err)}
+	}
+
+	milo := map[string]bool{}
+	if family, ok := families[featureEnabledMetric]; ok {
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "name" {
+					milo[label.GetValue()] = ptr.Deref(metric.GetGauge().Value, 0) == 1
+				}
+			}
+		}
+	}
+
+	names := make([]string, 0, len(gates))
+	for name := range gates {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var report Report
+	for _, name := range names {
+		check := fmt.Sprintf("feature gate %s", name)
+		enabled := gates[name]
+		miloEnabled, ok := milo[name]
+		switch {
+		case !ok:
+			report = append(report, pass(check, "%s in datum, not a milo feature gate", enabledString(enabled)))
+		case miloEnabled != enabled:
+			report = append(report, fail(check, fmt.Sprintf("Set --feature-gates=%s=%t on both milo and datum.", name, miloEnabled),
+				"%s in milo but %s in datum", enabledString(miloEnabled), enabledString(enabled)))
+		default:
+			report = append(report, pass(check, "%s in milo and datum", enabledString(enabled)))
+		}
+	}
+	return report
+}
+
+func enabledString(enabled bool) string {
+	if enabled {
+		return "enabled"
+	}
+	return "disabled"
+}
@@ -0,0 +1,121 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"go.datum.net/datum/internal/quota"
+)
+
+// PolicyKinds are the kinds of the service configuration checked by
+// CheckPolicies.
+var PolicyKinds = []string{"GrantCreationPolicy", "ClaimCreationPolicy", "ResourceRegistration"}
+
+// unifiedOrganizationsOverlay is the overlay applied to the service
+// configuration when UnifiedOrganizations is enabled.
+const unifiedOrganizationsOverlay = "unified-organizations"
+
+// CheckPolicies renders the service configuration in configDir the same way
+// it is deployed and reports, for each service, whether its grant, claim and
+// registration policies are installed. Policies added by the
+// unified-organizations overlay are reported on their own.
+func CheckPolicies(ctx context.Context, c client.Reader, configDir string, unifiedOrganizations bool) Report {
+	servicesDir := filepath.Join(configDir, "services")
+	components := []string{servicesDir}
+	if unifiedOrganizations {
+		components = append(components, filepath.Join(configDir, "overlays", unifiedOrganizationsOverlay))
+	}
+	objects, err := quota.Render(components...)
+	if err != nil {
+		return Report{fail("policies", "Run datum doctor from the repository root, or set --config-dir.",
+			"unable to render the service configuration: %v", err)}
+	}
+
+	// Render each service on its own to find out which service an object of
+	// the deployed configuration comes from.
+	entries, err := os.ReadDir(servicesDir)
+	if err != nil {
+		return Report{fail("policies", "Run datum doctor from the repository root, or set --config-dir.",
+			"unable to read the service configuration: %v", err)}
+	}
+	var services []string
+	serviceOf := map[string]string{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		serviceObjects, err := quota.Render(filepath.Join(servicesDir, entry.Name()))
+		if err != nil {
+			return Report{fail("policies", "Fix the kustomization of the service.",
+				"unable to render service %s: %v", entry.Name(), err)}
+		}
+		services = append(services, entry.Name())
+		for _, obj := range serviceObjects {
+			serviceOf[objectString(obj)] = entry.Name()
+		}
+	}
+	services = append(services, unifiedOrganizationsOverlay)
+
+	expected := map[string][]*unstructured.Unstructured{}
+	for _, obj := range objects {
+		if !slices.Contains(PolicyKinds, obj.GetKind()) {
+			continue
+		}
+		service, ok := serviceOf[objectString(obj)]
+		if !ok {
+			service = unifiedOrganizationsOverlay
+		}
+		expected[service] = append(expected[service], obj)
+	}
+
+	var report Report
+	for _, service := range services {
+		if len(expected[service]) == 0 {
+			continue
+		}
+		check := fmt.Sprintf("policies %s", service)
+		var missing []string
+		var failure error
+		for _, obj := range expected[service] {
+			installed := &metav1.PartialObjectMetadata{}
+			installed.SetGroupVersionKind(obj.GroupVersionKind())
+			err := c.Get(ctx, client.ObjectKeyFromObject(obj), installed)
+			if apierrors.IsNotFound(err) {
+				missing = append(missing, objectString(obj))
+				continue
+			}
+			if err != nil {
+				failure = err
+				break
+			}
+		}
+		switch {
+		case meta.IsNoMatchError(failure):
+			report = append(report, fail(check, "Install the Milo quota APIs.", "%v", failure))
+		case failure != nil:
+			report = append(report, fail(check, "Check the connection to the API server.", "failed to get policies: %v", failure))
+		case len(missing) > 0:
+			report = append(report, fail(check, fmt.Sprintf("Apply %s with kustomize.", servicesDir),
+				"missing %s", strings.Join(missing, ", ")))
+		default:
+			report = append(report, pass(check, "%d policies installed", len(expected[service])))
+		}
+	}
+	return report
+}
+
+func objectString(obj *unstructured.Unstructured) string {
+	return obj.GetKind() + "/" + obj.GetName()
+}
@@ -0,0 +1,156 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+
+	iamv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
+	resourcemanagerv1alpha1 "go.miloapis.com/milo/pkg/apis/resourcemanager/v1alpha1"
+)
+
+// RequiredAPIs are the kinds the controllers read and write.
+var RequiredAPIs = []schema.GroupVersionKind{
+	iamv1alpha1.SchemeGroupVersion.WithKind("User"),
+	iamv1alpha1.SchemeGroupVersion.WithKind("Role"),
+	iamv1alpha1.SchemeGroupVersion.WithKind("Group"),
+	iamv1alpha1.SchemeGroupVersion.WithKind("PolicyBinding"),
+	resourcemanagerv1alpha1.SchemeGroupVersion.WithKind("Organization"),
+	resourcemanagerv1alpha1.SchemeGroupVersion.WithKind("OrganizationMembership"),
+	resourcemanagerv1alpha1.SchemeGroupVersion.WithKind("Project"),
+	{Group: "quota.miloapis.com", Version: "v1alpha1", Kind: "ResourceClaim"},
+	{Group: "quota.miloapis.com", Version: "v1alpha1", Kind: "ResourceGrant"},
+}
+
+// RequiredPermissions are the permissions granted to the controller manager by
+// the kubebuilder RBAC markers of the controllers. They must match
+// config/rbac/role.yaml, which is generated from the same markers.
+var RequiredPermissions = []rbacv1.PolicyRule{
+	{APIGroups: []string{""}, Resources: []string{"events"}, Verbs: []string{"create", "patch"}},
+	{APIGroups: []string{""}, Resources: []string{"groups", "uids", "users"}, Verbs: []string{"impersonate"}},
+	{APIGroups: []string{"authentication.k8s.io"}, Resources: []string{"userextras/*"}, Verbs: []string{"impersonate"}},
+	{APIGroups: []string{"iam.datumapis.com"}, Resources: []string{"users"}, Verbs: []string{"get", "list", "watch"}},
+	{APIGroups: []string{"iam.miloapis.com"}, Resources: []string{"groups", "policybindings"}, Verbs: []string{"create", "get", "list", "watch"}},
+	{APIGroups: []string{"iam.miloapis.com"}, Resources: []string{"roles"}, Verbs: []string{"create", "delete", "get", "list", "patch", "update", "watch"}},
+	{APIGroups: []string{"iam.miloapis.com"}, Resources: []string{"roles/status", "users/status"}, Verbs: []string{"get", "patch", "update"}},
+	{APIGroups: []string{"iam.miloapis.com"}, Resources: []string{"users"}, Verbs: []string{"get", "list", "patch", "update", "watch"}},
+	{APIGroups: []string{"quota.miloapis.com"}, Resources: []string{"resourceclaims"}, Verbs: []string{"create", "delete", "get", "list", "watch"}},
+	{APIGroups: []string{"quota.miloapis.com"}, Resources: []string{"resourcegrants"}, Verbs: []string{"get", "list", "watch"}},
+	{APIGroups: []string{"resourcemanager.datumapis.com"}, Resources: []string{"organizations"}, Verbs: []string{"create"}},
+	{APIGroups: []string{"resourcemanager.datumapis.com", "resourcemanager.miloapis.com"}, Resources: []string{"projects"}, Verbs: []string{"create", "get", "list", "patch", "update", "watch"}},
+	{APIGroups: []string{"resourcemanager.miloapis.com"}, Resources: []string{"organizationmemberships"}, Verbs: []string{"create", "delete", "get", "list", "patch", "update", "watch"}},
+	{APIGroups: []string{"resourcemanager.miloapis.com"}, Resources: []string{"organizations"}, Verbs: []string{"get", "list", "patch", "update", "watch"}},
+	{APIGroups: []string{"resourcemanager.miloapis.com"}, Resources: []string{"organizations/status"}, Verbs: []string{"get", "patch", "update"}},
+}
+
+// Preflight checks that the APIs used by the controllers are served and that
+// the controller manager holds the permissions it needs.
+func Preflight(ctx context.Context, clientset kubernetes.Interface) Report {
+	report := CheckAPIs(clientset)
+	return append(report, CheckPermissions(ctx, clientset)...)
+}
+
+// CheckAPIs reports, for each group version of RequiredAPIs, whether all of its
+// kinds are served.
+func CheckAPIs(clientset kubernetes.Interface) Report {
+	kinds := map[schema.GroupVersion][]string{}
+	var groupVersions []schema.GroupVersion
+	for _, gvk := range RequiredAPIs {
+		gv := gvk.GroupVersion()
+		if _, ok := kinds[gv]; !ok {
+			groupVersions = append(groupVersions, gv)
+		}
+		kinds[gv] = append(kinds[gv], gvk.Kind)
+	}
+
+	var report Report
+	for _, gv := range groupVersions {
+		check := fmt.Sprintf("api %s", gv)
+		resources, err := clientset.Discovery().ServerResourcesForGroupVersion(gv.String())
+		if apierrors.IsNotFound(err) {
+			report = append(report, fail(check, "Install the Milo APIs, or point the kubeconfig at the Milo API server.",
+				"%s is not served", gv))
+			continue
+		}
+		if err != nil {
+			report = append(report, fail(check, "Check the connection to the API server.",
+				"failed to discover %s: %v", gv, err))
+			continue
+		}
+
+		var missing []string
+		for _, kind := range kinds[gv] {
+			if !slices.ContainsFunc(resources.APIResources, func(resource metav1.APIResource) bool {
+				return resource.Kind == kind
+			}) {
+				missing = append(missing, kind)
+			}
+		}
+		if len(missing) > 0 {
+			report = append(report, fail(check, "Upgrade Milo to a version serving these kinds.",
+				"missing kinds %s", strings.Join(missing, ", ")))
+			continue
+		}
+		report = append(report, pass(check, "serves %s", strings.Join(kinds[gv], ", ")))
+	}
+	return report
+}
+
+// CheckPermissions reports whether the current identity holds each of the
+// RequiredPermissions, using SelfSubjectAccessReviews.
+func CheckPermissions(ctx context.Context, clientset kubernetes.Interface) Report {
+	var denied []string
+	for _, rule := range RequiredPermissions {
+		for _, group := range rule.APIGroups {
+			for _, resource := range rule.Resources {
+				resource, subresource, _ := strings.Cut(resource, "/")
+				for _, verb := range rule.Verbs {
+					review := &authorizationv1.SelfSubjectAccessReview{
+						Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+							ResourceAttributes: &authorizationv1.ResourceAttributes{
+								Group:       group,
+								Resource:    resource,
+								Subresource: subresource,
+								Verb:        verb,
+							},
+						},
+					}
+					result, err := clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+					if err != nil {
+						return Report{fail("permissions", "Check the connection to the API server.",
+							"failed to review access: %v", err)}
+					}
+					if !result.Status.Allowed {
+						denied = append(denied, permissionString(group, resource, subresource, verb))
+					}
+				}
+			}
+		}
+	}
+
+	if len(denied) > 0 {
+		return Report{fail("permissions", "Apply config/rbac to grant the manager-role ClusterRole to the controller manager.",
+			"denied %s", strings.Join(denied, ", "))}
+	}
+	return Report{pass("permissions", "all %d permission rules are granted", len(RequiredPermissions))}
+}
+
+func permissionString(group, resource, subresource, verb string) string {
+	if group != "" {
+		resource = resource + "." + group
+	}
+	if subresource != "" {
+		resource = resource + "/" + subresource
+	}
+	return verb + " " + resource
+}
@@ -0,0 +1,77 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package doctor
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/yaml"
+)
+
+// TestRequiredPermissionsUpToDate makes sure the preflight checks the
+// permissions generated from the RBAC markers.
+func TestRequiredPermissionsUpToDate(t *testing.T) {
+	data, err := os.ReadFile("../../config/rbac/role.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	role := &rbacv1.ClusterRole{}
+	if err := yaml.Unmarshal(data, role); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(role.Rules, RequiredPermissions) {
+		t.Errorf("RequiredPermissions do not match config/rbac/role.yaml, update them after changing RBAC markers:\n%v", role.Rules)
+	}
+}
+
+func TestPreflight(t *testing.T) {
+	clientset := fake.NewClientset()
+	clientset.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "iam.miloapis.com/v1alpha1",
+			APIResources: []metav1.APIResource{{Name: "users", Kind: "User"}, {Name: "roles", Kind: "Role"}, {Name: "groups", Kind: "Group"}, {Name: "policybindings", Kind: "PolicyBinding"}},
+		},
+		{
+			GroupVersion: "resourcemanager.miloapis.com/v1alpha1",
+			APIResources: []metav1.APIResource{{Name: "organizations", Kind: "Organization"}},
+		},
+	}
+	clientset.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		attributes := review.Spec.ResourceAttributes
+		review.Status.Allowed = attributes.Verb != "impersonate"
+		return true, review, nil
+	})
+
+	report := Preflight(context.Background(), clientset)
+	if !report.Failed() {
+		t.Fatal("expected preflight to fail")
+	}
+
+	results := map[string]Result{}
+	for _, result := range report {
+		results[result.Check] = result
+	}
+	if results["api iam.miloapis.com/v1alpha1"].Status != StatusPass {
+		t.Errorf("expected iam API check to pass, got %+v", results["api iam.miloapis.com/v1alpha1"])
+	}
+	if result := results["api resourcemanager.miloapis.com/v1alpha1"]; result.Status != StatusFail || !strings.Contains(result.Message, "OrganizationMembership") {
+		t.Errorf("expected missing resourcemanager kinds to fail, got %+v", result)
+	}
+	if result := results["api quota.miloapis.com/v1alpha1"]; result.Status != StatusFail {
+		t.Errorf("expected missing quota API to fail, got %+v", result)
+	}
+	if result := results["permissions"]; result.Status != StatusFail || !strings.Contains(result.Message, "impersonate users") {
+		t.Errorf("expected impersonation to be denied, got %+v", result)
+	}
+}
@@ -0,0 +1,70 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	iamv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
+
+	"go.datum.net/datum/internal/catalog"
+)
+
+// CheckRoles reports, for each assignable role of the catalog, whether it is
+// installed, whether every role it inherits exists, and whether its inherited
+// roles match the catalog.
+func CheckRoles(ctx context.Context, c client.Reader) Report {
+	var report Report
+	for _, assignable := range catalog.AssignableRoles {
+		check := fmt.Sprintf("role %s/%s", catalog.RoleNamespace, assignable.Level)
+		role := &iamv1alpha1.Role{}
+		err := c.Get(ctx, client.ObjectKey{Namespace: catalog.RoleNamespace, Name: string(assignable.Level)}, role)
+		if apierrors.IsNotFound(err) {
+			report = append(report, fail(check, "Apply config/assignable-organization-roles.", "role is missing"))
+			continue
+		}
+		if err != nil {
+			report = append(report, fail(check, "Check the connection to the API server.", "failed to get role: %v", err))
+			continue
+		}
+
+		var missing []string
+		for _, ref := range role.Spec.InheritedRoles {
+			namespace := ref.Namespace
+			if namespace == "" {
+				namespace = role.Namespace
+			}
+			err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: ref.Name}, &iamv1alpha1.Role{})
+			if apierrors.IsNotFound(err) {
+				missing = append(missing, namespace+"/"+ref.Name)
+				continue
+			}
+			if err != nil {
+				missing = append(missing, fmt.Sprintf("%s/%s (%v)", namespace, ref.Name, err))
+			}
+		}
+		if len(missing) > 0 {
+			report = append(report, fail(check, "Install the services providing these roles, or regenerate the roles with datum generate roles and apply them.",
+				"inherits missing roles %s", strings.Join(missing, ", ")))
+			continue
+		}
+
+		var expected []iamv1alpha1.ScopedRoleReference
+		for _, ref := range catalog.InheritedRoles(assignable, catalog.Services) {
+			expected = append(expected, iamv1alpha1.ScopedRoleReference{Name: ref.Name, Namespace: ref.Namespace})
+		}
+		if !slices.Equal(role.Spec.InheritedRoles, expected) {
+			report = append(report, warn(check, "Apply config/assignable-organization-roles, running datum generate roles first if the catalog changed.",
+				"inherited roles differ from the service catalog"))
+			continue
+		}
+		report = append(report, pass(check, "inherits %d roles", len(role.Spec.InheritedRoles)))
+	}
+	return report
+}
@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	iamv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
+	resourcemanagerv1alpha1 "go.miloapis.com/milo/pkg/apis/resourcemanager/v1alpha1"
+
+	resourcemanagercontroller "go.datum.net/datum/internal/controller/resourcemanager"
+)
+
+// maxListedUsers is the number of stuck users named in a result.
+const maxListedUsers = 5
+
+// CheckUsers reports users that signed up more than threshold ago and still
+// have no personal organization, and approved users without a personal
+// project. Personal organizations are not created when UnifiedOrganizations is
+// enabled, so the check is skipped.
+func CheckUsers(ctx context.Context, c client.Reader, unifiedOrganizations bool, threshold time.Duration, now time.Time) Report {
+	if unifiedOrganizations {
+		return Report{pass("users", "personal organizations are disabled by UnifiedOrganizations")}
+	}
+
+	users := &iamv1alpha1.UserList{}
+	if err := c.List(ctx, users); err != nil {
+		return Report{fail("users", "Check the connection to the API server.", "failed to list users: %v", err)}
+	}
+	organizations := &resourcemanagerv1alpha1.OrganizationList{}
+	if err := c.List(ctx, organizations); err != nil {
+		return Report{fail("users", "Check the connection to the API server.", "failed to list organizations: %v", err)}
+	}
+	projects := &resourcemanagerv1alpha1.ProjectList{}
+	if err := c.List(ctx, projects); err != nil {
+		return Report{fail("users", "Check the connection to the API server.", "failed to list projects: %v", err)}
+	}
+
+	organizationNames := sets.New[string]()
+	for _, organization := range organizations.Items {
+		organizationNames.Insert(organization.Name)
+	}
+	projectNames := sets.New[string]()
+	for _, project := range projects.Items {
+		projectNames.Insert(project.Name)
+	}
+
+	var withoutOrganization, withoutProject []string
+	for _, user := range users.Items {
+		if !user.DeletionTimestamp.IsZero() || now.Sub(user.CreationTimestamp.Time) < threshold {
+			continue
+		}
+		if !organizationNames.Has(resourcemanagercontroller.PersonalOrganizationName(user.UID)) {
+			withoutOrganization = append(withoutOrganization, user.Name)
+			continue
+		}
+		if user.Status.RegistrationApproval == iamv1alpha1.RegistrationApprovalStateApproved &&
+			!projectNames.Has(resourcemanagercontroller.PersonalProjectName(user.UID)) {
+			withoutProject = append(withoutProject, user.Name)
+		}
+	}
+
+	return Report{
+		stuckUsers("users personal organization", "without a personal organization", withoutOrganization, threshold),
+		stuckUsers("users personal project", "approved without a personal project", withoutProject, threshold),
+	}
+}
+
+func stuckUsers(check, problem string, names []string, threshold time.Duration) Result {
+	if len(names) == 0 {
+		return pass(check, "no user %s after %s", problem, threshold)
+	}
+	listed := names
+	if len(listed) > maxListedUsers {
+		listed = listed[:maxListedUsers]
+	}
+	message := fmt.Sprintf("%d users %s after %s: %s", len(names), problem, threshold, strings.Join(listed, ", "))
+	if len(names) > len(listed) {
+		message += ", ..."
+	}
+	return fail(check, "Check the personal-organization controller logs for these users.", "%s", message)
+}
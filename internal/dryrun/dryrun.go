@@ -0,0 +1,118 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package dryrun counts the writes the controller manager sends to the API
+// server and, in dry-run mode, turns them into server-side dry-run requests so
+// a shadow build can run alongside the active one without side effects.
+package dryrun
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/transport"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var mutations = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "datum_api_mutations_total",
+	Help: "Number of mutating requests sent to the API server, by verb, group and resource. " +
+		"In dry-run mode, the mutations are planned and not persisted.",
+}, []string{"verb", "group", "resource", "dry_run"})
+
+func init() {
+	metrics.Registry.MustRegister(mutations)
+}
+
+// reviewGroups serve create-only review resources, such as TokenReviews and
+// SubjectAccessReviews, which query the API server rather than mutate it.
+var reviewGroups = sets.New("authentication.k8s.io", "authorization.k8s.io")
+
+var requestInfoFactory = &request.RequestInfoFactory{
+	APIPrefixes:          sets.NewString("api", "apis"),
+	GrouplessAPIPrefixes: sets.NewString("api"),
+}
+
+// WrapTransport returns a transport wrapper counting the mutating requests
+// sent through the transport. When dryRun is set, mutating requests are sent
+// with dryRun=All, so the API server validates and admits them without
+// persisting them, and they are logged as planned mutations.
+//
+// It is meant to be used with rest.Config.Wrap, so clients created from copies
+// of the config, such as impersonated clients, are covered as well.
+func WrapTransport(dryRun bool) transport.WrapperFunc {
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return &roundTripper{next: rt, dryRun: dryRun}
+	}
+}
+
+type roundTripper struct {
+	next   http.RoundTripper
+	dryRun bool
+}
+
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	info, err := requestInfoFactory.NewRequestInfo(req)
+	if err != nil || !info.IsResourceRequest || reviewGroups.Has(info.APIGroup) {
+		return rt.next.RoundTrip(req)
+	}
+	switch info.Verb {
+	case "create", "update", "patch", "delete", "deletecollection":
+	default:
+		return rt.next.RoundTrip(req)
+	}
+
+	resource := info.Resource
+	if info.Subresource != "" {
+		resource += "/" + info.Subresource
+	}
+	mutations.WithLabelValues(info.Verb, info.APIGroup, resource, strconv.FormatBool(rt.dryRun)).Inc()
+	if !rt.dryRun {
+		return rt.next.RoundTrip(req)
+	}
+
+	// Round trippers must not modify the request.
+	req = req.Clone(req.Context())
+	query := req.URL.Query()
+	query.Set("dryRun", metav1.DryRunAll)
+	req.URL.RawQuery = query.Encode()
+
+	name := info.Name
+	if name == "" && info.Verb == "create" {
+		name = createdName(req)
+	}
+	logf.FromContext(req.Context()).Info("Planned mutation",
+		"verb", info.Verb,
+		"group", info.APIGroup,
+		"resource", resource,
+		"namespace", info.Namespace,
+		"name", name,
+	)
+	return rt.next.RoundTrip(req)
+}
+
+// createdName returns the name of the object created by the request, read from
+// a copy of its body.
+func createdName(req *http.Request) string {
+	if req.GetBody == nil {
+		return ""
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return ""
+	}
+	defer body.Close()
+	obj := &metav1.PartialObjectMetadata{}
+	if err := json.NewDecoder(body).Decode(obj); err != nil {
+		return ""
+	}
+	if obj.Name == "" {
+		return obj.GenerateName
+	}
+	return obj.Name
+}
@@ -0,0 +1,78 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package dryrun
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type recordingRoundTripper struct {
+	requests []*http.Request
+}
+
+func (rt *recordingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.requests = append(rt.requests, req)
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("{}"))}, nil
+}
+
+func TestWrapTransport(t *testing.T) {
+	body := `{"apiVersion":"resourcemanager.miloapis.com/v1alpha1","kind":"Project","metadata":{"name":"personal-project-1234"}}`
+	tests := []struct {
+		name       string
+		dryRun     bool
+		method     string
+		path       string
+		wantDryRun bool
+	}{
+		{name: "create", dryRun: true, method: http.MethodPost, path: "/apis/resourcemanager.miloapis.com/v1alpha1/projects", wantDryRun: true},
+		{name: "status update", dryRun: true, method: http.MethodPut, path: "/apis/iam.miloapis.com/v1alpha1/users/jane/status", wantDryRun: true},
+		{name: "delete", dryRun: true, method: http.MethodDelete, path: "/api/v1/namespaces/default/configmaps/example", wantDryRun: true},
+		{name: "get", dryRun: true, method: http.MethodGet, path: "/apis/iam.miloapis.com/v1alpha1/users/jane"},
+		{name: "review", dryRun: true, method: http.MethodPost, path: "/apis/authorization.k8s.io/v1/selfsubjectaccessreviews"},
+		{name: "not dry run", method: http.MethodPost, path: "/apis/resourcemanager.miloapis.com/v1alpha1/projects"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &recordingRoundTripper{}
+			req, err := http.NewRequest(tt.method, "https://milo.example"+tt.path, strings.NewReader(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := WrapTransport(tt.dryRun)(next).RoundTrip(req); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(next.requests) != 1 {
+				t.Fatalf("expected 1 request, got %d", len(next.requests))
+			}
+			sent := next.requests[0]
+			if got := sent.URL.Query().Get("dryRun"); (got == "All") != tt.wantDryRun {
+				t.Errorf("expected dry run %t, got dryRun=%q", tt.wantDryRun, got)
+			}
+			if req.URL.RawQuery != "" {
+				t.Errorf("expected the original request to be unmodified, got query %q", req.URL.RawQuery)
+			}
+			data, err := io.ReadAll(sent.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != body {
+				t.Errorf("expected the body to be sent unchanged, got %q", data)
+			}
+		})
+	}
+}
+
+func TestCreatedName(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPost, "https://milo.example/apis/iam.miloapis.com/v1alpha1/users",
+		strings.NewReader(`{"metadata":{"generateName":"user-"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := createdName(req); name != "user-" {
+		t.Errorf("expected generate name user-, got %q", name)
+	}
+}
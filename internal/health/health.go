@@ -0,0 +1,85 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package health provides the readiness and liveness checks of the controller
+// manager.
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// longestRunningProcessorMetric is the metric controller-runtime reports the
+// duration of the longest running reconcile of each controller with.
+const longestRunningProcessorMetric = metrics.WorkQueueSubsystem + "_" + metrics.LongestRunningProcessorKey
+
+// cacheSyncTimeout bounds how long a readiness probe waits for the informer
+// caches.
+const cacheSyncTimeout = time.Second
+
+// CacheSyncCheck returns a check that passes once the informer caches have
+// synced.
+func CacheSyncCheck(c cache.Cache) healthz.Checker {
+	return func(req *http.Request) error {
+		ctx, cancel := context.WithTimeout(req.Context(), cacheSyncTimeout)
+		defer cancel()
+		if !c.WaitForCacheSync(ctx) {
+			return errors.New("informer caches have not synced")
+		}
+		return nil
+	}
+}
+
+// ObjectExistsCheck returns a check that passes while the object with the given
+// key exists. The object is only used to determine the kind to get.
+func ObjectExistsCheck(c client.Reader, obj client.Object, key client.ObjectKey) healthz.Checker {
+	return func(req *http.Request) error {
+		if err := c.Get(req.Context(), key, obj.DeepCopyObject().(client.Object)); err != nil {
+			return fmt.Errorf("failed to get %s: %w", key, err)
+		}
+		return nil
+	}
+}
+
+// ReconcileStallCheck returns a check failing while a reconcile of any
+// controller has been running for longer than the timeout, based on the work
+// queue metrics of controller-runtime.
+func ReconcileStallCheck(gatherer prometheus.Gatherer, timeout time.Duration) healthz.Checker {
+	return func(*http.Request) error {
+		families, err := gatherer.Gather()
+		if err != nil {
+			return fmt.Errorf("failed to gather metrics: %w", err)
+		}
+		var stalled []string
+		for _, family := range families {
+			if family.GetName() != longestRunningProcessorMetric {
+				continue
+			}
+			for _, metric := range family.GetMetric() {
+				if metric.GetGauge().GetValue() <= timeout.Seconds() {
+					continue
+				}
+				for _, label := range metric.GetLabel() {
+					if label.GetName() == "controller" {
+						stalled = append(stalled, label.GetValue())
+					}
+				}
+			}
+		}
+		if len(stalled) > 0 {
+			slices.Sort(stalled)
+			return fmt.Errorf("reconciles running for more than %s: %v", timeout, stalled)
+		}
+		return nil
+	}
+}
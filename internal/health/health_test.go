@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package health
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	iamv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
+)
+
+func TestReconcileStallCheck(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	longestRunning := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: longestRunningProcessorMetric,
+	}, []string{"name", "controller"})
+	registry.MustRegister(longestRunning)
+	check := ReconcileStallCheck(registry, time.Minute)
+	req := httptest.NewRequest("GET", "/healthz", nil)
+
+	longestRunning.WithLabelValues("user-waitlist", "user-waitlist").Set(30)
+	if err := check(req); err != nil {
+		t.Errorf("expected check to pass, got %v", err)
+	}
+
+	longestRunning.WithLabelValues("role-catalog", "role-catalog").Set(120)
+	if err := check(req); err == nil {
+		t.Error("expected check to fail for a stalled reconcile")
+	}
+}
+
+func TestCacheSyncCheck(t *testing.T) {
+	informers := &informertest.FakeInformers{Synced: ptr.To(false)}
+	check := CacheSyncCheck(informers)
+	req := httptest.NewRequest("GET", "/readyz", nil)
+
+	if err := check(req); err == nil {
+		t.Error("expected check to fail before the caches synced")
+	}
+	*informers.Synced = true
+	if err := check(req); err != nil {
+		t.Errorf("expected check to pass once the caches synced, got %v", err)
+	}
+}
+
+func TestObjectExistsCheck(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iamv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(&iamv1alpha1.Role{ObjectMeta: metav1.ObjectMeta{Name: "owner", Namespace: "datum-cloud"}}).
+		Build()
+	req := httptest.NewRequest("GET", "/readyz", nil)
+
+	if err := ObjectExistsCheck(c, &iamv1alpha1.Role{}, client.ObjectKey{Name: "owner", Namespace: "datum-cloud"})(req); err != nil {
+		t.Errorf("expected check to pass, got %v", err)
+	}
+	if err := ObjectExistsCheck(c, &iamv1alpha1.Role{}, client.ObjectKey{Name: "missing", Namespace: "datum-cloud"})(req); err == nil {
+		t.Error("expected check to fail for a missing role")
+	}
+}
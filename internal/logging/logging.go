@@ -0,0 +1,162 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package logging builds the structured logger of the controller manager.
+package logging
+
+import (
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/zapr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	crzap "sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+// RedactedValue replaces the values of redacted fields.
+const RedactedValue = "[REDACTED]"
+
+// Format is the encoding of log lines.
+type Format string
+
+const (
+	FormatJSON    Format = "json"
+	FormatConsole Format = "console"
+)
+
+// Options configure the logger.
+type Options struct {
+	// Format is the encoding of log lines. Defaults to JSON.
+	Format Format
+
+	// Level is the minimum level of logged entries.
+	Level zapcore.Level
+
+	// Levels overrides the level of named loggers and their descendants. The
+	// most specific name applies.
+	Levels map[string]zapcore.Level
+
+	// StacktraceLevel is the minimum level of entries logged with a stack
+	// trace.
+	StacktraceLevel zapcore.Level
+
+	// Sampling limits repeated entries to SamplingInitial entries per second,
+	// and every SamplingThereafter entry after that. Sampling is disabled when
+	// SamplingInitial is 0.
+	SamplingInitial    int
+	SamplingThereafter int
+
+	// RedactedKeys are the keys of fields whose values are replaced with
+	// RedactedValue.
+	RedactedKeys []string
+}
+
+// New returns a logger writing to w.
+func New(w io.Writer, opts Options) logr.Logger {
+	minLevel := opts.Level
+	for _, level := range opts.Levels {
+		minLevel = min(minLevel, level)
+	}
+
+	var encoder zapcore.Encoder
+	if opts.Format == FormatConsole {
+		encoderConfig := zap.NewDevelopmentEncoderConfig()
+		encoderConfig.EncodeTime = zapcore.RFC3339TimeEncoder
+		encoder = zapcore.NewConsoleEncoder(encoderConfig)
+	} else {
+		encoderConfig := zap.NewProductionEncoderConfig()
+		encoderConfig.EncodeTime = zapcore.RFC3339TimeEncoder
+		encoder = zapcore.NewJSONEncoder(encoderConfig)
+	}
+
+	sink := zapcore.AddSync(w)
+	var core zapcore.Core = zapcore.NewCore(&crzap.KubeAwareEncoder{Encoder: encoder}, sink, minLevel)
+	if len(opts.RedactedKeys) > 0 {
+		core = &redactingCore{Core: core, keys: opts.RedactedKeys}
+	}
+	// The sampler only supports levels down to debug.
+	if opts.SamplingInitial > 0 && minLevel >= zapcore.DebugLevel {
+		core = zapcore.NewSamplerWithOptions(core, time.Second, opts.SamplingInitial, opts.SamplingThereafter)
+	}
+	if len(opts.Levels) > 0 {
+		core = &namedLevelCore{Core: core, level: opts.Level, levels: opts.Levels}
+	}
+
+	return zapr.NewLogger(zap.New(core, zap.AddStacktrace(opts.StacktraceLevel), zap.ErrorOutput(sink)))
+}
+
+// namedLevelCore filters entries by the level of their logger name. The
+// wrapped core must enable the lowest configured level.
+type namedLevelCore struct {
+	zapcore.Core
+	level  zapcore.Level
+	levels map[string]zapcore.Level
+}
+
+func (c *namedLevelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &namedLevelCore{Core: c.Core.With(fields), level: c.level, levels: c.levels}
+}
+
+func (c *namedLevelCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if entry.Level < c.levelFor(entry.LoggerName) {
+		return checked
+	}
+	return c.Core.Check(entry, checked)
+}
+
+// levelFor returns the level of the most specific configured name that is the
+// logger name or one of its ancestors.
+func (c *namedLevelCore) levelFor(name string) zapcore.Level {
+	for name != "" {
+		if level, ok := c.levels[name]; ok {
+			return level
+		}
+		i := strings.LastIndex(name, ".")
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return c.level
+}
+
+// redactingCore replaces the values of fields with the configured keys.
+type redactingCore struct {
+	zapcore.Core
+	keys []string
+}
+
+func (c *redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactingCore{Core: c.Core.With(c.redact(fields)), keys: c.keys}
+}
+
+func (c *redactingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *redactingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(entry, c.redact(fields))
+}
+
+func (c *redactingCore) redact(fields []zapcore.Field) []zapcore.Field {
+	var redacted []zapcore.Field
+	for i, field := range fields {
+		if !slices.Contains(c.keys, field.Key) {
+			continue
+		}
+		if redacted == nil {
+			redacted = slices.Clone(fields)
+		}
+		redacted[i] = zap.String(field.Key, RedactedValue)
+	}
+	if redacted == nil {
+		return fields
+	}
+	return redacted
+}
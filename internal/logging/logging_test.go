@@ -0,0 +1,63 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, Options{
+		Format: FormatJSON,
+		Level:  zapcore.InfoLevel,
+		Levels: map[string]zapcore.Level{
+			"noisy":       zapcore.ErrorLevel,
+			"noisy.debug": zapcore.DebugLevel,
+		},
+		StacktraceLevel: zapcore.PanicLevel,
+		RedactedKeys:    []string{"email", "givenName"},
+	})
+
+	logger.Info("invitation accepted", "email", "alice@example.com", "user", "user-1")
+	logger.WithValues("givenName", "Alice").Info("with values")
+	logger.V(1).Info("debug is filtered")
+	logger.WithName("noisy").Info("info is filtered for noisy")
+	logger.WithName("noisy").WithName("child").Error(nil, "errors are logged for noisy children")
+	logger.WithName("noisy").WithName("debug").V(1).Info("debug is logged for noisy.debug")
+
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		entry := map[string]any{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("failed to parse log line %q: %v", line, err)
+		}
+		lines = append(lines, entry)
+	}
+
+	var messages []string
+	for _, line := range lines {
+		messages = append(messages, line["msg"].(string))
+	}
+	want := []string{
+		"invitation accepted",
+		"with values",
+		"errors are logged for noisy children",
+		"debug is logged for noisy.debug",
+	}
+	if strings.Join(messages, "|") != strings.Join(want, "|") {
+		t.Fatalf("logged messages = %q, want %q", messages, want)
+	}
+
+	if lines[0]["email"] != RedactedValue || lines[0]["user"] != "user-1" {
+		t.Errorf("expected email to be redacted, got %v", lines[0])
+	}
+	if lines[1]["givenName"] != RedactedValue {
+		t.Errorf("expected given name to be redacted, got %v", lines[1])
+	}
+}
@@ -0,0 +1,194 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package manifest loads Kubernetes manifests from disk while keeping track of
+// the file and line each document, and each field within it, came from.
+package manifest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+// Object is a single YAML document loaded from a manifest file.
+type Object struct {
+	// Path is the file the document was read from.
+	Path string
+
+	// Line is the line the document starts on.
+	Line int
+
+	// Object is the decoded document.
+	Object *unstructured.Unstructured
+
+	// Raw is the JSON encoding of the document.
+	Raw []byte
+
+	node *yaml.Node
+}
+
+// GroupVersionKind returns the GroupVersionKind of the document.
+func (o *Object) GroupVersionKind() schema.GroupVersionKind {
+	return o.Object.GroupVersionKind()
+}
+
+// String returns a human readable reference to the document.
+func (o *Object) String() string {
+	return fmt.Sprintf("%s %q", o.Object.GetKind(), o.Object.GetName())
+}
+
+// LineOf returns the line of the field at the given path within the document,
+// where list items are addressed by their index. When the path can not be
+// resolved, the line of the closest parent that could be is returned.
+func (o *Object) LineOf(path ...string) int {
+	node := o.node
+	line := o.Line
+	for _, segment := range path {
+		next := child(node, segment)
+		if next == nil {
+			break
+		}
+		node = next
+		line = node.Line
+	}
+	return line
+}
+
+func child(node *yaml.Node, segment string) *yaml.Node {
+	if node == nil {
+		return nil
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == segment {
+				return node.Content[i+1]
+			}
+		}
+	case yaml.SequenceNode:
+		index, err := strconv.Atoi(segment)
+		if err == nil && index >= 0 && index < len(node.Content) {
+			return node.Content[index]
+		}
+	}
+	return nil
+}
+
+// Load reads all YAML documents from the given files and directories.
+// Directories are walked recursively. Kustomization files and testdata
+// directories are skipped, as are empty documents and kustomize patch
+// directives such as `$patch: delete`.
+func Load(paths ...string) ([]Object, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		err = filepath.WalkDir(path, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if d.Name() == "testdata" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if isManifest(d.Name()) {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(files)
+
+	var objects []Object
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		fileObjects, err := Parse(file, data)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, fileObjects...)
+	}
+	return objects, nil
+}
+
+func isManifest(name string) bool {
+	switch name {
+	case "kustomization.yaml", "kustomization.yml", "Kustomization":
+		return false
+	}
+	ext := filepath.Ext(name)
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// Parse decodes all YAML documents in data. The path is only used to annotate
+// the returned objects and errors.
+func Parse(path string, data []byte) ([]Object, error) {
+	var objects []Object
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc yaml.Node
+		if err := decoder.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if len(doc.Content) == 0 {
+			continue
+		}
+		root := doc.Content[0]
+		if root.Kind != yaml.MappingNode || len(root.Content) == 0 {
+			continue
+		}
+		if child(root, "$patch") != nil {
+			continue
+		}
+
+		encoded, err := yaml.Marshal(root)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, root.Line, err)
+		}
+		raw, err := sigsyaml.YAMLToJSON(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, root.Line, err)
+		}
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(raw); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, root.Line, err)
+		}
+
+		objects = append(objects, Object{
+			Path:   path,
+			Line:   root.Line,
+			Object: obj,
+			Raw:    raw,
+			node:   root,
+		})
+	}
+	return objects, nil
+}
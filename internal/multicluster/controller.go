@@ -0,0 +1,73 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package multicluster
+
+import (
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"go.datum.net/datum/internal/tracing"
+)
+
+// Controller reconciles objects of a type in every project control plane.
+// Each control plane has its own controller, sharing the name, and thus the
+// metrics, of the others.
+type Controller struct {
+	// Manager provides the defaults of the controllers, such as the logger.
+	Manager manager.Manager
+
+	// Name is the name of the controllers.
+	Name string
+
+	// For is the type of the reconciled objects.
+	For client.Object
+
+	// Options configures the workers of the controllers. Its rate limiter is
+	// ignored, as the requests of a control plane must not delay the others.
+	Options controller.Options
+
+	// NewRateLimiter returns the rate limiter of the controller of a control
+	// plane, called once per control plane. Defaults to the rate limiter of
+	// controller-runtime.
+	NewRateLimiter func() workqueue.TypedRateLimiter[reconcile.Request]
+
+	// New returns the reconciler of the control plane of a project.
+	New func(project string, cl cluster.Cluster) reconcile.Reconciler
+}
+
+// Engage implements Aware.
+func (c *Controller) Engage(project string, cl cluster.Cluster) (manager.Runnable, error) {
+	opts := c.Options
+	opts.RateLimiter = nil
+	if c.NewRateLimiter != nil {
+		opts.RateLimiter = c.NewRateLimiter()
+	}
+	opts.Reconciler = tracing.Reconciler(c.Name, c.New(project, cl))
+	opts.SkipNameValidation = ptr.To(true)
+	log := c.Manager.GetLogger().WithValues("controller", c.Name, "project", project)
+	opts.LogConstructor = func(req *reconcile.Request) logr.Logger {
+		if req == nil {
+			return log
+		}
+		return log.WithValues("namespace", req.Namespace, "name", req.Name)
+	}
+
+	ctrl, err := controller.NewUnmanaged(c.Name, c.Manager, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create controller %s: %w", c.Name, err)
+	}
+	if err := ctrl.Watch(source.Kind(cl.GetCache(), c.For, &handler.EnqueueRequestForObject{})); err != nil {
+		return nil, fmt.Errorf("failed to watch %T: %w", c.For, err)
+	}
+	return ctrl, nil
+}
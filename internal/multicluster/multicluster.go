@@ -0,0 +1,253 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package multicluster runs controllers against the control planes of Milo
+// projects. Every project has its own control plane, served by the root API
+// server. The Provider discovers the projects of the root control plane and
+// engages the registered controllers with the control plane of each project,
+// each with its own cache.
+//
+// The controller manager does not run the Provider yet, as no controller acts
+// on project-scoped resources.
+package multicluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/discovery"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	resourcemanagerv1alpha1 "go.miloapis.com/milo/pkg/apis/resourcemanager/v1alpha1"
+
+	"go.datum.net/datum/internal/tracing"
+)
+
+var engagedControlPlanes = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "datum_project_control_planes",
+	Help: "Number of project control planes the controllers are engaged with.",
+})
+
+func init() {
+	metrics.Registry.MustRegister(engagedControlPlanes)
+}
+
+// Aware is implemented by controllers running against every project control
+// plane.
+type Aware interface {
+	// Engage returns the controller for the control plane of the project. The
+	// controller is started by the Provider once the cache of the control
+	// plane is running, and stopped when the project is removed.
+	Engage(project string, cl cluster.Cluster) (manager.Runnable, error)
+}
+
+// Provider engages controllers with the control planes of the projects of
+// the root control plane. It reconciles Projects, and runs on the leader
+// only.
+type Provider struct {
+	// Client reads the projects of the root control plane.
+	Client client.Client
+
+	// NewCluster returns the cluster of the control plane of a project. Each
+	// cluster should have its own client rate limiter rather than sharing
+	// the one of the root client.
+	NewCluster func(project string) (cluster.Cluster, error)
+
+	// SyncTimeout is how long to wait for the cache of a control plane to
+	// sync before retrying.
+	SyncTimeout time.Duration
+
+	// Controllers are engaged with every project control plane.
+	Controllers []Aware
+
+	// Options configures the workers and rate limiting of the controller
+	// reconciling Projects.
+	Options controller.Options
+
+	mu sync.Mutex
+	// ctx is the parent of the contexts of the control planes, canceled when
+	// the provider stops.
+	ctx    context.Context
+	cancel context.CancelFunc
+	// clusters are the engaged control planes, by project name.
+	clusters map[string]*engagedCluster
+	// failures receive the projects whose control plane stopped with an
+	// error, to engage them again.
+	failures chan event.GenericEvent
+}
+
+type engagedCluster struct {
+	cluster cluster.Cluster
+	cancel  context.CancelFunc
+}
+
+// +kubebuilder:rbac:groups=resourcemanager.miloapis.com,resources=projects,verbs=get;list;watch
+
+// Reconcile engages the controllers with the control plane of a project, and
+// disengages them once the project is deleted.
+func (p *Provider) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := logf.FromContext(ctx)
+	p.init()
+
+	project := &resourcemanagerv1alpha1.Project{}
+	if err := p.Client.Get(ctx, req.NamespacedName, project); client.IgnoreNotFound(err) != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to get project: %w", err)
+	} else if err != nil || !project.DeletionTimestamp.IsZero() {
+		if p.disengage(req.Name) {
+			logger.Info("Disengaged project control plane")
+		}
+		return ctrl.Result{}, nil
+	}
+
+	if _, err := p.Get(req.Name); err == nil {
+		return ctrl.Result{}, nil
+	}
+
+	cl, err := p.NewCluster(req.Name)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to create cluster of project control plane: %w", err)
+	}
+	// Fail fast when the control plane is not served yet, rather than waiting
+	// for the caches of the controllers to time out.
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(cl.GetConfig())
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to create discovery client of project control plane: %w", err)
+	}
+	if _, err := discoveryClient.ServerVersion(); err != nil {
+		return ctrl.Result{}, fmt.Errorf("project control plane is not reachable: %w", err)
+	}
+
+	runnables := []manager.Runnable{cl}
+	for _, aware := range p.Controllers {
+		runnable, err := aware.Engage(req.Name, cl)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to engage controller with project control plane: %w", err)
+		}
+		runnables = append(runnables, runnable)
+	}
+
+	clusterCtx, cancel := context.WithCancel(logf.IntoContext(p.ctx, logger))
+	p.mu.Lock()
+	p.clusters[req.Name] = &engagedCluster{cluster: cl, cancel: cancel}
+	engagedControlPlanes.Set(float64(len(p.clusters)))
+	p.mu.Unlock()
+
+	go p.run(clusterCtx, req.Name, cl)
+
+	syncCtx, cancelSync := context.WithTimeout(ctx, p.SyncTimeout)
+	defer cancelSync()
+	if !cl.GetCache().WaitForCacheSync(syncCtx) {
+		p.disengage(req.Name)
+		return ctrl.Result{}, errors.New("cache of project control plane did not sync")
+	}
+
+	// The controllers wait for the caches of their sources to sync, and fail
+	// if they do not sync in time.
+	for _, runnable := range runnables[1:] {
+		go p.run(clusterCtx, req.Name, runnable)
+	}
+
+	logger.Info("Engaged project control plane", "controllers", len(p.Controllers))
+	return ctrl.Result{}, nil
+}
+
+// Get returns the cluster of the control plane of an engaged project.
+func (p *Provider) Get(project string) (cluster.Cluster, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	engaged, ok := p.clusters[project]
+	if !ok {
+		return nil, fmt.Errorf("control plane of project %s is not engaged", project)
+	}
+	return engaged.cluster, nil
+}
+
+// run runs a runnable of the control plane of a project until ctx is done.
+// When it stops with an error, the control plane is disengaged and the
+// project is reconciled again.
+func (p *Provider) run(ctx context.Context, project string, runnable manager.Runnable) {
+	err := runnable.Start(ctx)
+	if err == nil || ctx.Err() != nil {
+		return
+	}
+
+	logf.FromContext(ctx).Error(err, "Project control plane stopped")
+	p.disengage(project)
+	failed := &resourcemanagerv1alpha1.Project{}
+	failed.Name = project
+	select {
+	case p.failures <- event.GenericEvent{Object: failed}:
+	case <-p.ctx.Done():
+	}
+}
+
+// disengage stops the controllers and the cache of the control plane of a
+// project, and reports whether it was engaged.
+func (p *Provider) disengage(project string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	engaged, ok := p.clusters[project]
+	if !ok {
+		return false
+	}
+	engaged.cancel()
+	delete(p.clusters, project)
+	engagedControlPlanes.Set(float64(len(p.clusters)))
+	return true
+}
+
+func (p *Provider) init() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.ctx == nil {
+		p.ctx, p.cancel = context.WithCancel(context.Background())
+		p.clusters = make(map[string]*engagedCluster)
+		p.failures = make(chan event.GenericEvent)
+	}
+}
+
+// Stop disengages every project control plane.
+func (p *Provider) Stop() {
+	p.init()
+	p.cancel()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	clear(p.clusters)
+	engagedControlPlanes.Set(0)
+}
+
+// SetupWithManager sets up the provider with the Manager. The control planes
+// are disengaged when the manager stops. A provider without controllers is
+// refused, as it would connect to every control plane for nothing.
+func (p *Provider) SetupWithManager(mgr ctrl.Manager) error {
+	if len(p.Controllers) == 0 {
+		return errors.New("no controller to engage with project control planes")
+	}
+	p.init()
+	if err := mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		p.Stop()
+		return nil
+	})); err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&resourcemanagerv1alpha1.Project{}).
+		Named("project-control-plane").
+		WatchesRawSource(source.Channel(p.failures, &handler.EnqueueRequestForObject{})).
+		WithOptions(p.Options).
+		Complete(tracing.Reconciler("project-control-plane", p))
+}
@@ -0,0 +1,229 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package multicluster
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	resourcemanagerv1alpha1 "go.miloapis.com/milo/pkg/apis/resourcemanager/v1alpha1"
+)
+
+func newScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := resourcemanagerv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return scheme
+}
+
+func newProject(name string) *resourcemanagerv1alpha1.Project {
+	return &resourcemanagerv1alpha1.Project{ObjectMeta: metav1.ObjectMeta{Name: name}}
+}
+
+func reconcileProject(t *testing.T, p *Provider, project string) error {
+	t.Helper()
+	_, err := p.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Name: project}})
+	return err
+}
+
+func TestProviderUnreachableControlPlane(t *testing.T) {
+	root := fake.NewClientBuilder().WithScheme(newScheme(t)).WithObjects(newProject("alpha")).Build()
+	p := &Provider{
+		Client: root,
+		NewCluster: func(string) (cluster.Cluster, error) {
+			return nil, errors.New("control plane not found")
+		},
+		SyncTimeout: time.Second,
+	}
+	defer p.Stop()
+
+	if err := reconcileProject(t, p, "alpha"); err == nil {
+		t.Error("expected an unreachable control plane to be retried")
+	}
+	if _, err := p.Get("alpha"); err == nil {
+		t.Error("expected an unreachable control plane not to be engaged")
+	}
+
+	if err := reconcileProject(t, p, "deleted"); err != nil {
+		t.Errorf("expected a deleted project to be ignored, got %v", err)
+	}
+}
+
+func TestControllerEngage(t *testing.T) {
+	scheme := newScheme(t)
+	config := &rest.Config{Host: "https://127.0.0.1:6443"}
+	mgr, err := ctrl.NewManager(config, ctrl.Options{
+		Scheme:  scheme,
+		Metrics: metricsserver.Options{BindAddress: "0"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var limiters []workqueue.TypedRateLimiter[reconcile.Request]
+	c := &Controller{
+		Manager: mgr,
+		Name:    "configmaps",
+		For:     &corev1.ConfigMap{},
+		Options: controller.Options{RateLimiter: workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]()},
+		NewRateLimiter: func() workqueue.TypedRateLimiter[reconcile.Request] {
+			limiter := workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]()
+			limiters = append(limiters, limiter)
+			return limiter
+		},
+		New: func(string, cluster.Cluster) reconcile.Reconciler {
+			return reconcile.Func(func(context.Context, reconcile.Request) (reconcile.Result, error) {
+				return reconcile.Result{}, nil
+			})
+		},
+	}
+	for _, project := range []string{"alpha", "beta"} {
+		cl, err := cluster.New(config, func(o *cluster.Options) {
+			o.Scheme = scheme
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.Engage(project, cl); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Every control plane has its own rate limiter.
+	if len(limiters) != 2 || limiters[0] == limiters[1] {
+		t.Errorf("expected a rate limiter per control plane, got %d", len(limiters))
+	}
+
+	// A provider without controllers is not set up.
+	if err := (&Provider{Client: mgr.GetClient()}).SetupWithManager(mgr); err == nil {
+		t.Error("expected a provider without controllers to be refused")
+	}
+}
+
+// TestProvider engages a controller with two project control planes, each
+// served by its own envtest API server. It requires the envtest binaries, set
+// up by make test.
+func TestProvider(t *testing.T) {
+	if os.Getenv("KUBEBUILDER_ASSETS") == "" {
+		t.Skip("KUBEBUILDER_ASSETS is not set")
+	}
+	ctx := context.Background()
+	scheme := newScheme(t)
+
+	configs := map[string]*rest.Config{}
+	for _, project := range []string{"alpha", "beta"} {
+		env := &envtest.Environment{}
+		cfg, err := env.Start()
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			if err := env.Stop(); err != nil {
+				t.Error(err)
+			}
+		})
+		configs[project] = cfg
+	}
+
+	// The manager provides the defaults of the controllers, and is not
+	// started.
+	mgr, err := ctrl.NewManager(configs["alpha"], ctrl.Options{
+		Scheme:  scheme,
+		Metrics: metricsserver.Options{BindAddress: "0"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reconciled := make(chan string, 100)
+	root := fake.NewClientBuilder().WithScheme(scheme).WithObjects(newProject("alpha"), newProject("beta")).Build()
+	p := &Provider{
+		Client: root,
+		NewCluster: func(project string) (cluster.Cluster, error) {
+			return cluster.New(configs[project], func(o *cluster.Options) {
+				o.Scheme = scheme
+			})
+		},
+		SyncTimeout: time.Minute,
+		Controllers: []Aware{&Controller{
+			Manager: mgr,
+			Name:    "configmaps",
+			For:     &corev1.ConfigMap{},
+			New: func(project string, _ cluster.Cluster) reconcile.Reconciler {
+				return reconcile.Func(func(_ context.Context, req reconcile.Request) (reconcile.Result, error) {
+					if req.Namespace == metav1.NamespaceDefault {
+						reconciled <- project + "/" + req.Name
+					}
+					return reconcile.Result{}, nil
+				})
+			},
+		}},
+	}
+	defer p.Stop()
+
+	for project, cfg := range configs {
+		if err := reconcileProject(t, p, project); err != nil {
+			t.Fatal(err)
+		}
+		c, err := client.New(cfg, client.Options{Scheme: scheme})
+		if err != nil {
+			t.Fatal(err)
+		}
+		configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: project + "-config"}}
+		if err := c.Create(ctx, configMap); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Each control plane only reconciles its own ConfigMap.
+	expected := map[string]bool{"alpha/alpha-config": true, "beta/beta-config": true}
+	timeout := time.After(30 * time.Second)
+	for len(expected) > 0 {
+		select {
+		case key := <-reconciled:
+			if key == "alpha/beta-config" || key == "beta/alpha-config" {
+				t.Fatalf("expected control planes to be isolated, got %s", key)
+			}
+			delete(expected, key)
+		case <-timeout:
+			t.Fatalf("expected %v to be reconciled", expected)
+		}
+	}
+
+	if err := root.Delete(ctx, newProject("beta")); err != nil {
+		t.Fatal(err)
+	}
+	if err := reconcileProject(t, p, "beta"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Get("beta"); err == nil {
+		t.Error("expected the control plane of a deleted project to be disengaged")
+	}
+	if _, err := p.Get("alpha"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,81 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package policy
+
+import (
+	"fmt"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apiserver/pkg/admission/plugin/cel"
+	"k8s.io/apiserver/pkg/admission/plugin/policy/validating"
+	"k8s.io/apiserver/pkg/admission/plugin/webhook/matchconditions"
+	"k8s.io/apiserver/pkg/cel/environment"
+)
+
+// expressionError is a CEL compilation error for the expression at the given
+// field path of a ValidatingAdmissionPolicy.
+type expressionError struct {
+	path []string
+	err  error
+}
+
+// admissionCompiler compiles the CEL expressions of ValidatingAdmissionPolicies
+// the same way the kube-apiserver does.
+type admissionCompiler struct {
+	*cel.CompositedCompiler
+
+	vars     cel.OptionalVariableDeclarations
+	exprVars cel.OptionalVariableDeclarations
+}
+
+func newAdmissionCompiler(policy *admissionregistrationv1.ValidatingAdmissionPolicy) (*admissionCompiler, error) {
+	compiler, err := cel.NewCompositedCompiler(environment.MustBaseEnvSet(environment.DefaultCompatibilityVersion(), true))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL compiler: %w", err)
+	}
+	hasParams := policy.Spec.ParamKind != nil
+	return &admissionCompiler{
+		CompositedCompiler: compiler,
+		vars:               cel.OptionalVariableDeclarations{HasParams: hasParams, HasAuthorizer: true, StrictCost: true},
+		exprVars:           cel.OptionalVariableDeclarations{HasParams: hasParams, HasAuthorizer: false, StrictCost: true},
+	}, nil
+}
+
+// compileErrors compiles every expression in the policy individually and
+// returns the errors found, keyed by the field path of the expression.
+func compileErrors(policy *admissionregistrationv1.ValidatingAdmissionPolicy) ([]expressionError, error) {
+	compiler, err := newAdmissionCompiler(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []expressionError
+	check := func(result cel.CompilationResult, path ...string) {
+		if result.Error != nil {
+			errs = append(errs, expressionError{path: path, err: result.Error})
+		}
+	}
+
+	for i := range policy.Spec.Variables {
+		v := policy.Spec.Variables[i]
+		check(compiler.CompileAndStoreVariable(&validating.Variable{Name: v.Name, Expression: v.Expression}, compiler.vars, environment.StoredExpressions),
+			"spec", "variables", fmt.Sprint(i), "expression")
+	}
+	for i := range policy.Spec.MatchConditions {
+		check(compiler.CompileCELExpression((*matchconditions.MatchCondition)(&policy.Spec.MatchConditions[i]), compiler.vars, environment.StoredExpressions),
+			"spec", "matchConditions", fmt.Sprint(i), "expression")
+	}
+	for i, v := range policy.Spec.Validations {
+		check(compiler.CompileCELExpression(&validating.ValidationCondition{Expression: v.Expression}, compiler.vars, environment.StoredExpressions),
+			"spec", "validations", fmt.Sprint(i), "expression")
+		if v.MessageExpression != "" {
+			check(compiler.CompileCELExpression(&validating.MessageExpressionCondition{MessageExpression: v.MessageExpression}, compiler.exprVars, environment.StoredExpressions),
+				"spec", "validations", fmt.Sprint(i), "messageExpression")
+		}
+	}
+	for i, a := range policy.Spec.AuditAnnotations {
+		check(compiler.CompileCELExpression(&validating.AuditAnnotationCondition{Key: a.Key, ValueExpression: a.ValueExpression}, compiler.vars, environment.StoredExpressions),
+			"spec", "auditAnnotations", fmt.Sprint(i), "valueExpression")
+	}
+	return errs, nil
+}
@@ -0,0 +1,13 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package policy_test
+
+import (
+	"testing"
+
+	"go.datum.net/datum/internal/policy/policytest"
+)
+
+func TestAdmissionPolicies(t *testing.T) {
+	policytest.Run(t, "../../config/services")
+}
@@ -0,0 +1,245 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package policy
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/apiserver/pkg/admission/plugin/cel"
+	"k8s.io/apiserver/pkg/admission/plugin/policy/validating"
+	"k8s.io/apiserver/pkg/admission/plugin/webhook/matchconditions"
+	celconfig "k8s.io/apiserver/pkg/apis/cel"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/apiserver/pkg/cel/environment"
+
+	"go.datum.net/datum/internal/manifest"
+)
+
+// AdmissionPolicy is a ValidatingAdmissionPolicy compiled with the upstream
+// CEL admission library so it can be evaluated without an API server.
+type AdmissionPolicy struct {
+	Policy *admissionregistrationv1.ValidatingAdmissionPolicy
+
+	validator validating.Validator
+}
+
+// Decision is the outcome of evaluating an admission request against a policy.
+type Decision struct {
+	// Matched is false when the request is not selected by the policy's match
+	// constraints, in which case the request is always allowed.
+	Matched bool
+
+	Allowed bool
+	Message string
+	Reason  metav1.StatusReason
+}
+
+// CompileAdmissionPolicy compiles all expressions of the policy.
+func CompileAdmissionPolicy(policy *admissionregistrationv1.ValidatingAdmissionPolicy) (*AdmissionPolicy, error) {
+	if errs, err := compileErrors(policy); err != nil {
+		return nil, err
+	} else if len(errs) > 0 {
+		return nil, fmt.Errorf("policy %q does not compile: %v", policy.Name, errs[0].err)
+	}
+
+	compiler, err := newAdmissionCompiler(policy)
+	if err != nil {
+		return nil, err
+	}
+	variables := make([]cel.NamedExpressionAccessor, len(policy.Spec.Variables))
+	for i, v := range policy.Spec.Variables {
+		variables[i] = &validating.Variable{Name: v.Name, Expression: v.Expression}
+	}
+	compiler.CompileAndStoreVariables(variables, compiler.vars, environment.StoredExpressions)
+
+	var matcher matchconditions.Matcher
+	if len(policy.Spec.MatchConditions) > 0 {
+		conditions := make([]cel.ExpressionAccessor, len(policy.Spec.MatchConditions))
+		for i := range policy.Spec.MatchConditions {
+			conditions[i] = (*matchconditions.MatchCondition)(&policy.Spec.MatchConditions[i])
+		}
+		matcher = matchconditions.NewMatcher(compiler.CompileCondition(conditions, compiler.vars, environment.StoredExpressions),
+			policy.Spec.FailurePolicy, "policy", "validate", policy.Name)
+	}
+
+	validations := make([]cel.ExpressionAccessor, len(policy.Spec.Validations))
+	messages := make([]cel.ExpressionAccessor, len(policy.Spec.Validations))
+	for i, v := range policy.Spec.Validations {
+		validations[i] = &validating.ValidationCondition{Expression: v.Expression, Message: v.Message, Reason: v.Reason}
+		if v.MessageExpression != "" {
+			messages[i] = &validating.MessageExpressionCondition{MessageExpression: v.MessageExpression}
+		}
+	}
+	auditAnnotations := make([]cel.ExpressionAccessor, len(policy.Spec.AuditAnnotations))
+	for i, a := range policy.Spec.AuditAnnotations {
+		auditAnnotations[i] = &validating.AuditAnnotationCondition{Key: a.Key, ValueExpression: a.ValueExpression}
+	}
+
+	return &AdmissionPolicy{
+		Policy: policy,
+		validator: validating.NewValidator(
+			compiler.CompileCondition(validations, compiler.vars, environment.StoredExpressions),
+			matcher,
+			compiler.CompileCondition(auditAnnotations, compiler.vars, environment.StoredExpressions),
+			compiler.CompileCondition(messages, compiler.exprVars, environment.StoredExpressions),
+			policy.Spec.FailurePolicy,
+		),
+	}, nil
+}
+
+// LoadAdmissionPolicies compiles all ValidatingAdmissionPolicies in objects,
+// keyed by policy name.
+func LoadAdmissionPolicies(objects []manifest.Object) (map[string]*AdmissionPolicy, error) {
+	policies := map[string]*AdmissionPolicy{}
+	for _, obj := range objects {
+		if obj.GroupVersionKind().GroupKind() != validatingAdmissionPolicyKind {
+			continue
+		}
+		policy := &admissionregistrationv1.ValidatingAdmissionPolicy{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object.Object, policy); err != nil {
+			return nil, fmt.Errorf("%s:%d: failed to decode %s: %w", obj.Path, obj.Line, obj.String(), err)
+		}
+		compiled, err := CompileAdmissionPolicy(policy)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", obj.Path, obj.Line, err)
+		}
+		policies[policy.Name] = compiled
+	}
+	return policies, nil
+}
+
+// Evaluate evaluates the admission request against the policy. Namespace and
+// object selectors of the policy are not evaluated, and the authorizer
+// available to expressions has no opinion on any request.
+func (p *AdmissionPolicy) Evaluate(ctx context.Context, request *admissionv1.AdmissionRequest) (Decision, error) {
+	if !p.matches(request) {
+		return Decision{Allowed: true}, nil
+	}
+
+	object, err := decodeRawObject(request.Object)
+	if err != nil {
+		return Decision{}, fmt.Errorf("invalid object: %w", err)
+	}
+	oldObject, err := decodeRawObject(request.OldObject)
+	if err != nil {
+		return Decision{}, fmt.Errorf("invalid oldObject: %w", err)
+	}
+
+	kind := schema.GroupVersionKind(request.Kind)
+	if kind.Empty() {
+		switch {
+		case object != nil:
+			kind = object.GetObjectKind().GroupVersionKind()
+		case oldObject != nil:
+			kind = oldObject.GetObjectKind().GroupVersionKind()
+		}
+	}
+	resource := schema.GroupVersionResource(request.Resource)
+
+	userInfo := &user.DefaultInfo{
+		Name:   request.UserInfo.Username,
+		UID:    request.UserInfo.UID,
+		Groups: request.UserInfo.Groups,
+	}
+	if len(request.UserInfo.Extra) > 0 {
+		userInfo.Extra = map[string][]string{}
+		for k, v := range request.UserInfo.Extra {
+			userInfo.Extra[k] = v
+		}
+	}
+
+	attributes := admission.NewAttributesRecord(
+		object, oldObject, kind, request.Namespace, request.Name, resource, request.SubResource,
+		admission.Operation(request.Operation), nil, request.DryRun != nil && *request.DryRun, userInfo,
+	)
+	versionedAttributes := &admission.VersionedAttributes{
+		Attributes:         attributes,
+		VersionedKind:      kind,
+		VersionedObject:    object,
+		VersionedOldObject: oldObject,
+	}
+
+	var namespace *corev1.Namespace
+	if request.Namespace != "" {
+		namespace = &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: request.Namespace}}
+	}
+
+	result := p.validator.Validate(ctx, resource, versionedAttributes, nil, namespace, celconfig.RuntimeCELCostBudget, noOpinionAuthorizer)
+
+	decision := Decision{Matched: true, Allowed: true}
+	for _, d := range result.Decisions {
+		if d.Action == validating.ActionDeny {
+			decision.Allowed = false
+			decision.Message = d.Message
+			decision.Reason = d.Reason
+			break
+		}
+	}
+	return decision, nil
+}
+
+var noOpinionAuthorizer = authorizer.AuthorizerFunc(func(context.Context, authorizer.Attributes) (authorizer.Decision, string, error) {
+	return authorizer.DecisionNoOpinion, "", nil
+})
+
+func decodeRawObject(raw runtime.RawExtension) (runtime.Object, error) {
+	if len(raw.Raw) == 0 {
+		return nil, nil
+	}
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(raw.Raw); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// matches returns true when the request is selected by the resource rules of
+// the policy's match constraints.
+func (p *AdmissionPolicy) matches(request *admissionv1.AdmissionRequest) bool {
+	constraints := p.Policy.Spec.MatchConstraints
+	if constraints == nil {
+		return false
+	}
+	for _, rule := range constraints.ExcludeResourceRules {
+		if ruleMatches(rule, request) {
+			return false
+		}
+	}
+	for _, rule := range constraints.ResourceRules {
+		if ruleMatches(rule, request) {
+			return true
+		}
+	}
+	return false
+}
+
+func ruleMatches(rule admissionregistrationv1.NamedRuleWithOperations, request *admissionv1.AdmissionRequest) bool {
+	resource := request.Resource.Resource
+	if request.SubResource != "" {
+		resource += "/" + request.SubResource
+	}
+	operations := make([]string, len(rule.Operations))
+	for i, op := range rule.Operations {
+		operations[i] = string(op)
+	}
+	return matchesAny(operations, string(request.Operation)) &&
+		matchesAny(rule.APIGroups, request.Resource.Group) &&
+		matchesAny(rule.APIVersions, request.Resource.Version) &&
+		matchesAny(rule.Resources, resource) &&
+		(len(rule.ResourceNames) == 0 || slices.Contains(rule.ResourceNames, request.Name))
+}
+
+func matchesAny(values []string, value string) bool {
+	return slices.Contains(values, "*") || slices.Contains(values, value)
+}
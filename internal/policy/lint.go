@@ -0,0 +1,379 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package policy validates and evaluates the quota, search and admission
+// policy manifests Datum ships for Milo services.
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"go.datum.net/datum/internal/manifest"
+)
+
+// Severity is the severity of a lint finding.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Finding is a single problem found in a manifest.
+type Finding struct {
+	Path     string
+	Line     int
+	Severity Severity
+	Object   string
+	Message  string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s:%d: %s: %s: %s", f.Path, f.Line, f.Severity, f.Object, f.Message)
+}
+
+// HasErrors returns true if any of the findings is an error.
+func HasErrors(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Lint validates the quota, search, notification and admission policies in the
+// given objects. Objects of other kinds are ignored.
+func Lint(objects []manifest.Object) []Finding {
+	l := &linter{
+		registrations: map[string]bool{},
+		grants:        map[string]bool{},
+		policies:      map[string]bool{},
+	}
+	checks := l.checks()
+	for i := range objects {
+		obj := &objects[i]
+		check, ok := checks[obj.GroupVersionKind().GroupKind()]
+		if !ok {
+			continue
+		}
+		if !l.decodeStrict(obj) {
+			continue
+		}
+		check(obj)
+	}
+	l.checkReferences()
+
+	sort.SliceStable(l.findings, func(i, j int) bool {
+		if l.findings[i].Path != l.findings[j].Path {
+			return l.findings[i].Path < l.findings[j].Path
+		}
+		return l.findings[i].Line < l.findings[j].Line
+	})
+	return l.findings
+}
+
+var (
+	grantCreationPolicyKind         = schema.GroupKind{Group: "quota.miloapis.com", Kind: "GrantCreationPolicy"}
+	claimCreationPolicyKind         = schema.GroupKind{Group: "quota.miloapis.com", Kind: "ClaimCreationPolicy"}
+	resourceRegistrationKind        = schema.GroupKind{Group: "quota.miloapis.com", Kind: "ResourceRegistration"}
+	resourceIndexPolicyKind         = schema.GroupKind{Group: "search.miloapis.com", Kind: "ResourceIndexPolicy"}
+	contactGroupEnrollmentKind      = schema.GroupKind{Group: "notification.miloapis.com", Kind: "ContactGroupEnrollmentPolicy"}
+	validatingAdmissionPolicyKind   = schema.GroupKind{Group: "admissionregistration.k8s.io", Kind: "ValidatingAdmissionPolicy"}
+	validatingAdmissionBindingKind  = schema.GroupKind{Group: "admissionregistration.k8s.io", Kind: "ValidatingAdmissionPolicyBinding"}
+	claimTemplateVariables          = []string{"trigger", "requestInfo", "user"}
+	grantTemplateVariables          = []string{"trigger"}
+	resourceIndexConditionVariables = []string{"metadata", "spec", "status"}
+)
+
+type reference struct {
+	obj  *manifest.Object
+	path []string
+	name string
+}
+
+type linter struct {
+	findings []Finding
+
+	// Resource types registered and granted by the linted manifests, and the
+	// resource types claimed by claim policies.
+	registrations map[string]bool
+	grants        map[string]bool
+	claims        []reference
+
+	// Names of the ValidatingAdmissionPolicies, and the policies referenced by
+	// bindings.
+	policies map[string]bool
+	bindings []reference
+}
+
+func (l *linter) checks() map[schema.GroupKind]func(*manifest.Object) {
+	return map[schema.GroupKind]func(*manifest.Object){
+		grantCreationPolicyKind:        l.checkGrantCreationPolicy,
+		claimCreationPolicyKind:        l.checkClaimCreationPolicy,
+		resourceRegistrationKind:       l.checkResourceRegistration,
+		resourceIndexPolicyKind:        l.checkResourceIndexPolicy,
+		contactGroupEnrollmentKind:     l.checkContactGroupEnrollmentPolicy,
+		validatingAdmissionPolicyKind:  l.checkValidatingAdmissionPolicy,
+		validatingAdmissionBindingKind: l.checkValidatingAdmissionPolicyBinding,
+	}
+}
+
+func (l *linter) report(obj *manifest.Object, severity Severity, path []string, format string, args ...any) {
+	l.findings = append(l.findings, Finding{
+		Path:     obj.Path,
+		Line:     obj.LineOf(path...),
+		Severity: severity,
+		Object:   obj.String(),
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// decodeStrict decodes the object into its Go type when the kind is known to
+// the linter, reporting unknown or duplicate fields.
+func (l *linter) decodeStrict(obj *manifest.Object) bool {
+	_, _, err := codecs.UniversalDeserializer().Decode(obj.Raw, nil, nil)
+	switch {
+	case err == nil, runtime.IsNotRegisteredError(err):
+		return true
+	case runtime.IsStrictDecodingError(err):
+		l.report(obj, SeverityError, nil, "%v", err)
+		return true
+	default:
+		l.report(obj, SeverityError, nil, "failed to decode: %v", err)
+		return false
+	}
+}
+
+// requireString reports an error when the string at path is empty or missing.
+func (l *linter) requireString(obj *manifest.Object, path ...string) string {
+	value, _, _ := unstructured.NestedString(obj.Object.Object, path...)
+	if value == "" {
+		l.report(obj, SeverityError, path, "%s is required", strings.Join(path, "."))
+	}
+	return value
+}
+
+func (l *linter) checkGrantCreationPolicy(obj *manifest.Object) {
+	trigger := l.checkTrigger(obj, grantTemplateVariables)
+	l.checkTemplate(obj, trigger, grantTemplateVariables, "spec", "target", "resourceGrantTemplate")
+
+	if expr, ok, _ := unstructured.NestedString(obj.Object.Object, "spec", "target", "parentContext", "nameExpression"); ok {
+		l.checkExpression(obj, trigger, grantTemplateVariables, expr, cel.StringType, "spec", "target", "parentContext", "nameExpression")
+	}
+
+	allowances, _, _ := unstructured.NestedSlice(obj.Object.Object, "spec", "target", "resourceGrantTemplate", "spec", "allowances")
+	for i, allowance := range allowances {
+		resourceType, _, _ := unstructured.NestedString(asMap(allowance), "resourceType")
+		if resourceType == "" {
+			l.report(obj, SeverityError, []string{"spec", "target", "resourceGrantTemplate", "spec", "allowances", fmt.Sprint(i)}, "allowance is missing a resourceType")
+			continue
+		}
+		l.grants[resourceType] = true
+	}
+}
+
+func (l *linter) checkClaimCreationPolicy(obj *manifest.Object) {
+	trigger := l.checkTrigger(obj, claimTemplateVariables)
+	l.checkTemplate(obj, trigger, claimTemplateVariables, "spec", "target", "resourceClaimTemplate")
+
+	requestsPath := []string{"spec", "target", "resourceClaimTemplate", "spec", "requests"}
+	requests, _, _ := unstructured.NestedSlice(obj.Object.Object, requestsPath...)
+	if len(requests) == 0 {
+		l.report(obj, SeverityError, requestsPath, "claim template does not request any resources")
+	}
+	for i, request := range requests {
+		path := append(append([]string{}, requestsPath...), fmt.Sprint(i), "resourceType")
+		resourceType, _, _ := unstructured.NestedString(asMap(request), "resourceType")
+		if resourceType == "" {
+			l.report(obj, SeverityError, path, "request is missing a resourceType")
+			continue
+		}
+		l.claims = append(l.claims, reference{obj: obj, path: path, name: resourceType})
+	}
+}
+
+func (l *linter) checkResourceRegistration(obj *manifest.Object) {
+	if resourceType := l.requireString(obj, "spec", "resourceType"); resourceType != "" {
+		l.registrations[resourceType] = true
+	}
+	l.requireString(obj, "spec", "consumerType", "kind")
+}
+
+// checkTrigger validates the trigger of a quota policy and returns the schema
+// of the triggering resource.
+func (l *linter) checkTrigger(obj *manifest.Object, variables []string) triggerSchema {
+	apiVersion := l.requireString(obj, "spec", "trigger", "resource", "apiVersion")
+	kind := l.requireString(obj, "spec", "trigger", "resource", "kind")
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		l.report(obj, SeverityError, []string{"spec", "trigger", "resource", "apiVersion"}, "invalid apiVersion: %v", err)
+	}
+	trigger := newTriggerSchema(gv.WithKind(kind))
+
+	constraints, _, _ := unstructured.NestedSlice(obj.Object.Object, "spec", "trigger", "constraints")
+	for i, constraint := range constraints {
+		expr, _, _ := unstructured.NestedString(asMap(constraint), "expression")
+		l.checkExpression(obj, trigger, variables, expr, cel.BoolType, "spec", "trigger", "constraints", fmt.Sprint(i), "expression")
+	}
+	return trigger
+}
+
+// checkTemplate validates every placeholder in the string fields below path.
+func (l *linter) checkTemplate(obj *manifest.Object, trigger triggerSchema, variables []string, path ...string) {
+	template, ok, _ := unstructured.NestedFieldNoCopy(obj.Object.Object, path...)
+	if !ok {
+		l.report(obj, SeverityError, path, "%s is required", strings.Join(path, "."))
+		return
+	}
+	walkStrings(template, path, func(fieldPath []string, value string) {
+		expressions, err := Placeholders(value)
+		if err != nil {
+			l.report(obj, SeverityError, fieldPath, "%v", err)
+			return
+		}
+		for _, expr := range expressions {
+			l.checkExpression(obj, trigger, variables, expr, nil, fieldPath...)
+		}
+	})
+}
+
+// checkExpression compiles a quota policy expression and validates the trigger
+// fields it references. If outputType is set, the expression must evaluate to
+// that type.
+func (l *linter) checkExpression(obj *manifest.Object, trigger triggerSchema, variables []string, expr string, outputType *cel.Type, path ...string) {
+	if expr == "" {
+		l.report(obj, SeverityError, path, "expression is empty")
+		return
+	}
+	env, err := newTemplateEnv(variables...)
+	if err != nil {
+		l.report(obj, SeverityError, path, "failed to create CEL environment: %v", err)
+		return
+	}
+	ast, issues := env.Compile(expr)
+	if issues.Err() != nil {
+		l.report(obj, SeverityError, path, "invalid expression %q: %v", expr, issues.Err())
+		return
+	}
+	if outputType != nil && !ast.OutputType().IsExactType(cel.DynType) && !ast.OutputType().IsExactType(outputType) {
+		l.report(obj, SeverityError, path, "expression %q must evaluate to %s, got %s", expr, outputType, ast.OutputType())
+	}
+	for _, ref := range fieldReferences(ast, "trigger") {
+		if err := trigger.validate(ref); err != nil {
+			l.report(obj, SeverityError, path, "expression %q: %v", expr, err)
+		}
+	}
+}
+
+func (l *linter) checkResourceIndexPolicy(obj *manifest.Object) {
+	l.requireString(obj, "spec", "targetResource", "group")
+	l.requireString(obj, "spec", "targetResource", "version")
+	l.requireString(obj, "spec", "targetResource", "kind")
+
+	env, err := newTemplateEnv(resourceIndexConditionVariables...)
+	if err != nil {
+		l.report(obj, SeverityError, nil, "failed to create CEL environment: %v", err)
+		return
+	}
+	conditions, _, _ := unstructured.NestedSlice(obj.Object.Object, "spec", "conditions")
+	for i, condition := range conditions {
+		path := []string{"spec", "conditions", fmt.Sprint(i), "expression"}
+		expr, _, _ := unstructured.NestedString(asMap(condition), "expression")
+		if _, issues := env.Compile(expr); issues.Err() != nil {
+			l.report(obj, SeverityError, path, "invalid expression %q: %v", expr, issues.Err())
+		}
+	}
+
+	fields, _, _ := unstructured.NestedSlice(obj.Object.Object, "spec", "fields")
+	for i, field := range fields {
+		fieldPath, _, _ := unstructured.NestedString(asMap(field), "path")
+		if !strings.HasPrefix(fieldPath, ".") {
+			l.report(obj, SeverityError, []string{"spec", "fields", fmt.Sprint(i), "path"}, "field path %q must start with '.'", fieldPath)
+		}
+	}
+}
+
+func (l *linter) checkContactGroupEnrollmentPolicy(obj *manifest.Object) {
+	l.requireString(obj, "spec", "contactGroupRef", "name")
+	l.requireString(obj, "spec", "contactGroupRef", "namespace")
+	l.requireString(obj, "spec", "trigger", "type")
+}
+
+func (l *linter) checkValidatingAdmissionPolicy(obj *manifest.Object) {
+	policy := &admissionregistrationv1.ValidatingAdmissionPolicy{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object.Object, policy); err != nil {
+		l.report(obj, SeverityError, nil, "failed to decode: %v", err)
+		return
+	}
+	l.policies[policy.Name] = true
+
+	if len(policy.Spec.Validations) == 0 {
+		l.report(obj, SeverityWarning, []string{"spec"}, "policy has no validations")
+	}
+	errs, err := compileErrors(policy)
+	if err != nil {
+		l.report(obj, SeverityError, nil, "%v", err)
+		return
+	}
+	for _, e := range errs {
+		l.report(obj, SeverityError, e.path, "%v", e.err)
+	}
+}
+
+func (l *linter) checkValidatingAdmissionPolicyBinding(obj *manifest.Object) {
+	if name := l.requireString(obj, "spec", "policyName"); name != "" {
+		l.bindings = append(l.bindings, reference{obj: obj, path: []string{"spec", "policyName"}, name: name})
+	}
+}
+
+// checkReferences validates references between the linted objects once all of
+// them have been visited.
+func (l *linter) checkReferences() {
+	for _, claim := range l.claims {
+		if !l.registrations[claim.name] {
+			l.report(claim.obj, SeverityError, claim.path, "resource type %q has no ResourceRegistration", claim.name)
+		}
+		if !l.grants[claim.name] {
+			l.report(claim.obj, SeverityError, claim.path, "resource type %q is not granted by any GrantCreationPolicy", claim.name)
+		}
+	}
+	for _, binding := range l.bindings {
+		if !l.policies[binding.name] {
+			l.report(binding.obj, SeverityError, binding.path, "ValidatingAdmissionPolicy %q not found", binding.name)
+		}
+	}
+}
+
+func asMap(v any) map[string]any {
+	m, _ := v.(map[string]any)
+	return m
+}
+
+// walkStrings calls fn for every string value below v.
+func walkStrings(v any, path []string, fn func(path []string, value string)) {
+	switch value := v.(type) {
+	case string:
+		fn(path, value)
+	case map[string]any:
+		keys := make([]string, 0, len(value))
+		for k := range value {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			walkStrings(value[k], append(append([]string{}, path...), k), fn)
+		}
+	case []any:
+		for i, item := range value {
+			walkStrings(item, append(append([]string{}, path...), fmt.Sprint(i)), fn)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package policy
+
+import (
+	"strings"
+	"testing"
+
+	"go.datum.net/datum/internal/manifest"
+)
+
+func TestLintRepositoryPolicies(t *testing.T) {
+	objects, err := manifest.Load("../../config/services", "../../config/overlays/unified-organizations")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	for _, f := range Lint(objects) {
+		t.Error(f.String())
+	}
+}
+
+func TestLintReportsProblems(t *testing.T) {
+	objects, err := manifest.Parse("policies.yaml", []byte(`apiVersion: quota.miloapis.com/v1alpha1
+kind: ClaimCreationPolicy
+metadata:
+  name: project-claims
+spec:
+  trigger:
+    resource:
+      apiVersion: resourcemanager.miloapis.com/v1alpha1
+      kind: Project
+  target:
+    resourceClaimTemplate:
+      metadata:
+        name: "project-{{ trigger.metadata.nam }}"
+      spec:
+        requests:
+          - resourceType: resourcemanager.miloapis.com/projects
+            amount: 1
+---
+apiVersion: admissionregistration.k8s.io/v1
+kind: ValidatingAdmissionPolicy
+metadata:
+  name: validate-project-name
+spec:
+  validations:
+  - expression: "size(object.metadata.name) >="
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	want := []struct {
+		line    int
+		message string
+	}{
+		{13, `Project has no field "metadata.nam"`},
+		{16, "has no ResourceRegistration"},
+		{16, "is not granted by any GrantCreationPolicy"},
+		{25, "Syntax error"},
+	}
+
+	findings := Lint(objects)
+	if len(findings) != len(want) {
+		t.Fatalf("Lint() returned %d findings, want %d: %v", len(findings), len(want), findings)
+	}
+	for i, w := range want {
+		if findings[i].Line != w.line || !strings.Contains(findings[i].Message, w.message) {
+			t.Errorf("finding %d = %s, want line %d containing %q", i, findings[i], w.line, w.message)
+		}
+	}
+}
+
+func TestPlaceholders(t *testing.T) {
+	got, err := Placeholders("organization-{{ trigger.metadata.name }}-{{requestInfo.namespace}}")
+	if err != nil {
+		t.Fatalf("Placeholders() error = %v", err)
+	}
+	if len(got) != 2 || got[0] != "trigger.metadata.name" || got[1] != "requestInfo.namespace" {
+		t.Fatalf("Placeholders() = %q", got)
+	}
+
+	if _, err := Placeholders("organization-{{ trigger.metadata.name"); err == nil {
+		t.Fatal("Placeholders() expected error for unterminated placeholder")
+	}
+}
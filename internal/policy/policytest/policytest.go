@@ -0,0 +1,47 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package policytest runs ValidatingAdmissionPolicy fixtures as Go tests so
+// policy changes can be covered by unit tests without a cluster.
+package policytest
+
+import (
+	"testing"
+
+	"go.datum.net/datum/internal/manifest"
+	"go.datum.net/datum/internal/policy"
+)
+
+// Run loads the ValidatingAdmissionPolicies and the admission test fixtures in
+// testdata directories below the given paths, and runs every fixture case as a
+// subtest named after the policy and the case.
+func Run(t *testing.T, paths ...string) {
+	t.Helper()
+
+	objects, err := manifest.Load(paths...)
+	if err != nil {
+		t.Fatalf("failed to load manifests: %v", err)
+	}
+	policies, err := policy.LoadAdmissionPolicies(objects)
+	if err != nil {
+		t.Fatalf("failed to compile admission policies: %v", err)
+	}
+	suites, err := policy.LoadAdmissionTestSuites(paths...)
+	if err != nil {
+		t.Fatalf("failed to load admission test fixtures: %v", err)
+	}
+	if len(suites) == 0 {
+		t.Fatalf("no admission test fixtures found in %v", paths)
+	}
+
+	for _, suite := range suites {
+		t.Run(suite.Policy, func(t *testing.T) {
+			for _, result := range policy.RunAdmissionTestSuite(t.Context(), policies, suite) {
+				t.Run(result.Case.Name, func(t *testing.T) {
+					if result.Err != nil {
+						t.Errorf("%s: %v", suite.Path, result.Err)
+					}
+				})
+			}
+		})
+	}
+}
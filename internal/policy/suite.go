@@ -0,0 +1,140 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package policy
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// AdmissionTestSuite is a set of admission requests to evaluate against a
+// single ValidatingAdmissionPolicy, loaded from a YAML fixture.
+type AdmissionTestSuite struct {
+	// Path is the file the suite was loaded from.
+	Path string `json:"-"`
+
+	// Policy is the name of the ValidatingAdmissionPolicy under test.
+	Policy string `json:"policy"`
+
+	Cases []AdmissionTestCase `json:"cases"`
+}
+
+// AdmissionTestCase is a single admission request and its expected outcome.
+type AdmissionTestCase struct {
+	Name    string                       `json:"name"`
+	Request admissionv1.AdmissionRequest `json:"request"`
+	Expect  AdmissionExpectation         `json:"expect"`
+}
+
+// AdmissionExpectation is the expected outcome of an admission request.
+type AdmissionExpectation struct {
+	Allowed bool `json:"allowed"`
+
+	// Message is the expected denial message. It is only checked when set.
+	Message string `json:"message,omitempty"`
+}
+
+// AdmissionTestResult is the outcome of running a single test case.
+type AdmissionTestResult struct {
+	Suite    *AdmissionTestSuite
+	Case     *AdmissionTestCase
+	Decision Decision
+
+	// Err is set when the case failed, either because it could not be
+	// evaluated or because the decision did not match the expectation.
+	Err error
+}
+
+// LoadAdmissionTestSuites loads the admission test fixtures found in testdata
+// directories below the given paths. Files given directly are always loaded.
+func LoadAdmissionTestSuites(paths ...string) ([]*AdmissionTestSuite, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		err = filepath.WalkDir(path, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || filepath.Base(filepath.Dir(path)) != "testdata" {
+				return nil
+			}
+			if ext := filepath.Ext(path); ext == ".yaml" || ext == ".yml" {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(files)
+
+	suites := make([]*AdmissionTestSuite, 0, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		suite := &AdmissionTestSuite{Path: file}
+		if err := yaml.UnmarshalStrict(data, suite); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		if suite.Policy == "" {
+			return nil, fmt.Errorf("%s: policy is required", file)
+		}
+		suites = append(suites, suite)
+	}
+	return suites, nil
+}
+
+// RunAdmissionTestSuite evaluates every case of the suite against the named
+// policy and returns one result per case.
+func RunAdmissionTestSuite(ctx context.Context, policies map[string]*AdmissionPolicy, suite *AdmissionTestSuite) []AdmissionTestResult {
+	results := make([]AdmissionTestResult, 0, len(suite.Cases))
+	policy, ok := policies[suite.Policy]
+	for i := range suite.Cases {
+		result := AdmissionTestResult{Suite: suite, Case: &suite.Cases[i]}
+		if !ok {
+			result.Err = fmt.Errorf("ValidatingAdmissionPolicy %q not found", suite.Policy)
+			results = append(results, result)
+			continue
+		}
+
+		result.Decision, result.Err = policy.Evaluate(ctx, &suite.Cases[i].Request)
+		if result.Err == nil {
+			result.Err = suite.Cases[i].Expect.check(result.Decision)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func (e AdmissionExpectation) check(d Decision) error {
+	if d.Allowed != e.Allowed {
+		if d.Allowed {
+			if !d.Matched {
+				return fmt.Errorf("expected request to be denied, but it did not match the policy")
+			}
+			return fmt.Errorf("expected request to be denied, but it was allowed")
+		}
+		return fmt.Errorf("expected request to be allowed, but it was denied: %s", d.Message)
+	}
+	if e.Message != "" && d.Message != e.Message {
+		return fmt.Errorf("expected message %q, got %q", e.Message, d.Message)
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package policy
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	celast "github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/ext"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// placeholderPattern matches the `{{ expression }}` placeholders used in quota
+// policy templates.
+var placeholderPattern = regexp.MustCompile(`\{\{(.*?)\}\}`)
+
+// Placeholders returns the expressions of all placeholders in s. An error is
+// returned when s contains an unterminated placeholder.
+func Placeholders(s string) ([]string, error) {
+	var expressions []string
+	for _, match := range placeholderPattern.FindAllStringSubmatch(s, -1) {
+		expressions = append(expressions, strings.TrimSpace(match[1]))
+	}
+	if rest := placeholderPattern.ReplaceAllString(s, ""); strings.Contains(rest, "{{") || strings.Contains(rest, "}}") {
+		return nil, fmt.Errorf("unbalanced placeholder delimiters in %q", s)
+	}
+	return expressions, nil
+}
+
+// newTemplateEnv creates the CEL environment used to compile quota policy
+// expressions, declaring the given variables as dynamically typed.
+func newTemplateEnv(variables ...string) (*cel.Env, error) {
+	opts := []cel.EnvOption{ext.Strings()}
+	for _, v := range variables {
+		opts = append(opts, cel.Variable(v, cel.DynType))
+	}
+	return cel.NewEnv(opts...)
+}
+
+// fieldReferences returns the field paths selected from the given variable in
+// a compiled expression, e.g. [metadata name] for `trigger.metadata.name`.
+func fieldReferences(ast *cel.Ast, variable string) [][]string {
+	var paths [][]string
+	celast.PostOrderVisit(ast.NativeRep().Expr(), celast.NewExprVisitor(func(e celast.Expr) {
+		if e.Kind() != celast.SelectKind {
+			return
+		}
+		var path []string
+		current := e
+		for current.Kind() == celast.SelectKind {
+			path = append([]string{current.AsSelect().FieldName()}, path...)
+			current = current.AsSelect().Operand()
+		}
+		if current.Kind() == celast.IdentKind && current.AsIdent() == variable {
+			paths = append(paths, path)
+		}
+	}))
+	return paths
+}
+
+// triggerSchema describes the fields available on a policy trigger.
+type triggerSchema struct {
+	gvk schema.GroupVersionKind
+
+	// typ is the Go type of the trigger, or nil when the trigger kind is not
+	// known to the linter. In that case only metadata fields are checked.
+	typ reflect.Type
+}
+
+func newTriggerSchema(gvk schema.GroupVersionKind) triggerSchema {
+	s := triggerSchema{gvk: gvk}
+	if obj, err := scheme.New(gvk); err == nil {
+		s.typ = reflect.TypeOf(obj)
+	}
+	return s
+}
+
+// validate returns an error when path does not exist on the trigger.
+func (s triggerSchema) validate(path []string) error {
+	if len(path) == 0 {
+		return nil
+	}
+	t := s.typ
+	if t == nil {
+		switch path[0] {
+		case "apiVersion", "kind", "spec", "status":
+			return nil
+		case "metadata":
+			t = reflect.TypeOf(struct {
+				metav1.ObjectMeta `json:"metadata"`
+			}{})
+		default:
+			return fmt.Errorf("%s has no field %q", s.gvk.Kind, path[0])
+		}
+	}
+	if !hasFieldPath(t, path) {
+		return fmt.Errorf("%s has no field %q", s.gvk.Kind, strings.Join(path, "."))
+	}
+	return nil
+}
+
+func hasFieldPath(t reflect.Type, path []string) bool {
+	for _, name := range path {
+		for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Map:
+			t = t.Elem()
+		case reflect.Interface:
+			return true
+		case reflect.Struct:
+			field, ok := jsonField(t, name)
+			if !ok {
+				return false
+			}
+			t = field.Type
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func jsonField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tagName := strings.Split(field.Tag.Get("json"), ",")[0]
+		if tagName == "-" {
+			continue
+		}
+		if field.Anonymous && tagName == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if f, ok := jsonField(embedded, name); ok {
+				return f, true
+			}
+			continue
+		}
+		if tagName == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// RenderTemplate returns a copy of v in which every placeholder in a string
+// value has been replaced with the result of evaluating its expression with the
+// given variables.
+func RenderTemplate(v any, vars map[string]any) (any, error) {
+	switch value := v.(type) {
+	case string:
+		return renderString(value, vars)
+	case map[string]any:
+		out := make(map[string]any, len(value))
+		for k, item := range value {
+			rendered, err := RenderTemplate(item, vars)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = rendered
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(value))
+		for i, item := range value {
+			rendered, err := RenderTemplate(item, vars)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = rendered
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+func renderString(s string, vars map[string]any) (string, error) {
+	if _, err := Placeholders(s); err != nil {
+		return "", err
+	}
+	var renderErr error
+	rendered := placeholderPattern.ReplaceAllStringFunc(s, func(match string) string {
+		expr := strings.TrimSpace(placeholderPattern.FindStringSubmatch(match)[1])
+		value, err := evaluate(expr, vars)
+		if err != nil {
+			renderErr = err
+			return match
+		}
+		return fmt.Sprint(value)
+	})
+	return rendered, renderErr
+}
+
+// EvaluateCondition evaluates a boolean quota policy expression, such as a
+// trigger constraint, with the given variables.
+func EvaluateCondition(expr string, vars map[string]any) (bool, error) {
+	value, err := evaluate(expr, vars)
+	if err != nil {
+		return false, err
+	}
+	result, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression %q evaluated to %T, not bool", expr, value)
+	}
+	return result, nil
+}
+
+func evaluate(expr string, vars map[string]any) (any, error) {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	env, err := newTemplateEnv(names...)
+	if err != nil {
+		return nil, err
+	}
+	ast, issues := env.Compile(expr)
+	if issues.Err() != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", expr, issues.Err())
+	}
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", expr, err)
+	}
+	value, _, err := program.Eval(vars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate %q: %w", expr, err)
+	}
+	return value.Value(), nil
+}
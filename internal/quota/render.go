@@ -0,0 +1,74 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package quota
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/yaml"
+)
+
+// Render builds a kustomization from the given kustomize components, in order,
+// and returns the resulting objects. This mirrors how the service
+// configuration is composed when it is deployed, including overlays such as
+// config/overlays/unified-organizations.
+func Render(components ...string) ([]*unstructured.Unstructured, error) {
+	dir, err := os.MkdirTemp("", "datum-kustomize-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	kustomization := types.Kustomization{
+		TypeMeta: types.TypeMeta{
+			APIVersion: types.KustomizationVersion,
+			Kind:       types.KustomizationKind,
+		},
+	}
+	for _, component := range components {
+		// Kustomize does not accept absolute paths, so components are referenced
+		// relative to the temporary kustomization.
+		path, err := filepath.Abs(component)
+		if err != nil {
+			return nil, err
+		}
+		if path, err = filepath.Rel(dir, path); err != nil {
+			return nil, err
+		}
+		kustomization.Components = append(kustomization.Components, path)
+	}
+	data, err := yaml.Marshal(kustomization)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "kustomization.yaml"), data, 0o600); err != nil {
+		return nil, err
+	}
+
+	opts := krusty.MakeDefaultOptions()
+	opts.LoadRestrictions = types.LoadRestrictionsNone
+	resources, err := krusty.MakeKustomizer(opts).Run(filesys.MakeFsOnDisk(), dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build kustomization: %w", err)
+	}
+
+	objects := make([]*unstructured.Unstructured, 0, resources.Size())
+	for _, resource := range resources.Resources() {
+		data, err := resource.MarshalJSON()
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert %s: %w", resource.CurId(), err)
+		}
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(data); err != nil {
+			return nil, fmt.Errorf("failed to convert %s: %w", resource.CurId(), err)
+		}
+		objects = append(objects, obj)
+	}
+	return objects, nil
+}
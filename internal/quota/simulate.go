@@ -0,0 +1,256 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package quota evaluates the quota policies Datum ships for Milo services
+// against hypothetical resources.
+package quota
+
+import (
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"go.datum.net/datum/internal/policy"
+)
+
+var (
+	grantCreationPolicyKind = schema.GroupKind{Group: "quota.miloapis.com", Kind: "GrantCreationPolicy"}
+	claimCreationPolicyKind = schema.GroupKind{Group: "quota.miloapis.com", Kind: "ClaimCreationPolicy"}
+
+	resourceGrantGVK = schema.GroupVersionKind{Group: "quota.miloapis.com", Version: "v1alpha1", Kind: "ResourceGrant"}
+	resourceClaimGVK = schema.GroupVersionKind{Group: "quota.miloapis.com", Version: "v1alpha1", Kind: "ResourceClaim"}
+)
+
+// Scenario describes the hypothetical organization quota policies are
+// evaluated against. A Project owned by the organization is simulated as well,
+// as most service quotas are granted per project.
+type Scenario struct {
+	OrganizationName string
+	OrganizationType string
+	ProjectName      string
+
+	// Username is the user the simulated resources are created by. It is
+	// available to claim templates as `user.name`.
+	Username string
+}
+
+// Triggers returns the synthetic trigger resources of the scenario.
+func (s Scenario) Triggers() []*unstructured.Unstructured {
+	organization := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "resourcemanager.miloapis.com/v1alpha1",
+		"kind":       "Organization",
+		"metadata": map[string]any{
+			"name": s.OrganizationName,
+		},
+		"spec": map[string]any{
+			"type": s.OrganizationType,
+		},
+	}}
+	project := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "resourcemanager.miloapis.com/v1alpha1",
+		"kind":       "Project",
+		"metadata": map[string]any{
+			"name": s.ProjectName,
+		},
+		"spec": map[string]any{
+			"ownerRef": map[string]any{
+				"kind": "Organization",
+				"name": s.OrganizationName,
+			},
+		},
+	}}
+	return []*unstructured.Unstructured{organization, project}
+}
+
+// Result is the outcome of a simulation.
+type Result struct {
+	// Grants are the ResourceGrants that would be created, in policy order.
+	Grants []Object
+
+	// Claims are the ResourceClaims that would be created when the simulated
+	// resources are created.
+	Claims []Object
+
+	// Allowances is the total amount granted per consumer and resource type.
+	Allowances []Allowance
+}
+
+// Object is a resource created by a quota policy.
+type Object struct {
+	// Policy is the name of the policy that created the object.
+	Policy string
+
+	// Trigger is the simulated resource that triggered the policy.
+	Trigger *unstructured.Unstructured
+
+	Object *unstructured.Unstructured
+}
+
+// Allowance is the total amount of a resource type granted to a consumer.
+type Allowance struct {
+	ConsumerKind string
+	ConsumerName string
+	ResourceType string
+	Amount       int64
+}
+
+// Simulate evaluates the grant and claim creation policies in objects against
+// the resources of the scenario.
+func Simulate(objects []*unstructured.Unstructured, scenario Scenario) (*Result, error) {
+	result := &Result{}
+	for _, obj := range objects {
+		var (
+			templateField string
+			gvk           schema.GroupVersionKind
+			created       *[]Object
+		)
+		switch obj.GroupVersionKind().GroupKind() {
+		case grantCreationPolicyKind:
+			templateField, gvk, created = "resourceGrantTemplate", resourceGrantGVK, &result.Grants
+		case claimCreationPolicyKind:
+			templateField, gvk, created = "resourceClaimTemplate", resourceClaimGVK, &result.Claims
+		default:
+			continue
+		}
+
+		for _, trigger := range scenario.Triggers() {
+			matched, err := triggered(obj, trigger, scenario)
+			if err != nil {
+				return nil, fmt.Errorf("%s %q: %w", obj.GetKind(), obj.GetName(), err)
+			}
+			if !matched {
+				continue
+			}
+			rendered, err := render(obj, templateField, gvk, trigger, scenario)
+			if err != nil {
+				return nil, fmt.Errorf("%s %q: %w", obj.GetKind(), obj.GetName(), err)
+			}
+			*created = append(*created, Object{Policy: obj.GetName(), Trigger: trigger, Object: rendered})
+		}
+	}
+
+	allowances, err := totalAllowances(result.Grants)
+	if err != nil {
+		return nil, err
+	}
+	result.Allowances = allowances
+	return result, nil
+}
+
+// triggered returns true when the policy's trigger selects the resource and
+// all of its constraints are satisfied.
+func triggered(obj, trigger *unstructured.Unstructured, scenario Scenario) (bool, error) {
+	apiVersion, _, _ := unstructured.NestedString(obj.Object, "spec", "trigger", "resource", "apiVersion")
+	kind, _, _ := unstructured.NestedString(obj.Object, "spec", "trigger", "resource", "kind")
+	if apiVersion != trigger.GetAPIVersion() || kind != trigger.GetKind() {
+		return false, nil
+	}
+
+	constraints, _, err := unstructured.NestedSlice(obj.Object, "spec", "trigger", "constraints")
+	if err != nil {
+		return false, err
+	}
+	vars := variables(trigger, scenario)
+	for _, c := range constraints {
+		constraint, _ := c.(map[string]any)
+		expression, _ := constraint["expression"].(string)
+		ok, err := policy.EvaluateCondition(expression, vars)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func render(obj *unstructured.Unstructured, templateField string, gvk schema.GroupVersionKind, trigger *unstructured.Unstructured, scenario Scenario) (*unstructured.Unstructured, error) {
+	template, found, err := unstructured.NestedMap(obj.Object, "spec", "target", templateField)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("spec.target.%s is required", templateField)
+	}
+	rendered, err := policy.RenderTemplate(template, variables(trigger, scenario))
+	if err != nil {
+		return nil, err
+	}
+	created := &unstructured.Unstructured{Object: rendered.(map[string]any)}
+	created.SetGroupVersionKind(gvk)
+	return created, nil
+}
+
+// variables returns the variables available to policy expressions. Claim
+// policies may also refer to the request and the requesting user.
+func variables(trigger *unstructured.Unstructured, scenario Scenario) map[string]any {
+	return map[string]any{
+		"trigger": trigger.Object,
+		"requestInfo": map[string]any{
+			"verb":      "create",
+			"name":      trigger.GetName(),
+			"namespace": trigger.GetNamespace(),
+		},
+		"user": map[string]any{
+			"name": scenario.Username,
+		},
+	}
+}
+
+func totalAllowances(grants []Object) ([]Allowance, error) {
+	totals := map[Allowance]int64{}
+	for _, grant := range grants {
+		kind, _, _ := unstructured.NestedString(grant.Object.Object, "spec", "consumerRef", "kind")
+		name, _, _ := unstructured.NestedString(grant.Object.Object, "spec", "consumerRef", "name")
+		allowances, _, err := unstructured.NestedSlice(grant.Object.Object, "spec", "allowances")
+		if err != nil {
+			return nil, fmt.Errorf("ResourceGrant %q: %w", grant.Object.GetName(), err)
+		}
+		for _, a := range allowances {
+			allowance, _ := a.(map[string]any)
+			resourceType, _ := allowance["resourceType"].(string)
+			buckets, _ := allowance["buckets"].([]any)
+			key := Allowance{ConsumerKind: kind, ConsumerName: name, ResourceType: resourceType}
+			for _, b := range buckets {
+				bucket, _ := b.(map[string]any)
+				amount, err := toInt64(bucket["amount"])
+				if err != nil {
+					return nil, fmt.Errorf("ResourceGrant %q: allowance for %s: %w", grant.Object.GetName(), resourceType, err)
+				}
+				totals[key] += amount
+			}
+		}
+	}
+
+	allowances := make([]Allowance, 0, len(totals))
+	for key, amount := range totals {
+		key.Amount = amount
+		allowances = append(allowances, key)
+	}
+	sort.Slice(allowances, func(i, j int) bool {
+		a, b := allowances[i], allowances[j]
+		if a.ConsumerKind != b.ConsumerKind {
+			return a.ConsumerKind < b.ConsumerKind
+		}
+		if a.ConsumerName != b.ConsumerName {
+			return a.ConsumerName < b.ConsumerName
+		}
+		return a.ResourceType < b.ResourceType
+	})
+	return allowances, nil
+}
+
+func toInt64(v any) (int64, error) {
+	switch n := v.(type) {
+	case int64:
+		return n, nil
+	case int:
+		return int64(n), nil
+	case float64:
+		return int64(n), nil
+	default:
+		return 0, fmt.Errorf("invalid amount %v", v)
+	}
+}
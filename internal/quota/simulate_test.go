@@ -0,0 +1,70 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package quota
+
+import (
+	"testing"
+)
+
+func TestSimulateProjectQuota(t *testing.T) {
+	tests := []struct {
+		name             string
+		components       []string
+		organizationType string
+		want             int64
+	}{
+		{
+			name:             "personal organization",
+			components:       []string{"../../config/services"},
+			organizationType: "Personal",
+			want:             2,
+		},
+		{
+			name:             "standard organization",
+			components:       []string{"../../config/services"},
+			organizationType: "Standard",
+			want:             10,
+		},
+		{
+			name:             "personal organization with unified organizations",
+			components:       []string{"../../config/services", "../../config/overlays/unified-organizations"},
+			organizationType: "Personal",
+			want:             10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects, err := Render(tt.components...)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			result, err := Simulate(objects, Scenario{
+				OrganizationName: "example",
+				OrganizationType: tt.organizationType,
+				ProjectName:      "example-project",
+			})
+			if err != nil {
+				t.Fatalf("Simulate() error = %v", err)
+			}
+
+			var found bool
+			for _, a := range result.Allowances {
+				if a.ConsumerKind != "Organization" || a.ResourceType != "resourcemanager.miloapis.com/projects" {
+					continue
+				}
+				found = true
+				if a.ConsumerName != "example" || a.Amount != tt.want {
+					t.Errorf("project allowance = %s %d, want example %d", a.ConsumerName, a.Amount, tt.want)
+				}
+			}
+			if !found {
+				t.Fatal("no project allowance granted to the organization")
+			}
+
+			if len(result.Claims) != 1 || result.Claims[0].Object.GetNamespace() != "organization-example" {
+				t.Errorf("Claims = %v, want a single project claim in organization-example", result.Claims)
+			}
+		})
+	}
+}
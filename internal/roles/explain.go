@@ -0,0 +1,157 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package roles
+
+import (
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	iamv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
+
+	"go.datum.net/datum/internal/catalog"
+	"go.datum.net/datum/internal/manifest"
+)
+
+var roleKind = schema.GroupKind{Group: "iam.miloapis.com", Kind: "Role"}
+
+// FromManifests returns the Roles found in the given manifests. The datum
+// roles have no namespace in their manifests because it is set by a
+// kustomization, so they are placed in the datum namespace. Other roles
+// without a namespace are left as they are.
+func FromManifests(objects []manifest.Object, datumNamespace string) ([]iamv1alpha1.Role, error) {
+	var roles []iamv1alpha1.Role
+	for _, obj := range objects {
+		if obj.GroupVersionKind().GroupKind() != roleKind {
+			continue
+		}
+		role := iamv1alpha1.Role{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object.Object, &role); err != nil {
+			return nil, fmt.Errorf("%s:%d: failed to decode %s: %w", obj.Path, obj.Line, obj.String(), err)
+		}
+		if role.Namespace == "" && isDatumRole(role.Name) {
+			role.Namespace = datumNamespace
+		}
+		roles = append(roles, role)
+	}
+	return roles, nil
+}
+
+// isDatumRole reports whether name is one of the roles generated by
+// `datum generate roles`.
+func isDatumRole(name string) bool {
+	for _, role := range catalog.AssignableRoles {
+		if string(role.Level) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Explanation is the effective permission set of a role.
+type Explanation struct {
+	Role string `json:"role"`
+
+	// Permissions are all permissions granted by the role, sorted by name.
+	Permissions []Grant `json:"permissions"`
+
+	// InheritedRoles are all roles inherited directly or indirectly.
+	InheritedRoles []string `json:"inheritedRoles"`
+
+	// Unresolved are inherited roles that could not be found. Their
+	// permissions are not part of the explanation.
+	Unresolved []string `json:"unresolved,omitempty"`
+
+	// Cycles are the inheritance cycles reachable from the role.
+	Cycles []string `json:"cycles,omitempty"`
+}
+
+// Grant is a permission and the inheritance path it is granted through.
+type Grant struct {
+	Permission string `json:"permission"`
+
+	// GrantedBy is the role that includes the permission.
+	GrantedBy string `json:"grantedBy"`
+
+	// Path is the inheritance path from the explained role to GrantedBy.
+	Path []string `json:"path"`
+}
+
+// Explain flattens the inheritance of the role with the given key into its
+// effective permission set. An error is returned when the role does not exist.
+func (g *Graph) Explain(key Key) (*Explanation, error) {
+	if _, ok := g.roles[key]; !ok {
+		return nil, fmt.Errorf("role %s not found", key)
+	}
+
+	resolution := g.Resolve(key)
+	e := &Explanation{
+		Role:           key.String(),
+		Permissions:    make([]Grant, 0, len(resolution.Permissions)),
+		InheritedRoles: make([]string, 0, len(resolution.Roles)),
+	}
+	for permission, path := range resolution.Permissions {
+		e.Permissions = append(e.Permissions, Grant{
+			Permission: permission,
+			GrantedBy:  path[len(path)-1].String(),
+			Path:       pathStrings(path),
+		})
+	}
+	sort.Slice(e.Permissions, func(i, j int) bool {
+		return e.Permissions[i].Permission < e.Permissions[j].Permission
+	})
+	for _, path := range resolution.Roles {
+		e.InheritedRoles = append(e.InheritedRoles, path[len(path)-1].String())
+	}
+	sort.Strings(e.InheritedRoles)
+	for _, path := range resolution.Missing {
+		e.Unresolved = append(e.Unresolved, path[len(path)-1].String())
+	}
+	sort.Strings(e.Unresolved)
+	for _, cycle := range resolution.Cycles {
+		e.Cycles = append(e.Cycles, cycle.String())
+	}
+	return e, nil
+}
+
+// Diff is the difference between the effective permissions of two roles.
+type Diff struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+
+	// Added are the permissions granted by To but not by From.
+	Added []Grant `json:"added"`
+
+	// Removed are the permissions granted by From but not by To.
+	Removed []Grant `json:"removed"`
+
+	// Common is the number of permissions granted by both roles.
+	Common int `json:"common"`
+}
+
+// DiffExplanations compares the effective permissions of two roles.
+func DiffExplanations(from, to *Explanation) *Diff {
+	d := &Diff{From: from.Role, To: to.Role, Added: []Grant{}, Removed: []Grant{}}
+
+	fromPermissions := map[string]bool{}
+	for _, grant := range from.Permissions {
+		fromPermissions[grant.Permission] = true
+	}
+	toPermissions := map[string]bool{}
+	for _, grant := range to.Permissions {
+		toPermissions[grant.Permission] = true
+		if fromPermissions[grant.Permission] {
+			d.Common++
+		} else {
+			d.Added = append(d.Added, grant)
+		}
+	}
+	for _, grant := range from.Permissions {
+		if !toPermissions[grant.Permission] {
+			d.Removed = append(d.Removed, grant)
+		}
+	}
+	return d
+}
@@ -0,0 +1,255 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package roles resolves and validates the inheritance of IAM Roles, such as
+// the assignable organization roles Datum ships in
+// config/assignable-organization-roles.
+package roles
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	iamv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
+	resourcemanagerv1alpha1 "go.miloapis.com/milo/pkg/apis/resourcemanager/v1alpha1"
+)
+
+// Key identifies a Role.
+type Key struct {
+	Namespace string
+	Name      string
+}
+
+func (k Key) String() string {
+	return k.Namespace + "/" + k.Name
+}
+
+// KeyOf returns the key of the role.
+func KeyOf(role *iamv1alpha1.Role) Key {
+	return Key{Namespace: role.Namespace, Name: role.Name}
+}
+
+// Graph is the inheritance graph of a set of roles.
+type Graph struct {
+	roles map[Key]*iamv1alpha1.Role
+}
+
+// NewGraph creates an inheritance graph from the given roles.
+func NewGraph(roles []iamv1alpha1.Role) *Graph {
+	g := &Graph{roles: make(map[Key]*iamv1alpha1.Role, len(roles))}
+	for i := range roles {
+		g.roles[KeyOf(&roles[i])] = &roles[i]
+	}
+	return g
+}
+
+// Role returns the role with the given key.
+func (g *Graph) Role(key Key) (*iamv1alpha1.Role, bool) {
+	role, ok := g.roles[key]
+	return role, ok
+}
+
+// Inherited returns the keys of the roles the role directly inherits. Roles
+// referenced without a namespace are resolved in the namespace of the role.
+func Inherited(role *iamv1alpha1.Role) []Key {
+	keys := make([]Key, 0, len(role.Spec.InheritedRoles))
+	for _, ref := range role.Spec.InheritedRoles {
+		key := Key{Namespace: ref.Namespace, Name: ref.Name}
+		if key.Namespace == "" {
+			key.Namespace = role.Namespace
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Path is a chain of inheritance, starting at the resolved role.
+type Path []Key
+
+func (p Path) String() string {
+	return strings.Join(pathStrings(p), " -> ")
+}
+
+func pathStrings(path Path) []string {
+	out := make([]string, len(path))
+	for i, key := range path {
+		out[i] = key.String()
+	}
+	return out
+}
+
+// Resolution is the result of resolving the inheritance of a role.
+type Resolution struct {
+	Role Key
+
+	// Roles are the paths to every role inherited directly or indirectly, in
+	// depth-first order. Each role is listed once, with the first path it was
+	// found through.
+	Roles []Path
+
+	// Permissions maps every permission granted by the role to the path of
+	// the role that includes it.
+	Permissions map[string]Path
+
+	// Missing are the paths to referenced roles that do not exist.
+	Missing []Path
+
+	// Cycles are the inheritance cycles reachable from the role. Each cycle
+	// starts and ends with the same role.
+	Cycles []Path
+}
+
+// Resolve resolves the full inheritance of the role with the given key.
+func (g *Graph) Resolve(key Key) Resolution {
+	r := Resolution{Role: key, Permissions: map[string]Path{}}
+
+	visited := map[Key]bool{}
+	cycles := map[string]bool{}
+	var visit func(path Path)
+	visit = func(path Path) {
+		current := path[len(path)-1]
+		role, ok := g.roles[current]
+		if !ok {
+			r.Missing = append(r.Missing, path)
+			return
+		}
+		visited[current] = true
+		if len(path) > 1 {
+			r.Roles = append(r.Roles, path)
+		}
+		for _, permission := range role.Spec.IncludedPermissions {
+			if _, ok := r.Permissions[permission]; !ok {
+				r.Permissions[permission] = path
+			}
+		}
+
+		for _, next := range Inherited(role) {
+			if i := slices.Index(path, next); i >= 0 {
+				cycle := append(slices.Clone(path[i:]), next)
+				if id := cycle.String(); !cycles[id] {
+					cycles[id] = true
+					r.Cycles = append(r.Cycles, cycle)
+				}
+				continue
+			}
+			if visited[next] {
+				continue
+			}
+			visit(append(slices.Clone(path), next))
+		}
+	}
+	visit(Path{key})
+
+	return r
+}
+
+// Grants reports whether the membership holds one of the given roles,
+// directly or through a role that inherits it. Roles referenced without a
+// namespace are resolved in the namespace of the membership.
+func (g *Graph) Grants(membership *resourcemanagerv1alpha1.OrganizationMembership, refs []resourcemanagerv1alpha1.RoleReference) bool {
+	targets := make([]Key, len(refs))
+	for i, ref := range refs {
+		targets[i] = Key{Namespace: ref.Namespace, Name: ref.Name}
+	}
+
+	for _, ref := range membership.Spec.Roles {
+		key := Key{Namespace: ref.Namespace, Name: ref.Name}
+		if key.Namespace == "" {
+			key.Namespace = membership.Namespace
+		}
+		if slices.Contains(targets, key) {
+			return true
+		}
+		for _, path := range g.Resolve(key).Roles {
+			if slices.Contains(targets, path[len(path)-1]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ProblemType categorizes a problem found in a role.
+type ProblemType string
+
+const (
+	ProblemMissingReference ProblemType = "MissingReference"
+	ProblemCycle            ProblemType = "InheritanceCycle"
+	ProblemEscalation       ProblemType = "PermissionEscalation"
+	ProblemInvalidTaxonomy  ProblemType = "InvalidTaxonomy"
+)
+
+// Problem is an issue found in a role.
+type Problem struct {
+	Type    ProblemType
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s", p.Type, p.Message)
+}
+
+// ReadOnlyVerbs are the verbs a read-only role may be granted.
+var ReadOnlyVerbs = []string{"get", "list", "watch"}
+
+// Validate resolves the role with the given key and reports missing
+// references and cycles. When readOnly is set, every inherited role that grants
+// a permission with a verb other than ReadOnlyVerbs is reported as an
+// escalation.
+func (g *Graph) Validate(key Key, readOnly bool) []Problem {
+	resolution := g.Resolve(key)
+
+	var problems []Problem
+	for _, path := range resolution.Missing {
+		problems = append(problems, Problem{
+			Type:    ProblemMissingReference,
+			Message: fmt.Sprintf("inherited role %s does not exist (%s)", path[len(path)-1], path),
+		})
+	}
+	for _, cycle := range resolution.Cycles {
+		problems = append(problems, Problem{
+			Type:    ProblemCycle,
+			Message: fmt.Sprintf("inheritance cycle %s", cycle),
+		})
+	}
+
+	if readOnly {
+		// Report each role granting write permissions once, with its first
+		// write permission in sorted order.
+		writes := map[string][]string{}
+		paths := map[string]Path{}
+		for permission, path := range resolution.Permissions {
+			if slices.Contains(ReadOnlyVerbs, Verb(permission)) {
+				continue
+			}
+			id := path.String()
+			writes[id] = append(writes[id], permission)
+			paths[id] = path
+		}
+		ids := make([]string, 0, len(writes))
+		for id := range writes {
+			ids = append(ids, id)
+		}
+		slices.Sort(ids)
+		for _, id := range ids {
+			permissions := writes[id]
+			slices.Sort(permissions)
+			problems = append(problems, Problem{
+				Type: ProblemEscalation,
+				Message: fmt.Sprintf("read-only role inherits %d write permission(s) such as %q through %s",
+					len(permissions), permissions[0], paths[id]),
+			})
+		}
+	}
+
+	return problems
+}
+
+// Verb returns the verb of a permission in the form
+// `<service>/<resource>.<verb>`.
+func Verb(permission string) string {
+	if i := strings.LastIndex(permission, "."); i >= 0 && i > strings.LastIndex(permission, "/") {
+		return permission[i+1:]
+	}
+	return ""
+}
@@ -0,0 +1,177 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package roles
+
+import (
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	iamv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
+
+	"go.datum.net/datum/internal/manifest"
+)
+
+func newRole(namespace, name string, permissions []string, inherited ...string) iamv1alpha1.Role {
+	role := iamv1alpha1.Role{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec:       iamv1alpha1.RoleSpec{IncludedPermissions: permissions},
+	}
+	for _, ref := range inherited {
+		ns, n, _ := strings.Cut(ref, "/")
+		role.Spec.InheritedRoles = append(role.Spec.InheritedRoles, iamv1alpha1.ScopedRoleReference{Namespace: ns, Name: n})
+	}
+	return role
+}
+
+func TestValidate(t *testing.T) {
+	graph := NewGraph([]iamv1alpha1.Role{
+		newRole("datum-cloud", "viewer", nil, "milo-system/projects-viewer", "milo-system/projects-admin", "milo-system/missing"),
+		newRole("datum-cloud", "editor", nil, "datum-cloud/viewer", "milo-system/a"),
+		newRole("milo-system", "projects-viewer", []string{"resourcemanager.miloapis.com/projects.get"}),
+		newRole("milo-system", "projects-admin", []string{"resourcemanager.miloapis.com/projects.delete"}),
+		newRole("milo-system", "a", nil, "milo-system/b"),
+		newRole("milo-system", "b", nil, "milo-system/a"),
+	})
+
+	problems := graph.Validate(Key{Namespace: "datum-cloud", Name: "viewer"}, true)
+	want := []string{
+		"MissingReference: inherited role milo-system/missing does not exist (datum-cloud/viewer -> milo-system/missing)",
+		`PermissionEscalation: read-only role inherits 1 write permission(s) such as "resourcemanager.miloapis.com/projects.delete" through datum-cloud/viewer -> milo-system/projects-admin`,
+	}
+	assertProblems(t, problems, want)
+
+	problems = graph.Validate(Key{Namespace: "datum-cloud", Name: "editor"}, false)
+	want = []string{
+		"MissingReference: inherited role milo-system/missing does not exist (datum-cloud/editor -> datum-cloud/viewer -> milo-system/missing)",
+		"InheritanceCycle: inheritance cycle milo-system/a -> milo-system/b -> milo-system/a",
+	}
+	assertProblems(t, problems, want)
+}
+
+func TestRepositoryRoleTaxonomy(t *testing.T) {
+	objects, err := manifest.Load("../../config/assignable-organization-roles/roles")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	var catalog []iamv1alpha1.Role
+	for _, obj := range objects {
+		role := iamv1alpha1.Role{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object.Object, &role); err != nil {
+			t.Fatalf("%s: %v", obj.Path, err)
+		}
+		role.Namespace = "datum-cloud"
+		catalog = append(catalog, role)
+	}
+	if len(catalog) == 0 {
+		t.Fatal("no roles loaded")
+	}
+
+	for key, problems := range ValidateTaxonomy(catalog) {
+		for _, p := range problems {
+			t.Errorf("%s: %s", key, p)
+		}
+	}
+}
+
+func TestValidateTaxonomy(t *testing.T) {
+	first := newRole("datum-cloud", "first", nil)
+	first.Annotations = map[string]string{
+		DisplayNameAnnotation: "First",
+		DescriptionAnnotation: "The first role",
+		ProductAnnotation:     "Access Everything",
+		SortOrderAnnotation:   "10",
+	}
+	second := newRole("datum-cloud", "second", nil)
+	second.Annotations = map[string]string{
+		DisplayNameAnnotation:                  "Second",
+		SortOrderAnnotation:                    "10",
+		TaxonomyAnnotationPrefix + "sort-ordr": "20",
+	}
+
+	problems := ValidateTaxonomy([]iamv1alpha1.Role{first, second})
+	assertProblems(t, problems[KeyOf(&first)], []string{
+		"InvalidTaxonomy: sort order 10 is shared with second",
+	})
+	assertProblems(t, problems[KeyOf(&second)], []string{
+		"InvalidTaxonomy: annotation kubernetes.io/description is required",
+		"InvalidTaxonomy: annotation taxonomy.miloapis.com/product is required",
+		"InvalidTaxonomy: unknown taxonomy annotation taxonomy.miloapis.com/sort-ordr",
+		"InvalidTaxonomy: sort order 10 is shared with first",
+	})
+}
+
+func assertProblems(t *testing.T, problems []Problem, want []string) {
+	t.Helper()
+	if len(problems) != len(want) {
+		t.Fatalf("got %d problems, want %d: %v", len(problems), len(want), problems)
+	}
+	for i, p := range problems {
+		if p.String() != want[i] {
+			t.Errorf("problem %d = %q, want %q", i, p.String(), want[i])
+		}
+	}
+}
+
+func TestExplainAndDiff(t *testing.T) {
+	graph := NewGraph([]iamv1alpha1.Role{
+		newRole("datum-cloud", "viewer", nil, "milo-system/projects-viewer"),
+		newRole("datum-cloud", "editor", []string{"resourcemanager.miloapis.com/projects.update"}, "datum-cloud/viewer", "milo-system/missing"),
+		newRole("milo-system", "projects-viewer", []string{"resourcemanager.miloapis.com/projects.get", "resourcemanager.miloapis.com/projects.list"}),
+	})
+
+	editor, err := graph.Explain(Key{Namespace: "datum-cloud", Name: "editor"})
+	if err != nil {
+		t.Fatalf("Explain() error = %v", err)
+	}
+	if len(editor.Permissions) != 3 || editor.Permissions[0].Permission != "resourcemanager.miloapis.com/projects.get" ||
+		editor.Permissions[0].GrantedBy != "milo-system/projects-viewer" {
+		t.Errorf("Explain() permissions = %+v", editor.Permissions)
+	}
+	if len(editor.Unresolved) != 1 || editor.Unresolved[0] != "milo-system/missing" {
+		t.Errorf("Explain() unresolved = %v", editor.Unresolved)
+	}
+
+	viewer, err := graph.Explain(Key{Namespace: "datum-cloud", Name: "viewer"})
+	if err != nil {
+		t.Fatalf("Explain() error = %v", err)
+	}
+	diff := DiffExplanations(viewer, editor)
+	if diff.Common != 2 || len(diff.Removed) != 0 || len(diff.Added) != 1 ||
+		diff.Added[0].Permission != "resourcemanager.miloapis.com/projects.update" {
+		t.Errorf("DiffExplanations() = %+v", diff)
+	}
+
+	if _, err := graph.Explain(Key{Namespace: "datum-cloud", Name: "owner"}); err == nil {
+		t.Error("Explain() expected error for unknown role")
+	}
+}
+
+func TestFromManifests(t *testing.T) {
+	var objects []manifest.Object
+	for _, ref := range []string{"/editor", "/custom", "milo-system/projects-viewer"} {
+		ns, name, _ := strings.Cut(ref, "/")
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("iam.miloapis.com/v1alpha1")
+		obj.SetKind("Role")
+		obj.SetNamespace(ns)
+		obj.SetName(name)
+		objects = append(objects, manifest.Object{Path: "roles.yaml", Object: obj})
+	}
+
+	roles, err := FromManifests(objects, "datum-cloud")
+	if err != nil {
+		t.Fatalf("FromManifests() error = %v", err)
+	}
+	var got []string
+	for _, role := range roles {
+		got = append(got, role.Namespace+"/"+role.Name)
+	}
+	want := []string{"datum-cloud/editor", "/custom", "milo-system/projects-viewer"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("FromManifests() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package roles
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	iamv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
+)
+
+const (
+	// DisplayNameAnnotation is the name of a role shown in the portal.
+	DisplayNameAnnotation = "kubernetes.io/display-name"
+
+	// DescriptionAnnotation describes a role in the portal.
+	DescriptionAnnotation = "kubernetes.io/description"
+
+	// TaxonomyAnnotationPrefix is the prefix of the annotations the portal uses
+	// to group and sort assignable roles.
+	TaxonomyAnnotationPrefix = "taxonomy.miloapis.com/"
+
+	// SortOrderAnnotation is the position of a role in the portal, as a
+	// non-negative integer. Roles are sorted in ascending order.
+	SortOrderAnnotation = TaxonomyAnnotationPrefix + "sort-order"
+
+	// ProductAnnotation is the product a role is grouped under in the portal.
+	ProductAnnotation = TaxonomyAnnotationPrefix + "product"
+)
+
+var taxonomyAnnotations = []string{SortOrderAnnotation, ProductAnnotation}
+
+// ValidateTaxonomy validates the display and taxonomy annotations of a catalog
+// of assignable roles. Sort orders must be unique within the catalog.
+func ValidateTaxonomy(catalog []iamv1alpha1.Role) map[Key][]Problem {
+	problems := map[Key][]Problem{}
+	report := func(role *iamv1alpha1.Role, format string, args ...any) {
+		key := KeyOf(role)
+		problems[key] = append(problems[key], Problem{Type: ProblemInvalidTaxonomy, Message: fmt.Sprintf(format, args...)})
+	}
+
+	sortOrders := map[int][]string{}
+	for i := range catalog {
+		role := &catalog[i]
+		annotations := role.GetAnnotations()
+
+		for _, key := range []string{DisplayNameAnnotation, DescriptionAnnotation, ProductAnnotation} {
+			if strings.TrimSpace(annotations[key]) == "" {
+				report(role, "annotation %s is required", key)
+			}
+		}
+
+		if value, ok := annotations[SortOrderAnnotation]; !ok {
+			report(role, "annotation %s is required", SortOrderAnnotation)
+		} else if order, err := strconv.Atoi(value); err != nil || order < 0 {
+			report(role, "annotation %s must be a non-negative integer, got %q", SortOrderAnnotation, value)
+		} else {
+			sortOrders[order] = append(sortOrders[order], role.Name)
+		}
+
+		var unknown []string
+		for key := range annotations {
+			if strings.HasPrefix(key, TaxonomyAnnotationPrefix) && !slices.Contains(taxonomyAnnotations, key) {
+				unknown = append(unknown, key)
+			}
+		}
+		sort.Strings(unknown)
+		for _, key := range unknown {
+			report(role, "unknown taxonomy annotation %s", key)
+		}
+	}
+
+	for i := range catalog {
+		role := &catalog[i]
+		order, err := strconv.Atoi(role.GetAnnotations()[SortOrderAnnotation])
+		if err != nil || len(sortOrders[order]) < 2 {
+			continue
+		}
+		report(role, "sort order %d is shared with %s", order, strings.Join(others(sortOrders[order], role.Name), ", "))
+	}
+
+	return problems
+}
+
+func others(names []string, name string) []string {
+	var out []string
+	for _, n := range names {
+		if n != name {
+			out = append(out, n)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,454 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package sharding spreads the reconciliation of Users across the replicas of
+// the controller manager. The hash space of User UIDs is split into a fixed
+// number of shards, and each replica claims shards through Leases, so every
+// User is reconciled by a single replica.
+package sharding
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	iamv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
+)
+
+const (
+	// SetLabel is set to the name of the coordinator on its Leases.
+	SetLabel = "sharding.datumapis.com/set"
+
+	// LeaseLabel is the kind of a Lease of the coordinator, either
+	// LeaseKindShard or LeaseKindMember.
+	LeaseLabel = "sharding.datumapis.com/lease"
+
+	// LeaseKindShard is the LeaseLabel value of the Lease held by the owner
+	// of a shard.
+	LeaseKindShard = "shard"
+
+	// LeaseKindMember is the LeaseLabel value of the Lease each replica
+	// renews to announce itself, so shards are balanced across live replicas.
+	LeaseKindMember = "member"
+)
+
+var (
+	shardOwned = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "datum_user_shard_owned",
+		Help: "Whether the replica owns the shard of Users.",
+	}, []string{"shard"})
+	shardMembers = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "datum_user_shard_members",
+		Help: "Number of live replicas sharing the shards of Users, as seen by the replica.",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(shardOwned, shardMembers)
+}
+
+// Coordinator claims shards of Users for the replica. It is a manager
+// runnable that runs on every replica regardless of leader election.
+type Coordinator struct {
+	// Client creates and renews the Leases. It must not be backed by the
+	// cache, nor intercepted in dry-run mode.
+	Client client.Client
+
+	// Reader lists the Users of newly claimed shards to reconcile them, and
+	// reads the Users of the requests of the reconcilers of Reconciler.
+	Reader client.Reader
+
+	// Name prefixes the names of the Leases. Replicas sharing Users must use
+	// the same name.
+	Name string
+
+	// Identity identifies the replica in the Leases it holds. It must be a
+	// valid object name.
+	Identity string
+
+	// Namespace is the namespace of the Leases.
+	Namespace string
+
+	// Shards is the number of shards the hash space of User UIDs is split
+	// into. It must be the same on every replica.
+	Shards int
+
+	// LeaseDuration is how long a replica owns a shard without renewing its
+	// Lease.
+	LeaseDuration time.Duration
+
+	// RenewInterval is the time between two renewals of the Leases.
+	RenewInterval time.Duration
+
+	mu sync.RWMutex
+	// ownedUntil is when the ownership of each owned shard expires unless
+	// renewed.
+	ownedUntil map[int]time.Time
+	// subscribers receive the Users of newly claimed shards.
+	subscribers []chan event.GenericEvent
+}
+
+// Shard returns the shard of a User UID. Shards are contiguous ranges of the
+// 32-bit FNV-1a hash of the UID.
+func (c *Coordinator) Shard(uid types.UID) int {
+	hasher := fnv.New32a()
+	//revive:disable-next-line:unhandled-error
+	hasher.Write([]byte(uid))
+	return int(uint64(hasher.Sum32()) * uint64(c.Shards) >> 32)
+}
+
+// Owns reports whether the replica owns the shard of the object's UID.
+func (c *Coordinator) Owns(obj client.Object) bool {
+	shard := c.Shard(obj.GetUID())
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return time.Now().Before(c.ownedUntil[shard])
+}
+
+// For configures the builder to reconcile obj, a User, for the shards owned
+// by the replica. Users are enqueued again when their shard is claimed. The
+// controller must be built with Options so that it runs on every replica, and
+// its reconciler wrapped with Reconciler. A nil coordinator reconciles every
+// User.
+func (c *Coordinator) For(b *builder.Builder, obj client.Object) *builder.Builder {
+	if c == nil {
+		return b.For(obj)
+	}
+
+	events := make(chan event.GenericEvent)
+	c.mu.Lock()
+	c.subscribers = append(c.subscribers, events)
+	c.mu.Unlock()
+
+	return b.For(obj, builder.WithPredicates(predicate.NewPredicateFuncs(c.Owns))).
+		WatchesRawSource(source.Channel(events, &handler.EnqueueRequestForObject{}))
+}
+
+// Reconciler restricts r to the Users of the shards owned by the replica.
+// Requests that do not go through the predicate of For, such as requeues and
+// requests from other sources, are dropped when the shard of their User is
+// not owned. Requests for Users that do not exist are passed to r. A nil
+// coordinator returns r unchanged.
+func (c *Coordinator) Reconciler(r reconcile.Reconciler) reconcile.Reconciler {
+	if c == nil {
+		return r
+	}
+	return reconcile.Func(func(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+		user := &iamv1alpha1.User{}
+		if err := c.Reader.Get(ctx, req.NamespacedName, user); client.IgnoreNotFound(err) != nil {
+			return reconcile.Result{}, fmt.Errorf("failed to get user: %w", err)
+		} else if err == nil && !c.Owns(user) {
+			logf.FromContext(ctx).V(1).Info("User is in a shard owned by another replica, skipping")
+			return reconcile.Result{}, nil
+		}
+		return r.Reconcile(ctx, req)
+	})
+}
+
+// Options returns opts for a controller built with For, running the
+// controller on every replica rather than only on the leader. A nil
+// coordinator returns opts unchanged.
+func (c *Coordinator) Options(opts controller.Options) controller.Options {
+	if c != nil {
+		opts.NeedLeaderElection = ptr.To(false)
+	}
+	return opts
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, so shards are
+// claimed by every replica.
+func (c *Coordinator) NeedLeaderElection() bool {
+	return false
+}
+
+// Start claims and renews shards until the context is done, then releases
+// them so other replicas take them over without waiting for their Leases to
+// expire.
+func (c *Coordinator) Start(ctx context.Context) error {
+	logger := logf.FromContext(ctx).WithName("sharding").WithValues("identity", c.Identity)
+	ctx = logf.IntoContext(ctx, logger)
+
+	ticker := time.NewTicker(c.RenewInterval)
+	defer ticker.Stop()
+	for {
+		if err := c.sync(ctx, time.Now()); err != nil {
+			logger.Error(err, "Failed to sync shards")
+		}
+		select {
+		case <-ctx.Done():
+			c.release(context.WithoutCancel(ctx))
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// sync renews the member Lease of the replica, releases the shards above its
+// fair share, renews the shards it keeps and claims free shards up to its fair
+// share. The fair share is the number of shards divided by the number of live
+// members, rounded up. The Users of claimed shards are sent to the
+// subscribers.
+func (c *Coordinator) sync(ctx context.Context, now time.Time) error {
+	logger := logf.FromContext(ctx)
+
+	if err := c.renew(ctx, c.memberLeaseName(), LeaseKindMember, now); err != nil {
+		return fmt.Errorf("failed to renew member lease: %w", err)
+	}
+
+	leases := &coordinationv1.LeaseList{}
+	if err := c.Client.List(ctx, leases, client.InNamespace(c.Namespace), client.MatchingLabels{SetLabel: c.Name}); err != nil {
+		return fmt.Errorf("failed to list leases: %w", err)
+	}
+	members := 0
+	shardLeases := map[string]*coordinationv1.Lease{}
+	for i := range leases.Items {
+		lease := &leases.Items[i]
+		switch lease.Labels[LeaseLabel] {
+		case LeaseKindMember:
+			if !expired(lease, now) {
+				members++
+				continue
+			}
+			// Replicas that stopped without releasing their member Lease are
+			// forgotten once it expires.
+			if err := c.Client.Delete(ctx, lease); err != nil && !apierrors.IsNotFound(err) {
+				logger.Error(err, "Failed to delete expired member lease", "lease", lease.Name)
+			}
+		case LeaseKindShard:
+			shardLeases[lease.Name] = lease
+		}
+	}
+	members = max(members, 1)
+	shardMembers.Set(float64(members))
+	fairShare := (c.Shards + members - 1) / members
+
+	var owned, free []int
+	for shard := range c.Shards {
+		lease := shardLeases[c.shardLeaseName(shard)]
+		switch {
+		case lease != nil && ptr.Deref(lease.Spec.HolderIdentity, "") == c.Identity:
+			owned = append(owned, shard)
+		case lease == nil || ptr.Deref(lease.Spec.HolderIdentity, "") == "" || expired(lease, now):
+			free = append(free, shard)
+		}
+	}
+
+	// Release the shards above the fair share, so replicas that joined can
+	// claim them.
+	for len(owned) > fairShare {
+		shard := owned[len(owned)-1]
+		owned = owned[:len(owned)-1]
+		c.setOwned(shard, time.Time{})
+		if err := c.releaseShard(ctx, shardLeases[c.shardLeaseName(shard)]); err != nil {
+			logger.Error(err, "Failed to release shard", "shard", shard)
+		} else {
+			logger.Info("Released shard", "shard", shard)
+		}
+	}
+
+	var claimed []int
+	for _, shard := range owned {
+		if err := c.renew(ctx, c.shardLeaseName(shard), LeaseKindShard, now); err != nil {
+			logger.Error(err, "Failed to renew shard", "shard", shard)
+			continue
+		}
+		c.setOwned(shard, now.Add(c.LeaseDuration))
+	}
+	for _, shard := range free {
+		if len(owned)+len(claimed) >= fairShare {
+			break
+		}
+		if err := c.claim(ctx, shardLeases[c.shardLeaseName(shard)], shard, now); err != nil {
+			// Another replica claimed the shard first.
+			if apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err) {
+				continue
+			}
+			logger.Error(err, "Failed to claim shard", "shard", shard)
+			continue
+		}
+		c.setOwned(shard, now.Add(c.LeaseDuration))
+		claimed = append(claimed, shard)
+	}
+	if len(claimed) > 0 {
+		logger.Info("Claimed shards", "shards", claimed, "members", members)
+		// Enqueueing waits for the controllers to consume the events, which
+		// must not delay the renewal of the Leases.
+		go func() {
+			if err := c.enqueue(ctx, claimed); err != nil {
+				logger.Error(err, "Failed to enqueue users of claimed shards", "shards", claimed)
+			}
+		}()
+	}
+	return nil
+}
+
+// enqueue sends the Users of the given shards to the subscribers.
+func (c *Coordinator) enqueue(ctx context.Context, shards []int) error {
+	if c.Reader == nil {
+		return nil
+	}
+	list := &iamv1alpha1.UserList{}
+	if err := c.Reader.List(ctx, list); err != nil {
+		return fmt.Errorf("failed to list users: %w", err)
+	}
+
+	c.mu.RLock()
+	subscribers := slices.Clone(c.subscribers)
+	c.mu.RUnlock()
+	for i := range list.Items {
+		user := &list.Items[i]
+		if !slices.Contains(shards, c.Shard(user.UID)) {
+			continue
+		}
+		for _, subscriber := range subscribers {
+			select {
+			case subscriber <- event.GenericEvent{Object: user}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+	return nil
+}
+
+// claim takes the Lease of a free shard. Updates use the resource version of
+// the listed Lease, so only one replica claims a shard.
+func (c *Coordinator) claim(ctx context.Context, lease *coordinationv1.Lease, shard int, now time.Time) error {
+	if lease == nil {
+		lease = c.newLease(c.shardLeaseName(shard), LeaseKindShard, now)
+		return c.Client.Create(ctx, lease)
+	}
+	lease = lease.DeepCopy()
+	c.hold(lease, now)
+	lease.Spec.AcquireTime = ptr.To(metav1.NewMicroTime(now))
+	lease.Spec.LeaseTransitions = ptr.To(ptr.Deref(lease.Spec.LeaseTransitions, 0) + 1)
+	return c.Client.Update(ctx, lease)
+}
+
+// renew renews a Lease held by the replica, creating it if needed.
+func (c *Coordinator) renew(ctx context.Context, name, kind string, now time.Time) error {
+	lease := &coordinationv1.Lease{}
+	err := c.Client.Get(ctx, client.ObjectKey{Namespace: c.Namespace, Name: name}, lease)
+	if apierrors.IsNotFound(err) {
+		return c.Client.Create(ctx, c.newLease(name, kind, now))
+	}
+	if err != nil {
+		return err
+	}
+	if ptr.Deref(lease.Spec.HolderIdentity, "") != c.Identity {
+		return fmt.Errorf("lease %s is held by %q", name, ptr.Deref(lease.Spec.HolderIdentity, ""))
+	}
+	c.hold(lease, now)
+	return c.Client.Update(ctx, lease)
+}
+
+// releaseShard clears the holder of a shard Lease.
+func (c *Coordinator) releaseShard(ctx context.Context, lease *coordinationv1.Lease) error {
+	lease = lease.DeepCopy()
+	lease.Spec.HolderIdentity = nil
+	return c.Client.Update(ctx, lease)
+}
+
+// release gives up all shards and the member Lease of the replica.
+func (c *Coordinator) release(ctx context.Context) {
+	logger := logf.FromContext(ctx)
+
+	c.mu.Lock()
+	var shards []int
+	for shard := range c.ownedUntil {
+		shards = append(shards, shard)
+	}
+	c.mu.Unlock()
+
+	for _, shard := range shards {
+		c.setOwned(shard, time.Time{})
+		lease := &coordinationv1.Lease{}
+		if err := c.Client.Get(ctx, client.ObjectKey{Namespace: c.Namespace, Name: c.shardLeaseName(shard)}, lease); err != nil {
+			continue
+		}
+		if ptr.Deref(lease.Spec.HolderIdentity, "") != c.Identity {
+			continue
+		}
+		if err := c.releaseShard(ctx, lease); err != nil {
+			logger.Error(err, "Failed to release shard", "shard", shard)
+		}
+	}
+
+	member := &coordinationv1.Lease{ObjectMeta: metav1.ObjectMeta{Namespace: c.Namespace, Name: c.memberLeaseName()}}
+	if err := c.Client.Delete(ctx, member); err != nil && !apierrors.IsNotFound(err) {
+		logger.Error(err, "Failed to delete member lease")
+	}
+}
+
+func (c *Coordinator) setOwned(shard int, until time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ownedUntil == nil {
+		c.ownedUntil = map[int]time.Time{}
+	}
+	if until.IsZero() {
+		delete(c.ownedUntil, shard)
+		shardOwned.WithLabelValues(strconv.Itoa(shard)).Set(0)
+		return
+	}
+	c.ownedUntil[shard] = until
+	shardOwned.WithLabelValues(strconv.Itoa(shard)).Set(1)
+}
+
+func (c *Coordinator) newLease(name, kind string, now time.Time) *coordinationv1.Lease {
+	lease := &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: c.Namespace,
+			Name:      name,
+			Labels:    map[string]string{SetLabel: c.Name, LeaseLabel: kind},
+		},
+		Spec: coordinationv1.LeaseSpec{
+			AcquireTime: ptr.To(metav1.NewMicroTime(now)),
+		},
+	}
+	c.hold(lease, now)
+	return lease
+}
+
+func (c *Coordinator) hold(lease *coordinationv1.Lease, now time.Time) {
+	lease.Spec.HolderIdentity = ptr.To(c.Identity)
+	lease.Spec.LeaseDurationSeconds = ptr.To(int32(c.LeaseDuration.Seconds()))
+	lease.Spec.RenewTime = ptr.To(metav1.NewMicroTime(now))
+}
+
+func (c *Coordinator) shardLeaseName(shard int) string {
+	return fmt.Sprintf("%s-%d", c.Name, shard)
+}
+
+func (c *Coordinator) memberLeaseName() string {
+	return fmt.Sprintf("%s-member-%s", c.Name, c.Identity)
+}
+
+// expired reports whether a Lease has not been renewed within its duration.
+func expired(lease *coordinationv1.Lease, now time.Time) bool {
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return true
+	}
+	duration := time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second
+	return !now.Before(lease.Spec.RenewTime.Add(duration))
+}
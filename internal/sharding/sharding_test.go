@@ -0,0 +1,138 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package sharding
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	iamv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
+)
+
+func TestShard(t *testing.T) {
+	c := &Coordinator{Shards: 8}
+	counts := make([]int, c.Shards)
+	for i := range 1000 {
+		shard := c.Shard(types.UID(fmt.Sprintf("uid-%d", i)))
+		if shard < 0 || shard >= c.Shards {
+			t.Fatalf("shard %d out of range", shard)
+		}
+		counts[shard]++
+	}
+	for shard, count := range counts {
+		if count == 0 {
+			t.Errorf("expected shard %d to hold some UIDs", shard)
+		}
+	}
+}
+
+func TestCoordinator(t *testing.T) {
+	ctx := context.Background()
+	leaseClient := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).Build()
+	newCoordinator := func(identity string) *Coordinator {
+		return &Coordinator{
+			Client:        leaseClient,
+			Name:          "datum-user-shard",
+			Identity:      identity,
+			Namespace:     "datum-system",
+			Shards:        8,
+			LeaseDuration: time.Minute,
+			RenewInterval: 10 * time.Second,
+		}
+	}
+	sync := func(t *testing.T, c *Coordinator) {
+		t.Helper()
+		if err := c.sync(ctx, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	owned := func(c *Coordinator) int {
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+		return len(c.ownedUntil)
+	}
+	users := make([]client.Object, 100)
+	for i := range users {
+		users[i] = &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{UID: types.UID(fmt.Sprintf("uid-%d", i))}}
+	}
+
+	first, second := newCoordinator("first"), newCoordinator("second")
+
+	sync(t, first)
+	if owned(first) != 8 {
+		t.Fatalf("expected a single replica to own all shards, got %d", owned(first))
+	}
+
+	// The second replica joins: the first releases half of the shards on its
+	// next sync, and the second claims them.
+	sync(t, second)
+	if owned(second) != 0 {
+		t.Fatalf("expected shards held by the first replica not to be claimed, got %d", owned(second))
+	}
+	sync(t, first)
+	sync(t, second)
+	if owned(first) != 4 || owned(second) != 4 {
+		t.Fatalf("expected shards to be balanced, got %d and %d", owned(first), owned(second))
+	}
+	for _, user := range users {
+		if first.Owns(user) == second.Owns(user) {
+			t.Fatalf("expected user %s to be owned by exactly one replica", user.GetUID())
+		}
+	}
+
+	// The second replica stops: its shards are released and claimed by the
+	// first replica.
+	second.release(ctx)
+	if owned(second) != 0 {
+		t.Fatalf("expected a stopped replica to own no shard, got %d", owned(second))
+	}
+	sync(t, first)
+	if owned(first) != 8 {
+		t.Fatalf("expected the remaining replica to own all shards, got %d", owned(first))
+	}
+}
+
+func TestCoordinatorReconciler(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iamv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	owned := &iamv1alpha1.User{ObjectMeta: metav1.ObjectMeta{Name: "owned", UID: "uid-owned"}}
+	other := &iamv1alpha1.User{ObjectMeta: metav1.ObjectMeta{Name: "other"}}
+	c := &Coordinator{Shards: 8}
+	// The replica owns the shard of the first User only.
+	c.setOwned(c.Shard(owned.UID), time.Now().Add(time.Minute))
+	for i := 0; ; i++ {
+		other.UID = types.UID(fmt.Sprintf("uid-%d", i))
+		if !c.Owns(other) {
+			break
+		}
+	}
+	c.Reader = fake.NewClientBuilder().WithScheme(scheme).WithObjects(owned, other).Build()
+
+	var reconciled []string
+	r := c.Reconciler(reconcile.Func(func(_ context.Context, req reconcile.Request) (reconcile.Result, error) {
+		reconciled = append(reconciled, req.Name)
+		return reconcile.Result{}, nil
+	}))
+	// Requests may come from sources other than the watch of the Users, such
+	// as requeues.
+	for _, name := range []string{"owned", "other", "deleted"} {
+		if _, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Name: name}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := []string{"owned", "deleted"}; fmt.Sprint(reconciled) != fmt.Sprint(want) {
+		t.Errorf("reconciled %v, want %v", reconciled, want)
+	}
+}
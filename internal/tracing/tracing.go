@@ -0,0 +1,63 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package tracing instruments reconciles and API calls with OpenTelemetry.
+// Spans are recorded with the global tracer provider, so they are dropped
+// unless tracing is enabled in the controller manager config.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// TracerName is the name of the tracer spans are recorded with.
+const TracerName = "go.datum.net/datum"
+
+// Reconciler wraps a reconciler to record a span for each reconcile. Spans of
+// API calls made with the reconcile's context are recorded as its children.
+func Reconciler(controller string, r reconcile.Reconciler) reconcile.Reconciler {
+	return &tracingReconciler{controller: controller, reconciler: r}
+}
+
+type tracingReconciler struct {
+	controller string
+	reconciler reconcile.Reconciler
+}
+
+func (r *tracingReconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	ctx, span := otel.Tracer(TracerName).Start(ctx, fmt.Sprintf("Reconcile %s", r.controller),
+		trace.WithAttributes(
+			attribute.String("controller", r.controller),
+			attribute.String("k8s.object.namespace", req.Namespace),
+			attribute.String("k8s.object.name", req.Name),
+		),
+	)
+	defer span.End()
+
+	result, err := r.reconciler.Reconcile(ctx, req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return result, err
+}
+
+// WrapTransport records a span for each request sent through the transport,
+// and propagates the trace context of the request to the API server. It is
+// meant to be used with rest.Config.Wrap, so clients created from copies of
+// the config, such as impersonated clients, are traced as well.
+func WrapTransport(rt http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(rt,
+		otelhttp.WithSpanNameFormatter(func(_ string, req *http.Request) string {
+			return fmt.Sprintf("HTTP %s", req.Method)
+		}),
+	)
+}
@@ -0,0 +1,80 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func setupTracing(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		_ = provider.Shutdown(context.Background())
+		otel.SetTracerProvider(noop.NewTracerProvider())
+	})
+	return exporter
+}
+
+func TestReconciler(t *testing.T) {
+	exporter := setupTracing(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("traceparent") == "" {
+			t.Error("expected the trace context to be propagated to the server")
+		}
+	}))
+	defer server.Close()
+	httpClient := &http.Client{Transport: WrapTransport(http.DefaultTransport)}
+
+	reconcileErr := errors.New("boom")
+	r := Reconciler("test", reconcile.Func(func(ctx context.Context, _ reconcile.Request) (reconcile.Result, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		_ = resp.Body.Close()
+		return reconcile.Result{}, reconcileErr
+	}))
+
+	if _, err := r.Reconcile(context.Background(), reconcile.Request{}); !errors.Is(err, reconcileErr) {
+		t.Fatalf("Reconcile() error = %v, want %v", err, reconcileErr)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	client, reconciled := spans[0], spans[1]
+	if reconciled.Name != "Reconcile test" {
+		t.Errorf("reconcile span name = %q", reconciled.Name)
+	}
+	if reconciled.Status.Code != codes.Error {
+		t.Errorf("expected reconcile span to record the error, got status %v", reconciled.Status)
+	}
+	if client.Name != "HTTP GET" {
+		t.Errorf("client span name = %q", client.Name)
+	}
+	if client.Parent.SpanID() != reconciled.SpanContext.SpanID() {
+		t.Error("expected client span to be a child of the reconcile span")
+	}
+}
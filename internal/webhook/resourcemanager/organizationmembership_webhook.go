@@ -0,0 +1,178 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package resourcemanager contains admission webhooks for Milo resource manager
+// resources.
+package resourcemanager
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	iamv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
+	resourcemanagerv1alpha1 "go.miloapis.com/milo/pkg/apis/resourcemanager/v1alpha1"
+
+	"go.datum.net/datum/internal/roles"
+)
+
+var membershipResource = schema.GroupResource{Group: "resourcemanager.miloapis.com", Resource: "organizationmemberships"}
+
+// garbageCollectorUser is the service account of the Kubernetes garbage
+// collector, which deletes the memberships owned by deleted objects.
+const garbageCollectorUser = "system:serviceaccount:kube-system:generic-garbage-collector"
+
+// +kubebuilder:object:generate=true
+
+type LastOwnerProtectionWebhookConfig struct {
+	// OwnerRoles are the roles that make a member an owner of an organization.
+	// Roles inheriting one of them also count. Defaults to the datum-cloud
+	// owner role.
+	OwnerRoles []resourcemanagerv1alpha1.RoleReference `json:"ownerRoles,omitempty"`
+
+	// ExemptUsers may remove the last owner of an organization. Defaults to
+	// the service account of the controller manager.
+	ExemptUsers []string `json:"exemptUsers,omitempty"`
+
+	// ExemptGroups may remove the last owner of an organization, for example a
+	// break-glass group used by support. Defaults to datum:break-glass.
+	ExemptGroups []string `json:"exemptGroups,omitempty"`
+}
+
+// OrganizationMembershipValidator prevents the last owner of an organization
+// from being removed or downgraded. The memberships of users that are deleted,
+// and memberships deleted by the garbage collector, may always be deleted, so
+// the deletion of users and of their owners is never blocked.
+type OrganizationMembershipValidator struct {
+	Client client.Client
+
+	Config LastOwnerProtectionWebhookConfig
+}
+
+// +kubebuilder:webhook:path=/validate-resourcemanager-miloapis-com-v1alpha1-organizationmembership,mutating=false,failurePolicy=fail,sideEffects=None,groups=resourcemanager.miloapis.com,resources=organizationmemberships,verbs=update;delete,versions=v1alpha1,name=vorganizationmembership-v1alpha1.datumapis.com,admissionReviewVersions=v1
+
+// +kubebuilder:rbac:groups=resourcemanager.miloapis.com,resources=organizations,verbs=get;list;watch
+// +kubebuilder:rbac:groups=resourcemanager.miloapis.com,resources=organizationmemberships,verbs=get;list;watch
+// +kubebuilder:rbac:groups=iam.miloapis.com,resources=roles,verbs=get;list;watch
+// +kubebuilder:rbac:groups=iam.miloapis.com,resources=users,verbs=get;list;watch
+
+// SetupWebhookWithManager registers the webhook with the Manager.
+func (v *OrganizationMembershipValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&resourcemanagerv1alpha1.OrganizationMembership{}).
+		WithValidator(v).
+		Complete()
+}
+
+// ValidateCreate allows all memberships to be created.
+func (v *OrganizationMembershipValidator) ValidateCreate(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// ValidateUpdate denies removing the owner roles of the last owner.
+func (v *OrganizationMembershipValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldMembership, ok := oldObj.(*resourcemanagerv1alpha1.OrganizationMembership)
+	if !ok {
+		return nil, fmt.Errorf("expected an OrganizationMembership but got %T", oldObj)
+	}
+	newMembership, ok := newObj.(*resourcemanagerv1alpha1.OrganizationMembership)
+	if !ok {
+		return nil, fmt.Errorf("expected an OrganizationMembership but got %T", newObj)
+	}
+	return nil, v.validateOwnerRemains(ctx, oldMembership, newMembership)
+}
+
+// ValidateDelete denies deleting the membership of the last owner, unless the
+// owner is deleted or the garbage collector deletes the membership.
+func (v *OrganizationMembershipValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	membership, ok := obj.(*resourcemanagerv1alpha1.OrganizationMembership)
+	if !ok {
+		return nil, fmt.Errorf("expected an OrganizationMembership but got %T", obj)
+	}
+
+	if req, err := admission.RequestFromContext(ctx); err == nil && req.UserInfo.Username == garbageCollectorUser {
+		return nil, nil
+	}
+	user := &iamv1alpha1.User{}
+	if err := v.Client.Get(ctx, client.ObjectKey{Name: membership.Spec.UserRef.Name}, user); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, apierrors.NewInternalError(fmt.Errorf("failed to get user: %w", err))
+	}
+	if !user.DeletionTimestamp.IsZero() {
+		return nil, nil
+	}
+
+	return nil, v.validateOwnerRemains(ctx, membership, nil)
+}
+
+// validateOwnerRemains returns a Forbidden error when changing the membership
+// from old to updated, or deleting it when updated is nil, leaves its
+// organization without an owner.
+func (v *OrganizationMembershipValidator) validateOwnerRemains(ctx context.Context, old, updated *resourcemanagerv1alpha1.OrganizationMembership) error {
+	logger := logf.FromContext(ctx)
+
+	if v.exempt(ctx) {
+		return nil
+	}
+
+	allRoles := &iamv1alpha1.RoleList{}
+	if err := v.Client.List(ctx, allRoles); err != nil {
+		return apierrors.NewInternalError(fmt.Errorf("failed to list roles: %w", err))
+	}
+	graph := roles.NewGraph(allRoles.Items)
+
+	if !graph.Grants(old, v.Config.OwnerRoles) || (updated != nil && graph.Grants(updated, v.Config.OwnerRoles)) {
+		return nil
+	}
+
+	// Memberships are removed when their organization is deleted.
+	organization := &resourcemanagerv1alpha1.Organization{}
+	if err := v.Client.Get(ctx, client.ObjectKey{Name: old.Spec.OrganizationRef.Name}, organization); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return apierrors.NewInternalError(fmt.Errorf("failed to get organization: %w", err))
+	}
+	if !organization.DeletionTimestamp.IsZero() {
+		return nil
+	}
+
+	memberships := &resourcemanagerv1alpha1.OrganizationMembershipList{}
+	if err := v.Client.List(ctx, memberships, client.InNamespace(old.Namespace)); err != nil {
+		return apierrors.NewInternalError(fmt.Errorf("failed to list organization memberships: %w", err))
+	}
+	for i := range memberships.Items {
+		other := &memberships.Items[i]
+		if other.Name != old.Name && other.DeletionTimestamp.IsZero() && graph.Grants(other, v.Config.OwnerRoles) {
+			return nil
+		}
+	}
+
+	logger.Info("Denied removing the last owner of an organization", "organization", organization.Name, "membership", old.Name)
+	return apierrors.NewForbidden(membershipResource, old.Name,
+		fmt.Errorf("user %s is the last owner of organization %s; assign the owner role to another member first",
+			old.Spec.UserRef.Name, organization.Name))
+}
+
+// exempt reports whether the request was made by an exempt user or group.
+func (v *OrganizationMembershipValidator) exempt(ctx context.Context) bool {
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return false
+	}
+	if slices.Contains(v.Config.ExemptUsers, req.UserInfo.Username) {
+		return true
+	}
+	return slices.ContainsFunc(req.UserInfo.Groups, func(group string) bool {
+		return slices.Contains(v.Config.ExemptGroups, group)
+	})
+}
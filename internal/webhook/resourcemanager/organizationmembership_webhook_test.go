@@ -0,0 +1,143 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package resourcemanager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	iamv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
+	resourcemanagerv1alpha1 "go.miloapis.com/milo/pkg/apis/resourcemanager/v1alpha1"
+)
+
+func TestOrganizationMembershipValidator(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iamv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := resourcemanagerv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	ownerRole := resourcemanagerv1alpha1.RoleReference{Name: "owner", Namespace: "datum-cloud"}
+	viewerRole := resourcemanagerv1alpha1.RoleReference{Name: "viewer", Namespace: "datum-cloud"}
+	// A custom role inheriting the owner role also makes its members owners.
+	superRole := resourcemanagerv1alpha1.RoleReference{Name: "super", Namespace: "organization-acme"}
+
+	newMembership := func(name string, roles ...resourcemanagerv1alpha1.RoleReference) *resourcemanagerv1alpha1.OrganizationMembership {
+		return &resourcemanagerv1alpha1.OrganizationMembership{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "organization-acme"},
+			Spec: resourcemanagerv1alpha1.OrganizationMembershipSpec{
+				OrganizationRef: resourcemanagerv1alpha1.OrganizationReference{Name: "acme"},
+				UserRef:         resourcemanagerv1alpha1.MemberReference{Name: name},
+				Roles:           roles,
+			},
+		}
+	}
+	newValidator := func(memberships ...client.Object) *OrganizationMembershipValidator {
+		objects := append([]client.Object{
+			&resourcemanagerv1alpha1.Organization{ObjectMeta: metav1.ObjectMeta{Name: "acme"}},
+			&iamv1alpha1.Role{ObjectMeta: metav1.ObjectMeta{Name: "owner", Namespace: "datum-cloud"}},
+			&iamv1alpha1.Role{
+				ObjectMeta: metav1.ObjectMeta{Name: "super", Namespace: "organization-acme"},
+				Spec: iamv1alpha1.RoleSpec{InheritedRoles: []iamv1alpha1.ScopedRoleReference{
+					{Name: "owner", Namespace: "datum-cloud"},
+				}},
+			},
+		}, memberships...)
+		return &OrganizationMembershipValidator{
+			Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build(),
+			Config: LastOwnerProtectionWebhookConfig{
+				OwnerRoles:   []resourcemanagerv1alpha1.RoleReference{ownerRole},
+				ExemptUsers:  []string{"system:serviceaccount:datum-system:datum-controller-manager"},
+				ExemptGroups: []string{"datum:break-glass"},
+			},
+		}
+	}
+	newUser := func(name string) *iamv1alpha1.User {
+		return &iamv1alpha1.User{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	}
+	requestBy := func(username string, groups ...string) context.Context {
+		return admission.NewContextWithRequest(context.Background(), admission.Request{
+			AdmissionRequest: admissionv1.AdmissionRequest{
+				UserInfo: authenticationv1.UserInfo{Username: username, Groups: groups},
+			},
+		})
+	}
+
+	t.Run("denies deleting the last owner", func(t *testing.T) {
+		owner := newMembership("alice", ownerRole)
+		v := newValidator(owner, newMembership("bob", viewerRole), newUser("alice"))
+
+		_, err := v.ValidateDelete(requestBy("alice"), owner)
+		if !apierrors.IsForbidden(err) {
+			t.Errorf("ValidateDelete() error = %v, want Forbidden", err)
+		}
+	})
+
+	t.Run("allows deleting the last owner when the user is deleted", func(t *testing.T) {
+		owner := newMembership("alice", ownerRole)
+		deleting := newUser("alice")
+		deleting.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+		deleting.Finalizers = []string{"iam.miloapis.com/user"}
+
+		for name, v := range map[string]*OrganizationMembershipValidator{
+			"deleted":  newValidator(owner),
+			"deleting": newValidator(owner, deleting),
+		} {
+			if _, err := v.ValidateDelete(requestBy("alice"), owner); err != nil {
+				t.Errorf("ValidateDelete() of the membership of a %s user error = %v", name, err)
+			}
+		}
+	})
+
+	t.Run("allows the garbage collector", func(t *testing.T) {
+		owner := newMembership("alice", ownerRole)
+		v := newValidator(owner, newUser("alice"))
+
+		if _, err := v.ValidateDelete(requestBy(garbageCollectorUser), owner); err != nil {
+			t.Errorf("ValidateDelete() by the garbage collector error = %v", err)
+		}
+	})
+
+	t.Run("denies downgrading the last owner", func(t *testing.T) {
+		owner := newMembership("alice", ownerRole)
+		v := newValidator(owner)
+
+		_, err := v.ValidateUpdate(requestBy("alice"), owner, newMembership("alice", viewerRole))
+		if !apierrors.IsForbidden(err) {
+			t.Errorf("ValidateUpdate() error = %v, want Forbidden", err)
+		}
+	})
+
+	t.Run("allows removing an owner when an inherited owner remains", func(t *testing.T) {
+		owner := newMembership("alice", ownerRole)
+		v := newValidator(owner, newMembership("bob", superRole))
+
+		if _, err := v.ValidateDelete(requestBy("alice"), owner); err != nil {
+			t.Errorf("ValidateDelete() error = %v", err)
+		}
+	})
+
+	t.Run("allows exempt users and groups", func(t *testing.T) {
+		owner := newMembership("alice", ownerRole)
+		v := newValidator(owner)
+
+		if _, err := v.ValidateDelete(requestBy("system:serviceaccount:datum-system:datum-controller-manager"), owner); err != nil {
+			t.Errorf("ValidateDelete() by exempt user error = %v", err)
+		}
+		if _, err := v.ValidateDelete(requestBy("support", "datum:break-glass"), owner); err != nil {
+			t.Errorf("ValidateDelete() by exempt group error = %v", err)
+		}
+	})
+}
@@ -0,0 +1,41 @@
+//go:build !ignore_autogenerated
+
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Code generated by controller-gen. DO NOT EDIT.
+
+package resourcemanager
+
+import (
+	"go.miloapis.com/milo/pkg/apis/resourcemanager/v1alpha1"
+)
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LastOwnerProtectionWebhookConfig) DeepCopyInto(out *LastOwnerProtectionWebhookConfig) {
+	*out = *in
+	if in.OwnerRoles != nil {
+		in, out := &in.OwnerRoles, &out.OwnerRoles
+		*out = make([]v1alpha1.RoleReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExemptUsers != nil {
+		in, out := &in.ExemptUsers, &out.ExemptUsers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExemptGroups != nil {
+		in, out := &in.ExemptGroups, &out.ExemptGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LastOwnerProtectionWebhookConfig.
+func (in *LastOwnerProtectionWebhookConfig) DeepCopy() *LastOwnerProtectionWebhookConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(LastOwnerProtectionWebhookConfig)
+	in.DeepCopyInto(out)
+	return out
+}
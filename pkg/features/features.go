@@ -16,15 +16,137 @@ const (
 	// owner: @datum-cloud/platform
 	// alpha: v0.1.0
 	UnifiedOrganizations featuregate.Feature = "UnifiedOrganizations"
+
+	// LastOwnerProtection registers the validating webhook that prevents the
+	// last owner of an organization from being removed or downgraded. Enabling
+	// it starts the webhook server, which requires serving certificates.
+	//
+	// owner: @datum-cloud/platform
+	// alpha: v0.1.0
+	LastOwnerProtection featuregate.Feature = "LastOwnerProtection"
+
+	// UserWaitlist enables the controller that approves pending users from
+	// the waitlist in batches. When disabled, users must be approved by other
+	// means.
+	//
+	// owner: @datum-cloud/platform
+	// alpha: v0.1.0
+	UserWaitlist featuregate.Feature = "UserWaitlist"
+
+	// UserOffboarding enables the controller that suspends or removes the
+	// organization memberships of deactivated and rejected users.
+	//
+	// owner: @datum-cloud/platform
+	// alpha: v0.1.0
+	UserOffboarding featuregate.Feature = "UserOffboarding"
+
+	// OrganizationQuotaUsage enables the controller that summarizes the quota usage of
+	// organizations.
+	//
+	// owner: @datum-cloud/platform
+	// alpha: v0.1.0
+	OrganizationQuotaUsage featuregate.Feature = "OrganizationQuotaUsage"
+
+	// OrganizationBootstrap enables the controller that provisions the starter
+	// resources of new organizations, such as their default project.
+	//
+	// owner: @datum-cloud/platform
+	// alpha: v0.1.0
+	OrganizationBootstrap featuregate.Feature = "OrganizationBootstrap"
+
+	// OrganizationCustomRoles enables the controller that materializes the custom
+	// roles of organizations.
+	//
+	// owner: @datum-cloud/platform
+	// alpha: v0.1.0
+	OrganizationCustomRoles featuregate.Feature = "OrganizationCustomRoles"
+
+	// OrganizationInvitations enables the controller that accepts and expires
+	// organization invitations.
+	//
+	// owner: @datum-cloud/platform
+	// alpha: v0.1.0
+	OrganizationInvitations featuregate.Feature = "OrganizationInvitations"
+
+	// OrganizationTransfer enables the controller that transfers the projects of
+	// personal organizations to team organizations.
+	//
+	// owner: @datum-cloud/platform
+	// alpha: v0.1.0
+	OrganizationTransfer featuregate.Feature = "OrganizationTransfer"
+
+	// UserOnboarding enables the controller that approves new users and
+	// assigns them to organizations based on the domain of their email.
+	//
+	// owner: @datum-cloud/platform
+	// alpha: v0.1.0
+	UserOnboarding featuregate.Feature = "UserOnboarding"
+
+	// RoleCatalog enables the controller that validates the assignable
+	// organization roles.
+	//
+	// owner: @datum-cloud/platform
+	// alpha: v0.1.0
+	RoleCatalog featuregate.Feature = "RoleCatalog"
 )
 
 func init() {
 	runtime.Must(utilfeature.DefaultMutableFeatureGate.Add(defaultFeatureGates))
 }
 
+// Gates returns whether each feature gate defined by datum is enabled in the
+// default feature gate.
+func Gates() map[string]bool {
+	gates := make(map[string]bool, len(defaultFeatureGates))
+	for feature := range defaultFeatureGates {
+		gates[string(feature)] = utilfeature.DefaultFeatureGate.Enabled(feature)
+	}
+	return gates
+}
+
 var defaultFeatureGates = map[featuregate.Feature]featuregate.FeatureSpec{
 	UnifiedOrganizations: {
 		Default:    false,
 		PreRelease: featuregate.Alpha,
 	},
+	LastOwnerProtection: {
+		Default:    false,
+		PreRelease: featuregate.Alpha,
+	},
+	UserWaitlist: {
+		Default:    false,
+		PreRelease: featuregate.Alpha,
+	},
+	UserOffboarding: {
+		Default:    false,
+		PreRelease: featuregate.Alpha,
+	},
+	OrganizationQuotaUsage: {
+		Default:    false,
+		PreRelease: featuregate.Alpha,
+	},
+	OrganizationBootstrap: {
+		Default:    false,
+		PreRelease: featuregate.Alpha,
+	},
+	OrganizationCustomRoles: {
+		Default:    false,
+		PreRelease: featuregate.Alpha,
+	},
+	OrganizationInvitations: {
+		Default:    false,
+		PreRelease: featuregate.Alpha,
+	},
+	OrganizationTransfer: {
+		Default:    false,
+		PreRelease: featuregate.Alpha,
+	},
+	UserOnboarding: {
+		Default:    false,
+		PreRelease: featuregate.Alpha,
+	},
+	RoleCatalog: {
+		Default:    false,
+		PreRelease: featuregate.Alpha,
+	},
 }